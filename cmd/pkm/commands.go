@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"github.com/anaminus/pkm"
+	"github.com/anaminus/pkm/export"
+	"github.com/anaminus/pkm/server"
+	"image/png"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+func runInfo(c *context) error {
+	gc := c.ver.GameCode()
+	info := struct {
+		Name     string `json:"name"`
+		GameCode string `json:"game_code"`
+		Language string `json:"language"`
+	}{
+		Name:     c.ver.Name(),
+		GameCode: gc.String(),
+		Language: gc.Language(),
+	}
+	if c.json {
+		return c.printJSON(info)
+	}
+	return c.printTable([][]string{
+		{"Name", info.Name},
+		{"Game code", info.GameCode},
+		{"Language", info.Language},
+	})
+}
+
+func runSpecies(c *context) error {
+	v, err := lookup(c.args[0], c.ver.SpeciesIndexSize(),
+		func(i int) interface{} { return c.ver.SpeciesByIndex(i) },
+		func(name string) interface{} {
+			if s := c.ver.SpeciesByName(name); s != nil {
+				return s
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return c.print(export.NewSpecies(v.(pkm.Species)))
+}
+
+func runMove(c *context) error {
+	v, err := lookup(c.args[0], c.ver.MoveIndexSize(),
+		func(i int) interface{} { return c.ver.MoveByIndex(i) },
+		func(name string) interface{} {
+			if m := c.ver.MoveByName(name); m != nil {
+				return m
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return c.print(export.NewMove(v.(pkm.Move)))
+}
+
+func runItem(c *context) error {
+	v, err := lookup(c.args[0], c.ver.ItemIndexSize(),
+		func(i int) interface{} { return c.ver.ItemByIndex(i) },
+		func(name string) interface{} {
+			if i := c.ver.ItemByName(name); i != nil {
+				return i
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return c.print(export.NewItem(v.(pkm.Item)))
+}
+
+func runAbility(c *context) error {
+	v, err := lookup(c.args[0], c.ver.AbilityIndexSize(),
+		func(i int) interface{} { return c.ver.AbilityByIndex(i) },
+		func(name string) interface{} {
+			if a := c.ver.AbilityByName(name); a != nil {
+				return a
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return c.print(export.NewAbility(v.(pkm.Ability)))
+}
+
+func runMapRender(c *context) error {
+	m, err := findMap(c.ver, c.args[0])
+	if err != nil {
+		return err
+	}
+	out := c.out
+	if out == "" {
+		out = fmt.Sprintf("%d.%d.png", m.BankIndex(), m.Index())
+	}
+	img := pkm.CombineLayers(m.Image()...)
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	size := img.Bounds().Size()
+	result := struct {
+		Map    string `json:"map"`
+		File   string `json:"file"`
+		Width  int    `json:"width"`
+		Height int    `json:"height"`
+	}{m.Name(), out, size.X, size.Y}
+	if c.json {
+		return c.printJSON(result)
+	}
+	return c.printTable([][]string{
+		{"Map", result.Map},
+		{"File", result.File},
+		{"Size", fmt.Sprintf("%dx%d", result.Width, result.Height)},
+	})
+}
+
+func runEncounters(c *context) error {
+	m, err := findMap(c.ver, c.args[0])
+	if err != nil {
+		return err
+	}
+	e := export.NewMap(m).Encounters
+	if c.json {
+		return c.printJSON(e)
+	}
+	rows := [][]string{{"AREA", "SLOT", "SPECIES", "LEVEL", "RATE"}}
+	for _, list := range e {
+		for i, enc := range list.Encounters {
+			level := strconv.Itoa(enc.MinLevel)
+			if enc.MaxLevel != enc.MinLevel {
+				level += "-" + strconv.Itoa(enc.MaxLevel)
+			}
+			rows = append(rows, []string{
+				list.Area,
+				strconv.Itoa(i),
+				enc.Species.Name,
+				level,
+				fmt.Sprintf("%g%%", enc.Rate*100),
+			})
+		}
+	}
+	return c.printTable(rows)
+}
+
+func runExport(c *context) error {
+	if c.out == "" {
+		return export.Write(c.w, c.ver)
+	}
+	f, err := os.Create(c.out)
+	if err != nil {
+		return err
+	}
+	if err := export.Write(f, c.ver); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func runDiff(c *context) error {
+	other, err := openROM(c.args[0])
+	if err != nil {
+		return err
+	}
+	diffs := pkm.Diff(c.ver, other)
+	if c.json {
+		return diffs.WriteJSON(c.w)
+	}
+	return diffs.WriteText(c.w)
+}
+
+func runServe(c *context) error {
+	fmt.Fprintf(os.Stderr, "serving %s on %s\n", c.ver.Name(), c.addr)
+	return http.ListenAndServe(c.addr, server.New(c.ver))
+}
@@ -0,0 +1,316 @@
+// Command pkm inspects the ROM files of Pokemon games.
+//
+// Usage:
+//
+//	pkm <command> [flags] <rom> [arguments]
+//
+// The commands are:
+//
+//	info                    Print the name, game code and language of a ROM.
+//	species <name|index>    Print a species.
+//	move <name|index>       Print a move.
+//	item <name|index>       Print an item.
+//	ability <name|index>    Print an ability.
+//	map render <map>        Render a map to a PNG file.
+//	encounters <map>        Print the wild encounters of a map.
+//	export                  Export every entity of a ROM as JSON.
+//	diff <rom>              Print the differences between two ROMs.
+//	serve                   Serve the data of a ROM over HTTP.
+//
+// A map is given as "<bank>.<index>" or by name. Every command except export
+// accepts a -json flag, which prints JSON instead of a table. The export
+// command always writes JSON.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/anaminus/pkm"
+	"github.com/anaminus/pkm/gen3"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// A subcommand. The first argument is always the ROM.
+type command struct {
+	usage string
+	help  string
+	// Number of arguments following the ROM.
+	args int
+	run  func(c *context) error
+}
+
+// State of a running command.
+type context struct {
+	flags *flag.FlagSet
+	json  bool
+	out   string
+	addr  string
+	ver   pkm.Version
+	args  []string
+	w     io.Writer
+}
+
+var commands = map[string]*command{
+	"info": {
+		usage: "info <rom>",
+		help:  "Print the name, game code and language of a ROM.",
+		run:   runInfo,
+	},
+	"species": {
+		usage: "species <rom> <name|index>",
+		help:  "Print a species.",
+		args:  1,
+		run:   runSpecies,
+	},
+	"move": {
+		usage: "move <rom> <name|index>",
+		help:  "Print a move.",
+		args:  1,
+		run:   runMove,
+	},
+	"item": {
+		usage: "item <rom> <name|index>",
+		help:  "Print an item.",
+		args:  1,
+		run:   runItem,
+	},
+	"ability": {
+		usage: "ability <rom> <name|index>",
+		help:  "Print an ability.",
+		args:  1,
+		run:   runAbility,
+	},
+	"map": {
+		usage: "map render [-o file] <rom> <map>",
+		help:  "Render a map to a PNG file.",
+		args:  1,
+		run:   runMapRender,
+	},
+	"encounters": {
+		usage: "encounters <rom> <map>",
+		help:  "Print the wild encounters of a map.",
+		args:  1,
+		run:   runEncounters,
+	},
+	"export": {
+		usage: "export [-o file] <rom>",
+		help:  "Export every entity of a ROM as JSON.",
+		run:   runExport,
+	},
+	"diff": {
+		usage: "diff <rom> <rom>",
+		help:  "Print the differences between two ROMs.",
+		args:  1,
+		run:   runDiff,
+	},
+	"serve": {
+		usage: "serve [-addr address] <rom>",
+		help:  "Serve the data of a ROM over HTTP.",
+		run:   runServe,
+	},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: pkm <command> [flags] <rom> [arguments]\n\nThe commands are:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w := tabwriter.NewWriter(os.Stderr, 0, 8, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(w, "\t%s\t%s\n", commands[name].usage, commands[name].help)
+	}
+	w.Flush()
+	fmt.Fprintln(os.Stderr, "\nEvery command except export accepts -json to print JSON instead of a table.")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	name := os.Args[1]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "pkm: unknown command %q\n", name)
+		usage()
+	}
+	args := os.Args[2:]
+	if name == "map" {
+		// The only map subcommand.
+		if len(args) == 0 || args[0] != "render" {
+			fmt.Fprintf(os.Stderr, "usage: pkm %s\n", cmd.usage)
+			os.Exit(2)
+		}
+		args = args[1:]
+	}
+
+	c := &context{
+		flags: flag.NewFlagSet(name, flag.ExitOnError),
+		w:     os.Stdout,
+	}
+	if name != "export" {
+		// The export is always JSON.
+		c.flags.BoolVar(&c.json, "json", false, "print JSON instead of a table")
+	}
+	if name == "map" || name == "export" {
+		c.flags.StringVar(&c.out, "o", "", "output file")
+	}
+	if name == "serve" {
+		c.flags.StringVar(&c.addr, "addr", "localhost:8080", "address to listen on")
+	}
+	c.flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: pkm %s\n", cmd.usage)
+		c.flags.PrintDefaults()
+	}
+	c.flags.Parse(args)
+	if c.flags.NArg() != cmd.args+1 {
+		c.flags.Usage()
+		os.Exit(2)
+	}
+	ver, err := openROM(c.flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pkm: %s\n", err)
+		os.Exit(1)
+	}
+	c.ver = ver
+	c.args = c.flags.Args()[1:]
+	if err := cmd.run(c); err != nil {
+		fmt.Fprintf(os.Stderr, "pkm %s: %s\n", name, err)
+		os.Exit(1)
+	}
+}
+
+// Opens a ROM file as a read-only version.
+func openROM(path string) (pkm.Version, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ver, err := gen3.OpenROM(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ver, nil
+}
+
+// Prints v as indented JSON.
+func (c *context) printJSON(v interface{}) error {
+	enc := json.NewEncoder(c.w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(v)
+}
+
+// Prints rows of tab-separated columns, aligned as a table.
+func (c *context) printTable(rows [][]string) error {
+	w := tabwriter.NewWriter(c.w, 0, 8, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// Prints v as JSON, or as a table of fields flattened from its JSON form.
+func (c *context) print(v interface{}) error {
+	if c.json {
+		return c.printJSON(v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var generic interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&generic); err != nil {
+		return err
+	}
+	var rows [][]string
+	flatten(&rows, "", generic)
+	return c.printTable(rows)
+}
+
+// Flattens a decoded JSON value into rows of field names and values.
+// References are printed as "<name> (#<index>)".
+func flatten(rows *[][]string, prefix string, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if name, ok := v["name"]; ok && len(v) == 2 {
+			if index, ok := v["index"]; ok {
+				*rows = append(*rows, []string{prefix, fmt.Sprintf("%v (#%v)", name, index)})
+				return
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flatten(rows, key, v[k])
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			*rows = append(*rows, []string{prefix, "-"})
+		}
+		for i, e := range v {
+			flatten(rows, prefix+"["+strconv.Itoa(i)+"]", e)
+		}
+	default:
+		s := fmt.Sprint(v)
+		// Keep multi-line descriptions on one row.
+		s = strings.Replace(s, "\n", " ", -1)
+		*rows = append(*rows, []string{prefix, s})
+	}
+}
+
+// Looks up a value by index or by name.
+func lookup(arg string, size int, byIndex func(int) interface{}, byName func(string) interface{}) (interface{}, error) {
+	if i, err := strconv.Atoi(arg); err == nil {
+		if i < 0 || i >= size {
+			return nil, fmt.Errorf("index %d out of range", i)
+		}
+		return byIndex(i), nil
+	}
+	if v := byName(arg); v != nil {
+		return v, nil
+	}
+	return nil, fmt.Errorf("%q not found", arg)
+}
+
+// Finds a map given as "<bank>.<index>" or by name.
+func findMap(ver pkm.Version, arg string) (pkm.Map, error) {
+	ver.ScanBanks()
+	if parts := strings.Split(arg, "."); len(parts) == 2 {
+		b, errb := strconv.Atoi(parts[0])
+		m, errm := strconv.Atoi(parts[1])
+		if errb == nil && errm == nil {
+			if b < 0 || b >= ver.BankIndexSize() {
+				return nil, fmt.Errorf("bank %d out of range", b)
+			}
+			bank := ver.BankByIndex(b)
+			if m < 0 || m >= bank.MapIndexSize() {
+				return nil, fmt.Errorf("map %d out of range", m)
+			}
+			return bank.MapByIndex(m), nil
+		}
+	}
+	if m := ver.MapByName(arg); m != nil {
+		return m, nil
+	}
+	return nil, errors.New("map " + strconv.Quote(arg) + " not found")
+}
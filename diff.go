@@ -0,0 +1,324 @@
+package pkm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Difference is a single field-level change between two versions.
+type Difference struct {
+	// The kind of value that changed, such as "Species" or "Map".
+	Category string `json:"category"`
+	// The name of the changed value, as it appears in the first version.
+	Subject string `json:"subject"`
+	// The changed field of the value, such as "BaseStats.Speed".
+	Field string `json:"field"`
+	// The field in the first version.
+	Old string `json:"old"`
+	// The field in the second version.
+	New string `json:"new"`
+}
+
+// String returns the difference formatted as "<subject> <field> <old> ->
+// <new>".
+func (d Difference) String() string {
+	return d.Subject + " " + d.Field + " " + d.Old + " -> " + d.New
+}
+
+// Differences is a list of differences between two versions.
+type Differences []Difference
+
+// WriteText writes each difference as a line of text.
+func (d Differences) WriteText(w io.Writer) error {
+	for _, diff := range d {
+		if _, err := io.WriteString(w, diff.String()+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the differences as a JSON array.
+func (d Differences) WriteJSON(w io.Writer) error {
+	if d == nil {
+		d = Differences{}
+	}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "\t")
+	return e.Encode(d)
+}
+
+// Used to build a list of differences.
+type differ struct {
+	diffs    Differences
+	category string
+	subject  string
+}
+
+// Adds a difference if the formatted values of a field are not equal.
+func (d *differ) cmp(field string, a, b interface{}) {
+	sa, sb := fmt.Sprint(a), fmt.Sprint(b)
+	if sa != sb {
+		d.diffs = append(d.diffs, Difference{
+			Category: d.category,
+			Subject:  d.subject,
+			Field:    field,
+			Old:      sa,
+			New:      sb,
+		})
+	}
+}
+
+// Names of values that may be nil.
+func speciesName(s Species) string {
+	if s == nil {
+		return "-"
+	}
+	return s.Name()
+}
+
+func itemName(i Item) string {
+	if i == nil {
+		return "-"
+	}
+	return i.Name()
+}
+
+func abilityName(a Ability) string {
+	if a == nil {
+		return "-"
+	}
+	return a.Name()
+}
+
+func moveName(m Move) string {
+	if m == nil {
+		return "-"
+	}
+	return m.Name()
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func (d *differ) stats(field string, a, b Stats) {
+	d.cmp(field+".HitPoints", a.HitPoints, b.HitPoints)
+	d.cmp(field+".Attack", a.Attack, b.Attack)
+	d.cmp(field+".Defense", a.Defense, b.Defense)
+	d.cmp(field+".Speed", a.Speed, b.Speed)
+	d.cmp(field+".SpAttack", a.SpAttack, b.SpAttack)
+	d.cmp(field+".SpDefense", a.SpDefense, b.SpDefense)
+}
+
+func (d *differ) effortPoints(field string, a, b EffortPoints) {
+	d.cmp(field+".HitPoints", a.Hitpoints(), b.Hitpoints())
+	d.cmp(field+".Attack", a.Attack(), b.Attack())
+	d.cmp(field+".Defense", a.Defense(), b.Defense())
+	d.cmp(field+".Speed", a.Speed(), b.Speed())
+	d.cmp(field+".SpAttack", a.SpAttack(), b.SpAttack())
+	d.cmp(field+".SpDefense", a.SpDefense(), b.SpDefense())
+}
+
+func (d *differ) species(a, b Species, tmsA, tmsB []TM) {
+	d.category, d.subject = "Species", a.Name()
+	d.cmp("Name", a.Name(), b.Name())
+	d.cmp("Category", a.Category(), b.Category())
+	d.cmp("Height", a.Height(), b.Height())
+	d.cmp("Weight", a.Weight(), b.Weight())
+	d.cmp("Description", a.Description(), b.Description())
+	d.stats("BaseStats", a.BaseStats(), b.BaseStats())
+	for i, t := range a.Type() {
+		d.cmp("Type["+strconv.Itoa(i)+"]", t, b.Type()[i])
+	}
+	d.cmp("CatchRate", a.CatchRate(), b.CatchRate())
+	d.cmp("ExpYield", a.ExpYield(), b.ExpYield())
+	d.effortPoints("EffortPoints", a.EffortPoints(), b.EffortPoints())
+	for i, item := range a.HeldItem() {
+		d.cmp("HeldItem["+strconv.Itoa(i)+"]", itemName(item), itemName(b.HeldItem()[i]))
+	}
+	d.cmp("GenderRatio", a.GenderRatio(), b.GenderRatio())
+	d.cmp("EggCycles", a.EggCycles(), b.EggCycles())
+	d.cmp("BaseFriendship", a.BaseFriendship(), b.BaseFriendship())
+	d.cmp("LevelType", a.LevelType(), b.LevelType())
+	for i, g := range a.EggGroup() {
+		d.cmp("EggGroup["+strconv.Itoa(i)+"]", g, b.EggGroup()[i])
+	}
+	for i, ability := range a.Ability() {
+		d.cmp("Ability["+strconv.Itoa(i)+"]", abilityName(ability), abilityName(b.Ability()[i]))
+	}
+	d.cmp("SafariRate", a.SafariRate(), b.SafariRate())
+	d.cmp("Color", a.Color(), b.Color())
+
+	// Learnset.
+	lma, lmb := a.LearnedMoves(), b.LearnedMoves()
+	levelMove := func(lm []LevelMove, i int) string {
+		if i >= len(lm) {
+			return "-"
+		}
+		return fmt.Sprintf("%d %s", lm[i].Level, moveName(lm[i].Move))
+	}
+	for i := 0; i < maxInt(len(lma), len(lmb)); i++ {
+		d.cmp("LearnedMoves["+strconv.Itoa(i)+"]", levelMove(lma, i), levelMove(lmb, i))
+	}
+
+	// TM compatibility.
+	for i := 0; i < minInt(len(tmsA), len(tmsB)); i++ {
+		d.cmp("CanLearnTM."+tmsA[i].Name(), a.CanLearnTM(tmsA[i]), b.CanLearnTM(tmsB[i]))
+	}
+
+	// Evolutions.
+	eva, evb := a.Evolutions(), b.Evolutions()
+	evolution := func(ev []Evolution, i int) string {
+		if i >= len(ev) {
+			return "-"
+		}
+		return fmt.Sprintf("%s (%s)", speciesName(ev[i].Target()), ev[i].MethodString())
+	}
+	for i := 0; i < maxInt(len(eva), len(evb)); i++ {
+		d.cmp("Evolutions["+strconv.Itoa(i)+"]", evolution(eva, i), evolution(evb, i))
+	}
+}
+
+func (d *differ) move(a, b Move) {
+	d.category, d.subject = "Move", a.Name()
+	d.cmp("Name", a.Name(), b.Name())
+	d.cmp("Description", a.Description(), b.Description())
+	d.cmp("Type", a.Type(), b.Type())
+	d.cmp("BasePower", a.BasePower(), b.BasePower())
+	d.cmp("Accuracy", a.Accuracy(), b.Accuracy())
+	d.cmp("PowerPoints", a.PowerPoints(), b.PowerPoints())
+	d.cmp("Effect", a.Effect(), b.Effect())
+	d.cmp("EffectAccuracy", a.EffectAccuracy(), b.EffectAccuracy())
+	d.cmp("Affectee", a.Affectee(), b.Affectee())
+	d.cmp("Priority", a.Priority(), b.Priority())
+	d.cmp("Flags", a.Flags(), b.Flags())
+}
+
+func (d *differ) item(a, b Item) {
+	d.category, d.subject = "Item", a.Name()
+	d.cmp("Name", a.Name(), b.Name())
+	d.cmp("Description", a.Description(), b.Description())
+	d.cmp("Price", a.Price(), b.Price())
+}
+
+func (d *differ) ability(a, b Ability) {
+	d.category, d.subject = "Ability", a.Name()
+	d.cmp("Name", a.Name(), b.Name())
+	d.cmp("Description", a.Description(), b.Description())
+}
+
+func (d *differ) tm(a, b TM) {
+	d.category, d.subject = "TM", a.Name()
+	d.cmp("Move", moveName(a.Move()), moveName(b.Move()))
+}
+
+func (d *differ) layout(field string, a, b Layout) {
+	d.cmp(field+".Width", a.Width(), b.Width())
+	d.cmp(field+".Height", a.Height(), b.Height())
+	if a.Width() != b.Width() || a.Height() != b.Height() {
+		return
+	}
+	changed := 0
+	for i := 0; i < a.Width()*a.Height(); i++ {
+		ab, aa := a.Cell(i)
+		bb, ba := b.Cell(i)
+		if ab != bb || aa != ba {
+			changed++
+		}
+	}
+	if changed > 0 {
+		d.cmp(field+".Cells", "unchanged", strconv.Itoa(changed)+" changed")
+	}
+}
+
+func (d *differ) mapData(a, b Map) {
+	d.category, d.subject = "Map", fmt.Sprintf("%s (%d.%d)", a.Name(), a.BankIndex(), a.Index())
+	d.cmp("Name", a.Name(), b.Name())
+	d.layout("Layout", a.Layout(), b.Layout())
+	d.layout("Border", a.Border(), b.Border())
+
+	ca, cb := a.Connections(), b.Connections()
+	connection := func(c []Connection, i int) string {
+		if i >= len(c) {
+			return "-"
+		}
+		return fmt.Sprintf("%s %d.%d offset %d", c[i].Direction, c[i].Bank, c[i].Map, c[i].Offset)
+	}
+	for i := 0; i < maxInt(len(ca), len(cb)); i++ {
+		d.cmp("Connections["+strconv.Itoa(i)+"]", connection(ca, i), connection(cb, i))
+	}
+
+	ea, eb := a.Encounters(), b.Encounters()
+	for i := 0; i < minInt(len(ea), len(eb)); i++ {
+		la, lb := ea[i], eb[i]
+		name := la.Name()
+		d.cmp(name+" Populated", la.Populated(), lb.Populated())
+		if !la.Populated() || !lb.Populated() {
+			continue
+		}
+		d.cmp(name+" EncounterRate", la.EncounterRate(), lb.EncounterRate())
+		for j := 0; j < minInt(la.EncounterIndexSize(), lb.EncounterIndexSize()); j++ {
+			sa, sb := la.Encounter(j), lb.Encounter(j)
+			slot := name + " slot " + strconv.Itoa(j)
+			d.cmp(slot, speciesName(sa.Species()), speciesName(sb.Species()))
+			d.cmp(slot+" MinLevel", sa.MinLevel(), sb.MinLevel())
+			d.cmp(slot+" MaxLevel", sa.MaxLevel(), sb.MaxLevel())
+		}
+	}
+}
+
+// Diff compares two versions, returning the field-level differences of their
+// species, moves, items, abilities, TMs and maps. Values are matched by
+// index, and are compared only where both versions have the index. Banks are
+// scanned in both versions.
+func Diff(a, b Version) Differences {
+	d := &differ{}
+
+	tmsA := make([]TM, a.TMIndexSize())
+	for i := range tmsA {
+		tmsA[i] = a.TMByIndex(i)
+	}
+	tmsB := make([]TM, b.TMIndexSize())
+	for i := range tmsB {
+		tmsB[i] = b.TMByIndex(i)
+	}
+
+	for i := 0; i < minInt(a.SpeciesIndexSize(), b.SpeciesIndexSize()); i++ {
+		d.species(a.SpeciesByIndex(i), b.SpeciesByIndex(i), tmsA, tmsB)
+	}
+	for i := 1; i < minInt(a.MoveIndexSize(), b.MoveIndexSize()); i++ {
+		d.move(a.MoveByIndex(i), b.MoveByIndex(i))
+	}
+	for i := 0; i < minInt(a.ItemIndexSize(), b.ItemIndexSize()); i++ {
+		d.item(a.ItemByIndex(i), b.ItemByIndex(i))
+	}
+	for i := 0; i < minInt(a.AbilityIndexSize(), b.AbilityIndexSize()); i++ {
+		d.ability(a.AbilityByIndex(i), b.AbilityByIndex(i))
+	}
+	for i := 0; i < minInt(len(tmsA), len(tmsB)); i++ {
+		d.tm(tmsA[i], tmsB[i])
+	}
+
+	a.ScanBanks()
+	b.ScanBanks()
+	for i := 0; i < minInt(a.BankIndexSize(), b.BankIndexSize()); i++ {
+		ba, bb := a.BankByIndex(i), b.BankByIndex(i)
+		for j := 0; j < minInt(ba.MapIndexSize(), bb.MapIndexSize()); j++ {
+			d.mapData(ba.MapByIndex(j), bb.MapByIndex(j))
+		}
+	}
+	return d.diffs
+}
@@ -0,0 +1,411 @@
+// Package export serializes an entire pkm.Version to JSON.
+//
+// The schema is described by the types of this package. Each field is encoded
+// under the name given by its JSON tag. References to other values are encoded
+// as a Ref, containing both the index and the name of the value. Enumerated
+// values, such as types and egg groups, are encoded as their names. Lists are
+// ordered by index. The schema is versioned by SchemaVersion, which is
+// incremented whenever a field is changed or removed.
+package export
+
+import (
+	"encoding/json"
+	"github.com/anaminus/pkm"
+	"io"
+)
+
+// SchemaVersion is the version of the schema produced by this package.
+const SchemaVersion = 1
+
+// Ref refers to a value by index and name.
+type Ref struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+}
+
+// Version is the root of the schema.
+type Version struct {
+	Schema    int        `json:"schema"`
+	Name      string     `json:"name"`
+	GameCode  string     `json:"game_code"`
+	Species   []Species  `json:"species"`
+	Moves     []Move     `json:"moves"`
+	Items     []Item     `json:"items"`
+	Abilities []Ability  `json:"abilities"`
+	TMs       []TM       `json:"tms"`
+	Pokedexes []Pokedex  `json:"pokedexes"`
+	TypeChart []TypeRule `json:"type_chart"`
+	Banks     []Bank     `json:"banks"`
+}
+
+// Stats is a set of values for each stat.
+type Stats struct {
+	HitPoints int `json:"hp"`
+	Attack    int `json:"attack"`
+	Defense   int `json:"defense"`
+	Speed     int `json:"speed"`
+	SpAttack  int `json:"sp_attack"`
+	SpDefense int `json:"sp_defense"`
+}
+
+type Species struct {
+	Index       int    `json:"index"`
+	Name        string `json:"name"`
+	Category    string `json:"category"`
+	Description string `json:"description"`
+	// Height in decimeters.
+	Height int `json:"height"`
+	// Weight in hectograms.
+	Weight         int         `json:"weight"`
+	BaseStats      Stats       `json:"base_stats"`
+	Types          [2]string   `json:"types"`
+	CatchRate      int         `json:"catch_rate"`
+	ExpYield       int         `json:"exp_yield"`
+	EffortPoints   Stats       `json:"effort_points"`
+	HeldItems      [2]Ref      `json:"held_items"`
+	GenderRatio    int         `json:"gender_ratio"`
+	EggCycles      int         `json:"egg_cycles"`
+	BaseFriendship int         `json:"base_friendship"`
+	LevelType      string      `json:"level_type"`
+	EggGroups      [2]string   `json:"egg_groups"`
+	Abilities      [2]Ref      `json:"abilities"`
+	SafariRate     int         `json:"safari_rate"`
+	Color          string      `json:"color"`
+	LearnedMoves   []LevelMove `json:"learned_moves"`
+	TMs            []Ref       `json:"tms"`
+	Evolutions     []Evolution `json:"evolutions"`
+}
+
+type LevelMove struct {
+	Level int `json:"level"`
+	Move  Ref `json:"move"`
+}
+
+type Evolution struct {
+	Target Ref `json:"target"`
+	// The raw method and parameter of the evolution.
+	Method int `json:"method"`
+	Param  int `json:"param"`
+	// A description of the method and parameter.
+	Description string `json:"description"`
+}
+
+type Move struct {
+	Index          int    `json:"index"`
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	Type           string `json:"type"`
+	BasePower      int    `json:"base_power"`
+	Accuracy       int    `json:"accuracy"`
+	PowerPoints    int    `json:"power_points"`
+	Effect         int    `json:"effect"`
+	EffectAccuracy int    `json:"effect_accuracy"`
+	Affectee       string `json:"affectee"`
+	Priority       int    `json:"priority"`
+	Flags          int    `json:"flags"`
+}
+
+type Item struct {
+	Index       int    `json:"index"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Price       int    `json:"price"`
+}
+
+type Ability struct {
+	Index       int    `json:"index"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type TM struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	Move  Ref    `json:"move"`
+}
+
+type Pokedex struct {
+	Name string `json:"name"`
+	// Species ordered by pokedex number, starting at 1.
+	Species []Ref `json:"species"`
+}
+
+// TypeRule is an entry of the type chart. Pairs of types that are not listed
+// are normally effective.
+type TypeRule struct {
+	Attack  string  `json:"attack"`
+	Defense string  `json:"defense"`
+	Effect  float64 `json:"effect"`
+	// Whether the entry is ignored when the defender has been identified by
+	// Foresight or Odor Sleuth.
+	Foresight bool `json:"foresight"`
+}
+
+type Bank struct {
+	Index int   `json:"index"`
+	Maps  []Map `json:"maps"`
+}
+
+type Map struct {
+	Bank        int             `json:"bank"`
+	Index       int             `json:"index"`
+	Name        string          `json:"name"`
+	Width       int             `json:"width"`
+	Height      int             `json:"height"`
+	Connections []Connection    `json:"connections"`
+	Encounters  []EncounterList `json:"encounters"`
+}
+
+type Connection struct {
+	Direction string `json:"direction"`
+	Offset    int    `json:"offset"`
+	Bank      int    `json:"bank"`
+	Map       int    `json:"map"`
+}
+
+// EncounterList is a populated encounter area of a map. Unpopulated areas
+// are omitted.
+type EncounterList struct {
+	Area          string      `json:"area"`
+	EncounterRate float64     `json:"encounter_rate"`
+	Encounters    []Encounter `json:"encounters"`
+}
+
+type Encounter struct {
+	Species  Ref     `json:"species"`
+	MinLevel int     `json:"min_level"`
+	MaxLevel int     `json:"max_level"`
+	Rate     float64 `json:"rate"`
+}
+
+////////////////////////////////////////////////////////////////
+
+type named interface {
+	Index() int
+	Name() string
+}
+
+func ref(v named) Ref {
+	if v == nil {
+		return Ref{}
+	}
+	return Ref{Index: v.Index(), Name: v.Name()}
+}
+
+func stats(s pkm.Stats) Stats {
+	return Stats{
+		HitPoints: int(s.HitPoints),
+		Attack:    int(s.Attack),
+		Defense:   int(s.Defense),
+		Speed:     int(s.Speed),
+		SpAttack:  int(s.SpAttack),
+		SpDefense: int(s.SpDefense),
+	}
+}
+
+// NewSpecies converts a species into the schema.
+func NewSpecies(s pkm.Species) Species {
+	ep := s.EffortPoints()
+	e := Species{
+		Index:       s.Index(),
+		Name:        s.Name(),
+		Category:    s.Category(),
+		Description: s.Description(),
+		Height:      int(s.Height()),
+		Weight:      int(s.Weight()),
+		BaseStats:   stats(s.BaseStats()),
+		CatchRate:   int(s.CatchRate()),
+		ExpYield:    int(s.ExpYield()),
+		EffortPoints: Stats{
+			HitPoints: int(ep.Hitpoints()),
+			Attack:    int(ep.Attack()),
+			Defense:   int(ep.Defense()),
+			Speed:     int(ep.Speed()),
+			SpAttack:  int(ep.SpAttack()),
+			SpDefense: int(ep.SpDefense()),
+		},
+		GenderRatio:    int(s.GenderRatio()),
+		EggCycles:      int(s.EggCycles()),
+		BaseFriendship: int(s.BaseFriendship()),
+		LevelType:      s.LevelType().String(),
+		SafariRate:     int(s.SafariRate()),
+		Color:          s.Color().String(),
+		LearnedMoves:   []LevelMove{},
+		TMs:            []Ref{},
+		Evolutions:     []Evolution{},
+	}
+	for i := 0; i < 2; i++ {
+		e.Types[i] = s.Type()[i].String()
+		e.HeldItems[i] = ref(s.HeldItem()[i])
+		e.EggGroups[i] = s.EggGroup()[i].String()
+		e.Abilities[i] = ref(s.Ability()[i])
+	}
+	for _, lm := range s.LearnedMoves() {
+		e.LearnedMoves = append(e.LearnedMoves, LevelMove{Level: int(lm.Level), Move: ref(lm.Move)})
+	}
+	for _, tm := range s.LearnableTMs() {
+		e.TMs = append(e.TMs, ref(tm))
+	}
+	for _, ev := range s.Evolutions() {
+		e.Evolutions = append(e.Evolutions, Evolution{
+			Target:      ref(ev.Target()),
+			Method:      int(ev.Method()),
+			Param:       int(ev.Param()),
+			Description: ev.MethodString(),
+		})
+	}
+	return e
+}
+
+// NewMove converts a move into the schema.
+func NewMove(m pkm.Move) Move {
+	return Move{
+		Index:          m.Index(),
+		Name:           m.Name(),
+		Description:    m.Description(),
+		Type:           m.Type().String(),
+		BasePower:      int(m.BasePower()),
+		Accuracy:       int(m.Accuracy()),
+		PowerPoints:    int(m.PowerPoints()),
+		Effect:         int(m.Effect()),
+		EffectAccuracy: int(m.EffectAccuracy()),
+		Affectee:       m.Affectee().String(),
+		Priority:       int(m.Priority()),
+		Flags:          int(m.Flags()),
+	}
+}
+
+// NewItem converts an item into the schema.
+func NewItem(i pkm.Item) Item {
+	return Item{
+		Index:       i.Index(),
+		Name:        i.Name(),
+		Description: i.Description(),
+		Price:       i.Price(),
+	}
+}
+
+// NewAbility converts an ability into the schema.
+func NewAbility(a pkm.Ability) Ability {
+	return Ability{
+		Index:       a.Index(),
+		Name:        a.Name(),
+		Description: a.Description(),
+	}
+}
+
+// NewMap converts a map into the schema.
+func NewMap(m pkm.Map) Map {
+	layout := m.Layout()
+	e := Map{
+		Bank:        m.BankIndex(),
+		Index:       m.Index(),
+		Name:        m.Name(),
+		Width:       layout.Width(),
+		Height:      layout.Height(),
+		Connections: []Connection{},
+		Encounters:  []EncounterList{},
+	}
+	for _, c := range m.Connections() {
+		e.Connections = append(e.Connections, Connection{
+			Direction: c.Direction.String(),
+			Offset:    c.Offset,
+			Bank:      c.Bank,
+			Map:       c.Map,
+		})
+	}
+	for _, list := range m.Encounters() {
+		if !list.Populated() {
+			continue
+		}
+		l := EncounterList{
+			Area:          list.Name(),
+			EncounterRate: list.EncounterRate(),
+			Encounters:    []Encounter{},
+		}
+		for i, enc := range list.Encounters() {
+			l.Encounters = append(l.Encounters, Encounter{
+				Species:  ref(enc.Species()),
+				MinLevel: enc.MinLevel(),
+				MaxLevel: enc.MaxLevel(),
+				Rate:     list.SpeciesRate(i),
+			})
+		}
+		e.Encounters = append(e.Encounters, l)
+	}
+	return e
+}
+
+// Export converts v into the values of the schema. Banks are scanned, if
+// they have not been already.
+func Export(v pkm.Version) *Version {
+	gc := v.GameCode()
+	e := &Version{
+		Schema:    SchemaVersion,
+		Name:      v.Name(),
+		GameCode:  string(gc[:]),
+		Species:   []Species{},
+		Moves:     []Move{},
+		Items:     []Item{},
+		Abilities: []Ability{},
+		TMs:       []TM{},
+		Pokedexes: []Pokedex{},
+		TypeChart: []TypeRule{},
+		Banks:     []Bank{},
+	}
+	for i := 0; i < v.SpeciesIndexSize(); i++ {
+		e.Species = append(e.Species, NewSpecies(v.SpeciesByIndex(i)))
+	}
+	// Move 0 is a placeholder with no description.
+	for i := 1; i < v.MoveIndexSize(); i++ {
+		e.Moves = append(e.Moves, NewMove(v.MoveByIndex(i)))
+	}
+	for i := 0; i < v.ItemIndexSize(); i++ {
+		e.Items = append(e.Items, NewItem(v.ItemByIndex(i)))
+	}
+	for i := 0; i < v.AbilityIndexSize(); i++ {
+		e.Abilities = append(e.Abilities, NewAbility(v.AbilityByIndex(i)))
+	}
+	for i := 0; i < v.TMIndexSize(); i++ {
+		tm := v.TMByIndex(i)
+		e.TMs = append(e.TMs, TM{Index: tm.Index(), Name: tm.Name(), Move: ref(tm.Move())})
+	}
+	for _, dex := range v.Pokedex() {
+		d := Pokedex{Name: dex.Name(), Species: []Ref{}}
+		for n := 1; n <= dex.Size(); n++ {
+			d.Species = append(d.Species, ref(dex.Species(n)))
+		}
+		e.Pokedexes = append(e.Pokedexes, d)
+	}
+	chart := v.TypeChart()
+	for atk := range chart.Effect {
+		for def, effect := range chart.Effect[atk] {
+			if effect == pkm.NormalEffect && !chart.Foresight[atk][def] {
+				continue
+			}
+			e.TypeChart = append(e.TypeChart, TypeRule{
+				Attack:    pkm.Type(atk).String(),
+				Defense:   pkm.Type(def).String(),
+				Effect:    effect.Multiplier(),
+				Foresight: chart.Foresight[atk][def],
+			})
+		}
+	}
+	v.ScanBanks()
+	for i := 0; i < v.BankIndexSize(); i++ {
+		bank := v.BankByIndex(i)
+		b := Bank{Index: bank.Index(), Maps: []Map{}}
+		for j := 0; j < bank.MapIndexSize(); j++ {
+			b.Maps = append(b.Maps, NewMap(bank.MapByIndex(j)))
+		}
+		e.Banks = append(e.Banks, b)
+	}
+	return e
+}
+
+// Write exports v, and writes it to w as indented JSON.
+func Write(w io.Writer, v pkm.Version) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(Export(v))
+}
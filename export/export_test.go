@@ -0,0 +1,167 @@
+package export_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/anaminus/pkm/export"
+	"github.com/anaminus/pkm/gen3"
+	"github.com/anaminus/pkm/gen3/romtest"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Tests use the same optional ROM as the gen3 package. Tests that require it
+// are skipped when the file is missing.
+const ROMLocation = "../gen3/rom.gba"
+
+func TestExport(t *testing.T) {
+	b, err := ioutil.ReadFile(ROMLocation)
+	if err != nil {
+		t.Skipf("requires `%s` file, whose contents are a ROM dump of Pokemon Emerald (BPEE): %s", ROMLocation, err)
+	}
+	ver, err := gen3.OpenROM(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := export.Write(&buf, ver); err != nil {
+		t.Fatalf("Write: unexpected error: %s", err)
+	}
+	var e export.Version
+	if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+		t.Fatalf("Write: invalid JSON: %s", err)
+	}
+
+	if e.Schema != export.SchemaVersion || e.GameCode != "BPEE" {
+		t.Errorf("Export: unexpected header %d %q", e.Schema, e.GameCode)
+	}
+	if len(e.Species) != ver.SpeciesIndexSize() {
+		t.Errorf("Export: unexpected species count %d", len(e.Species))
+	}
+	s := e.Species[ver.SpeciesByName("sceptile").Index()]
+	if s.Name != "SCEPTILE" || s.BaseStats.Speed != 120 || s.Types[0] != "Grass" {
+		t.Errorf("Export: unexpected species %+v", s)
+	}
+	if len(s.LearnedMoves) == 0 || len(s.TMs) == 0 {
+		t.Errorf("Export: missing learnset or TMs")
+	}
+	bulbasaur := e.Species[1]
+	if len(bulbasaur.Evolutions) != 1 || bulbasaur.Evolutions[0].Target.Name != "IVYSAUR" {
+		t.Errorf("Export: unexpected evolutions %+v", bulbasaur.Evolutions)
+	}
+	if e.Moves[0].Index != 1 || e.Moves[0].Name != "POUND" {
+		t.Errorf("Export: unexpected first move %+v", e.Moves[0])
+	}
+	if len(e.TMs) != ver.TMIndexSize() || len(e.Pokedexes) == 0 || len(e.TypeChart) == 0 {
+		t.Errorf("Export: missing TMs, pokedexes or type chart")
+	}
+	route := e.Banks[0].Maps[16]
+	if route.Name != "ROUTE 101" || len(route.Encounters) == 0 || len(route.Connections) == 0 {
+		t.Errorf("Export: unexpected map %s", route.Name)
+	}
+}
+
+func TestExportSynthetic(t *testing.T) {
+	ver, err := gen3.OpenROM(bytes.NewReader(romtest.Build()))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := export.Write(&buf, ver); err != nil {
+		t.Fatalf("Write: unexpected error: %s", err)
+	}
+	var e export.Version
+	if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+		t.Fatalf("Write: invalid JSON: %s", err)
+	}
+
+	if e.Schema != export.SchemaVersion || e.GameCode != "BPEE" {
+		t.Errorf("Export: unexpected header %d %q", e.Schema, e.GameCode)
+	}
+	if len(e.Species) != ver.SpeciesIndexSize() {
+		t.Errorf("Export: unexpected species count %d", len(e.Species))
+	}
+	s := e.Species[254]
+	if s.Name != romtest.SpeciesName(254) || s.BaseStats.Speed != int(romtest.BaseStats(254).Speed) || s.Types[0] != romtest.SpeciesTypes(254)[0].String() {
+		t.Errorf("Export: unexpected species %+v", s)
+	}
+	if len(s.LearnedMoves) == 0 || len(s.TMs) == 0 {
+		t.Errorf("Export: missing learnset or TMs")
+	}
+	if evos := e.Species[1].Evolutions; len(evos) != 1 || evos[0].Target.Name != romtest.SpeciesName(2) {
+		t.Errorf("Export: unexpected evolutions %+v", evos)
+	}
+	if e.Moves[0].Index != 1 || e.Moves[0].Name != romtest.MoveName(1) {
+		t.Errorf("Export: unexpected first move %+v", e.Moves[0])
+	}
+	if len(e.TMs) != ver.TMIndexSize() || len(e.Pokedexes) == 0 || len(e.TypeChart) == 0 {
+		t.Errorf("Export: missing TMs, pokedexes or type chart")
+	}
+	route := e.Banks[0].Maps[0]
+	if route.Name != romtest.MapLabel(0) || len(route.Encounters) == 0 || len(route.Connections) == 0 {
+		t.Errorf("Export: unexpected map %s", route.Name)
+	}
+}
+
+func BenchmarkExport(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		opts []gen3.Option
+	}{
+		{"Uncached", nil},
+		{"Cached", []gen3.Option{gen3.Cached()}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			// Read from the file, so that each access reaches the ROM.
+			f, err := os.Open(ROMLocation)
+			if err != nil {
+				b.Skipf("benchmark requires `%s` file: %s", ROMLocation, err)
+			}
+			defer f.Close()
+			// The Version is opened once, as by a server, so that a cached
+			// Version is read from memory after the first export.
+			ver, err := gen3.OpenROM(f, bm.opts...)
+			if err != nil {
+				b.Fatalf("failed to open ROM: %s", err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := export.Write(ioutil.Discard, ver); err != nil {
+					b.Fatalf("Write: unexpected error: %s", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkExportSynthetic(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		opts []gen3.Option
+	}{
+		{"Uncached", nil},
+		{"Cached", []gen3.Option{gen3.Cached()}},
+	}
+	rom := romtest.Build()
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			// Hide ReadAt, so that each access seeks and reads the ROM.
+			r := struct{ io.ReadSeeker }{bytes.NewReader(rom)}
+			ver, err := gen3.OpenROM(r, bm.opts...)
+			if err != nil {
+				b.Fatalf("failed to open ROM: %s", err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := export.Write(ioutil.Discard, ver); err != nil {
+					b.Fatalf("Write: unexpected error: %s", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+//go:build sqlite
+// +build sqlite
+
+// Package sqlite writes an entire pkm.Version into normalized SQLite tables.
+//
+// Each table is described by Schema. Rows refer to each other by index, with
+// foreign keys declared between tables. For example, the following query
+// lists every Water species with a base stat total under 300 that can be
+// found on a route:
+//
+//	SELECT DISTINCT s.name FROM species s
+//	JOIN types t ON t.id IN (s.type1_id, s.type2_id)
+//	JOIN encounters e ON e.species_id = s.id
+//	JOIN maps m ON m.id = e.map_id
+//	WHERE t.name = 'Water' AND s.bst < 300 AND m.name LIKE 'ROUTE %';
+//
+// WriteFile uses modernc.org/sqlite, a pure-Go driver that does not require
+// cgo. Write can be used with any database/sql driver for SQLite.
+//
+// The driver is required by the go.mod of this module, but is large, so the
+// package is built only with the sqlite build tag:
+//
+//	go build -tags sqlite ./...
+package sqlite
+
+import (
+	"database/sql"
+	"github.com/anaminus/pkm"
+	_ "modernc.org/sqlite"
+)
+
+// Schema contains the statements that create each table. Existing tables are
+// replaced.
+var Schema = []string{
+	`DROP TABLE IF EXISTS encounters`,
+	`DROP TABLE IF EXISTS connections`,
+	`DROP TABLE IF EXISTS maps`,
+	`DROP TABLE IF EXISTS evolutions`,
+	`DROP TABLE IF EXISTS species_moves`,
+	`DROP TABLE IF EXISTS tms`,
+	`DROP TABLE IF EXISTS species`,
+	`DROP TABLE IF EXISTS moves`,
+	`DROP TABLE IF EXISTS abilities`,
+	`DROP TABLE IF EXISTS items`,
+	`DROP TABLE IF EXISTS types`,
+
+	`CREATE TABLE types (
+		id   INTEGER PRIMARY KEY,
+		name TEXT NOT NULL
+	)`,
+	`CREATE TABLE items (
+		id          INTEGER PRIMARY KEY,
+		name        TEXT NOT NULL,
+		description TEXT NOT NULL,
+		price       INTEGER NOT NULL
+	)`,
+	`CREATE TABLE abilities (
+		id          INTEGER PRIMARY KEY,
+		name        TEXT NOT NULL,
+		description TEXT NOT NULL
+	)`,
+	`CREATE TABLE moves (
+		id              INTEGER PRIMARY KEY,
+		name            TEXT NOT NULL,
+		description     TEXT NOT NULL,
+		type_id         INTEGER NOT NULL REFERENCES types(id),
+		base_power      INTEGER NOT NULL,
+		accuracy        INTEGER NOT NULL,
+		power_points    INTEGER NOT NULL,
+		effect          INTEGER NOT NULL,
+		effect_accuracy INTEGER NOT NULL,
+		affectee        TEXT NOT NULL,
+		priority        INTEGER NOT NULL,
+		flags           INTEGER NOT NULL
+	)`,
+	`CREATE TABLE species (
+		id              INTEGER PRIMARY KEY,
+		name            TEXT NOT NULL,
+		category        TEXT NOT NULL,
+		description     TEXT NOT NULL,
+		height          INTEGER NOT NULL, -- Decimeters.
+		weight          INTEGER NOT NULL, -- Hectograms.
+		hp              INTEGER NOT NULL,
+		attack          INTEGER NOT NULL,
+		defense         INTEGER NOT NULL,
+		speed           INTEGER NOT NULL,
+		sp_attack       INTEGER NOT NULL,
+		sp_defense      INTEGER NOT NULL,
+		bst             INTEGER NOT NULL,
+		type1_id        INTEGER NOT NULL REFERENCES types(id),
+		type2_id        INTEGER NOT NULL REFERENCES types(id),
+		catch_rate      INTEGER NOT NULL,
+		exp_yield       INTEGER NOT NULL,
+		held_item1_id   INTEGER NOT NULL REFERENCES items(id),
+		held_item2_id   INTEGER NOT NULL REFERENCES items(id),
+		gender_ratio    INTEGER NOT NULL,
+		egg_cycles      INTEGER NOT NULL,
+		base_friendship INTEGER NOT NULL,
+		level_type      TEXT NOT NULL,
+		egg_group1      TEXT NOT NULL,
+		egg_group2      TEXT NOT NULL,
+		ability1_id     INTEGER NOT NULL REFERENCES abilities(id),
+		ability2_id     INTEGER NOT NULL REFERENCES abilities(id),
+		safari_rate     INTEGER NOT NULL,
+		color           TEXT NOT NULL
+	)`,
+	`CREATE TABLE tms (
+		id      INTEGER PRIMARY KEY,
+		name    TEXT NOT NULL,
+		move_id INTEGER NOT NULL REFERENCES moves(id)
+	)`,
+	// Moves learned by level have a level, and moves learned by TM have a
+	// TM.
+	`CREATE TABLE species_moves (
+		species_id INTEGER NOT NULL REFERENCES species(id),
+		move_id    INTEGER NOT NULL REFERENCES moves(id),
+		method     TEXT NOT NULL CHECK (method IN ('level', 'tm')),
+		level      INTEGER,
+		tm_id      INTEGER REFERENCES tms(id)
+	)`,
+	`CREATE TABLE evolutions (
+		species_id  INTEGER NOT NULL REFERENCES species(id),
+		target_id   INTEGER NOT NULL REFERENCES species(id),
+		method      INTEGER NOT NULL,
+		param       INTEGER NOT NULL,
+		description TEXT NOT NULL
+	)`,
+	// The id of a map is bank*256 + number.
+	`CREATE TABLE maps (
+		id     INTEGER PRIMARY KEY,
+		bank   INTEGER NOT NULL,
+		number INTEGER NOT NULL,
+		name   TEXT NOT NULL,
+		width  INTEGER NOT NULL,
+		height INTEGER NOT NULL,
+		UNIQUE (bank, number)
+	)`,
+	`CREATE TABLE connections (
+		map_id        INTEGER NOT NULL REFERENCES maps(id),
+		direction     TEXT NOT NULL,
+		offset        INTEGER NOT NULL,
+		target_map_id INTEGER NOT NULL REFERENCES maps(id)
+	)`,
+	`CREATE TABLE encounters (
+		map_id         INTEGER NOT NULL REFERENCES maps(id),
+		area           TEXT NOT NULL,
+		encounter_rate REAL NOT NULL,
+		slot           INTEGER NOT NULL,
+		species_id     INTEGER NOT NULL REFERENCES species(id),
+		min_level      INTEGER NOT NULL,
+		max_level      INTEGER NOT NULL,
+		rate           REAL NOT NULL
+	)`,
+}
+
+func mapID(bank, number int) int {
+	return bank*256 + number
+}
+
+// Executes statements, retaining the first error.
+type writer struct {
+	tx    *sql.Tx
+	stmts map[string]*sql.Stmt
+	err   error
+}
+
+func (w *writer) exec(query string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	stmt, ok := w.stmts[query]
+	if !ok {
+		if stmt, w.err = w.tx.Prepare(query); w.err != nil {
+			return
+		}
+		w.stmts[query] = stmt
+	}
+	_, w.err = stmt.Exec(args...)
+}
+
+func (w *writer) close() {
+	for _, stmt := range w.stmts {
+		stmt.Close()
+	}
+}
+
+// Write creates the tables of Schema in db, and fills them with the data of
+// v. Banks are scanned, if they have not been already. The tables are written
+// in a single transaction.
+func Write(db *sql.DB, v pkm.Version) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	w := &writer{tx: tx, stmts: map[string]*sql.Stmt{}}
+	write(w, v)
+	w.close()
+	if w.err != nil {
+		tx.Rollback()
+		return w.err
+	}
+	return tx.Commit()
+}
+
+// WriteFile writes v to the SQLite database at the given path, which is
+// created if it does not exist.
+func WriteFile(path string, v pkm.Version) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	if err := Write(db, v); err != nil {
+		db.Close()
+		return err
+	}
+	return db.Close()
+}
+
+func write(w *writer, v pkm.Version) {
+	for _, query := range Schema {
+		w.exec(query)
+	}
+
+	for t := 0; t < pkm.TypeIndexSize; t++ {
+		w.exec(`INSERT INTO types VALUES (?, ?)`, t, pkm.Type(t).String())
+	}
+	for i := 0; i < v.ItemIndexSize(); i++ {
+		item := v.ItemByIndex(i)
+		w.exec(`INSERT INTO items VALUES (?, ?, ?, ?)`,
+			i, item.Name(), item.Description(), item.Price())
+	}
+	for i := 0; i < v.AbilityIndexSize(); i++ {
+		a := v.AbilityByIndex(i)
+		w.exec(`INSERT INTO abilities VALUES (?, ?, ?)`,
+			i, a.Name(), a.Description())
+	}
+	// Move 0 is a placeholder with no description.
+	for i := 1; i < v.MoveIndexSize(); i++ {
+		m := v.MoveByIndex(i)
+		w.exec(`INSERT INTO moves VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			i, m.Name(), m.Description(), int(m.Type()), m.BasePower(),
+			m.Accuracy(), m.PowerPoints(), int(m.Effect()),
+			m.EffectAccuracy(), m.Affectee().String(), m.Priority(),
+			int(m.Flags()))
+	}
+	for i := 0; i < v.SpeciesIndexSize(); i++ {
+		s := v.SpeciesByIndex(i)
+		stats := s.BaseStats()
+		types := s.Type()
+		items := s.HeldItem()
+		groups := s.EggGroup()
+		abilities := s.Ability()
+		w.exec(`INSERT INTO species VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			i, s.Name(), s.Category(), s.Description(), int(s.Height()),
+			int(s.Weight()), stats.HitPoints, stats.Attack, stats.Defense,
+			stats.Speed, stats.SpAttack, stats.SpDefense, stats.Total(),
+			int(types[0]), int(types[1]), s.CatchRate(), s.ExpYield(),
+			items[0].Index(), items[1].Index(), int(s.GenderRatio()),
+			s.EggCycles(), s.BaseFriendship(), s.LevelType().String(),
+			groups[0].String(), groups[1].String(), abilities[0].Index(),
+			abilities[1].Index(), s.SafariRate(), s.Color().String())
+	}
+	for i := 0; i < v.TMIndexSize(); i++ {
+		tm := v.TMByIndex(i)
+		w.exec(`INSERT INTO tms VALUES (?, ?, ?)`, i, tm.Name(), tm.Move().Index())
+	}
+	for i := 0; i < v.SpeciesIndexSize(); i++ {
+		s := v.SpeciesByIndex(i)
+		for _, lm := range s.LearnedMoves() {
+			w.exec(`INSERT INTO species_moves VALUES (?, ?, 'level', ?, NULL)`,
+				i, lm.Move.Index(), lm.Level)
+		}
+		for _, tm := range s.LearnableTMs() {
+			w.exec(`INSERT INTO species_moves VALUES (?, ?, 'tm', NULL, ?)`,
+				i, tm.Move().Index(), tm.Index())
+		}
+		for _, ev := range s.Evolutions() {
+			w.exec(`INSERT INTO evolutions VALUES (?, ?, ?, ?, ?)`,
+				i, ev.Target().Index(), ev.Method(), ev.Param(), ev.MethodString())
+		}
+	}
+
+	v.ScanBanks()
+	for _, m := range v.AllMaps() {
+		id := mapID(m.BankIndex(), m.Index())
+		layout := m.Layout()
+		w.exec(`INSERT INTO maps VALUES (?, ?, ?, ?, ?, ?)`,
+			id, m.BankIndex(), m.Index(), m.Name(), layout.Width(), layout.Height())
+		for _, c := range m.Connections() {
+			w.exec(`INSERT INTO connections VALUES (?, ?, ?, ?)`,
+				id, c.Direction.String(), c.Offset, mapID(c.Bank, c.Map))
+		}
+		for _, list := range m.Encounters() {
+			if !list.Populated() {
+				continue
+			}
+			for slot, e := range list.Encounters() {
+				w.exec(`INSERT INTO encounters VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+					id, list.Name(), list.EncounterRate(), slot,
+					e.Species().Index(), e.MinLevel(), e.MaxLevel(),
+					list.SpeciesRate(slot))
+			}
+		}
+	}
+}
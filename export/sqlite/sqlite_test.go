@@ -0,0 +1,141 @@
+//go:build sqlite
+// +build sqlite
+
+package sqlite_test
+
+import (
+	"bytes"
+	"database/sql"
+	"github.com/anaminus/pkm/export/sqlite"
+	"github.com/anaminus/pkm/gen3"
+	"github.com/anaminus/pkm/gen3/romtest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests use the same optional ROM as the gen3 package. Tests that require it
+// are skipped when the file is missing.
+const ROMLocation = "../../gen3/rom.gba"
+
+func TestWriteFile(t *testing.T) {
+	b, err := ioutil.ReadFile(ROMLocation)
+	if err != nil {
+		t.Skipf("requires `%s` file, whose contents are a ROM dump of Pokemon Emerald (BPEE): %s", ROMLocation, err)
+	}
+	ver, err := gen3.OpenROM(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	dir, err := ioutil.TempDir("", "pkm")
+	if err != nil {
+		t.Fatalf("failed to create directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "emerald.db")
+	if err := sqlite.WriteFile(path, ver); err != nil {
+		t.Fatalf("WriteFile: unexpected error: %s", err)
+	}
+	// Tables are replaced when written again.
+	if err := sqlite.WriteFile(path, ver); err != nil {
+		t.Fatalf("WriteFile: unexpected error: %s", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("failed to open database: %s", err)
+	}
+	defer db.Close()
+
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM species`).Scan(&n); err != nil || n != ver.SpeciesIndexSize() {
+		t.Errorf("species: unexpected count %d (%v)", n, err)
+	}
+	var bst int
+	if err := db.QueryRow(`SELECT bst FROM species WHERE name = 'SCEPTILE'`).Scan(&bst); err != nil || bst != 530 {
+		t.Errorf("species: unexpected BST %d (%v)", bst, err)
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM species_moves WHERE method = 'tm'`).Scan(&n); err != nil || n == 0 {
+		t.Errorf("species_moves: unexpected TM count %d (%v)", n, err)
+	}
+
+	rows, err := db.Query(`SELECT DISTINCT s.name FROM species s
+		JOIN types t ON t.id IN (s.type1_id, s.type2_id)
+		JOIN encounters e ON e.species_id = s.id
+		JOIN maps m ON m.id = e.map_id
+		WHERE t.name = 'Water' AND s.bst < 300 AND m.name LIKE 'ROUTE %'`)
+	if err != nil {
+		t.Fatalf("query: unexpected error: %s", err)
+	}
+	defer rows.Close()
+	found := map[string]bool{}
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		found[name] = true
+	}
+	if !found["WINGULL"] || !found["MAGIKARP"] {
+		t.Errorf("query: unexpected result %v", found)
+	}
+}
+
+func TestWriteFileSynthetic(t *testing.T) {
+	ver, err := gen3.OpenROM(bytes.NewReader(romtest.Build()))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	dir, err := ioutil.TempDir("", "pkm")
+	if err != nil {
+		t.Fatalf("failed to create directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "emerald.db")
+	if err := sqlite.WriteFile(path, ver); err != nil {
+		t.Fatalf("WriteFile: unexpected error: %s", err)
+	}
+	// Tables are replaced when written again.
+	if err := sqlite.WriteFile(path, ver); err != nil {
+		t.Fatalf("WriteFile: unexpected error: %s", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("failed to open database: %s", err)
+	}
+	defer db.Close()
+
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM species`).Scan(&n); err != nil || n != ver.SpeciesIndexSize() {
+		t.Errorf("species: unexpected count %d (%v)", n, err)
+	}
+	// Base stats of 55 to 60.
+	var bst int
+	if err := db.QueryRow(`SELECT bst FROM species WHERE name = ?`, romtest.SpeciesName(254)).Scan(&bst); err != nil || bst != 345 {
+		t.Errorf("species: unexpected BST %d (%v)", bst, err)
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM species_moves WHERE method = 'tm'`).Scan(&n); err != nil || n == 0 {
+		t.Errorf("species_moves: unexpected TM count %d (%v)", n, err)
+	}
+
+	rows, err := db.Query(`SELECT DISTINCT s.name FROM species s
+		JOIN types t ON t.id IN (s.type1_id, s.type2_id)
+		JOIN encounters e ON e.species_id = s.id
+		JOIN maps m ON m.id = e.map_id
+		WHERE t.name = 'Water' AND s.bst < 300 AND m.name LIKE 'LABEL%'`)
+	if err != nil {
+		t.Fatalf("query: unexpected error: %s", err)
+	}
+	defer rows.Close()
+	found := map[string]bool{}
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		found[name] = true
+	}
+	if !found[romtest.SpeciesName(17)] || !found[romtest.SpeciesName(41)] || found[romtest.SpeciesName(81)] {
+		t.Errorf("query: unexpected result %v", found)
+	}
+}
@@ -0,0 +1,152 @@
+// Package compress implements the compression formats supported by the GBA
+// BIOS: LZ77, Huffman, RLE, and the Diff8 and Diff16 filters.
+//
+// Each format begins with a 4-byte header that contains the type of the
+// format and the size of the decompressed data. Decompress reads data of any
+// type:
+//
+//	r := io.NewSectionReader(rom, off, size)
+//	b, err := compress.Decompress(r)
+//
+// Decompressors read no further than the end of the compressed data.
+package compress
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrType is returned when data does not have the type expected by a
+	// decompressor.
+	ErrType = errors.New("unexpected compression type")
+	// ErrFormat is returned when compressed data is malformed.
+	ErrFormat = errors.New("invalid compressed data")
+	// ErrSize is returned when data is too large to be compressed.
+	ErrSize = errors.New("data too large to compress")
+)
+
+// MaxSize is the maximum size of decompressed data.
+const MaxSize = 0xFFFFFF
+
+// Type indicates the compression format of data.
+type Type byte
+
+const (
+	LZ77    Type = 0x1
+	Huffman Type = 0x2
+	RLE     Type = 0x3
+	Diff    Type = 0x8
+)
+
+func (t Type) String() string {
+	switch t {
+	case LZ77:
+		return "LZ77"
+	case Huffman:
+		return "Huffman"
+	case RLE:
+		return "RLE"
+	case Diff:
+		return "Diff"
+	}
+	return "Unknown"
+}
+
+// Header is the header of compressed data.
+type Header struct {
+	Type Type
+	// Parameter of the format. For Huffman, the number of bits in each data
+	// unit. For Diff, the number of bytes in each data unit.
+	Param byte
+	// Size of the decompressed data.
+	Size int
+}
+
+// ReadHeader reads the header of compressed data.
+func ReadHeader(r io.Reader) (h Header, err error) {
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return h, err
+	}
+	h.Param = b[0] & 0xF
+	h.Type = Type(b[0] >> 4)
+	h.Size = int(binary.LittleEndian.Uint32(b[:]) >> 8)
+	return h, nil
+}
+
+// Appends the encoded header to b.
+func appendHeader(b []byte, h Header) []byte {
+	return append(b, byte(h.Type)<<4|h.Param&0xF, byte(h.Size), byte(h.Size>>8), byte(h.Size>>16))
+}
+
+// Decompress reads compressed data of any type, returning the decompressed
+// data.
+func Decompress(r io.Reader) ([]byte, error) {
+	h, err := ReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	return decompress(h, r)
+}
+
+// Decompresses data of the type given by a header.
+func decompress(h Header, r io.Reader) (b []byte, err error) {
+	br := newByteReader(r)
+	switch h.Type {
+	case LZ77:
+		b, err = decompressLZ77(h, br)
+	case Huffman:
+		b, err = decompressHuffman(h, r)
+	case RLE:
+		b, err = decompressRLE(h, br)
+	case Diff:
+		b, err = unfilterDiff(h, br)
+	default:
+		return nil, fmt.Errorf("%w 0x%X", ErrType, byte(h.Type))
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return b, err
+}
+
+// Decompresses data of an expected type.
+func decompressType(t Type, r io.Reader) ([]byte, error) {
+	h, err := ReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if h.Type != t {
+		return nil, fmt.Errorf("%w %s, expected %s", ErrType, h.Type, t)
+	}
+	return decompress(h, r)
+}
+
+// Returns an error if data of size n cannot be compressed.
+func checkSize(n int) error {
+	if n > MaxSize {
+		return ErrSize
+	}
+	return nil
+}
+
+// Reads bytes one at a time, so that no more than the compressed data is read.
+type byteReader struct {
+	r io.Reader
+	b [1]byte
+}
+
+func newByteReader(r io.Reader) io.ByteReader {
+	if br, ok := r.(io.ByteReader); ok {
+		return br
+	}
+	return &byteReader{r: r}
+}
+
+func (r *byteReader) ReadByte() (byte, error) {
+	_, err := io.ReadFull(r.r, r.b[:])
+	return r.b[0], err
+}
@@ -0,0 +1,182 @@
+package compress_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/anaminus/pkm/gba/compress"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// Returns named inputs to be compressed.
+func inputs() map[string][]byte {
+	rnd := rand.New(rand.NewSource(1))
+	in := map[string][]byte{
+		"empty":  {},
+		"single": {0x42},
+		"zeros":  make([]byte, 0x2000),
+	}
+
+	random := make([]byte, 0x4000)
+	rnd.Read(random)
+	in["random"] = random
+
+	// Tile data: few distinct values with repetition.
+	tiles := make([]byte, 0x8000)
+	for i := range tiles {
+		tiles[i] = byte(i/32%7) | byte(rnd.Intn(3))<<4
+	}
+	in["tiles"] = tiles
+
+	// Runs of varying length.
+	var runs []byte
+	for i := 0; i < 300; i++ {
+		v := byte(rnd.Intn(256))
+		for n := rnd.Intn(200); n >= 0; n-- {
+			runs = append(runs, v)
+		}
+	}
+	in["runs"] = runs
+
+	// Every byte value with skewed weights, producing a deep tree.
+	var skewed []byte
+	for v := 0; v < 256; v++ {
+		for n := 0; n < 1+v*v/64; n++ {
+			skewed = append(skewed, byte(v))
+		}
+	}
+	rnd.Shuffle(len(skewed), func(i, j int) { skewed[i], skewed[j] = skewed[j], skewed[i] })
+	in["skewed"] = skewed
+
+	// Values with weights of a Fibonacci sequence, producing a maximally
+	// unbalanced tree.
+	var fib []byte
+	for v, a, b := 0, 1, 1; v < 24; v, a, b = v+1, b, a+b {
+		for n := 0; n < a; n++ {
+			fib = append(fib, byte(v))
+		}
+	}
+	in["fibonacci"] = fib
+
+	// An even input for Diff16.
+	ramp := make([]byte, 0x1000)
+	for i := 0; i < len(ramp); i += 2 {
+		ramp[i], ramp[i+1] = byte(i*3), byte(i>>4)
+	}
+	in["ramp"] = ramp
+	return in
+}
+
+// Compressor of a format, and the decompressor specific to the format.
+var formats = []struct {
+	name       string
+	compress   func([]byte) ([]byte, error)
+	decompress func(io.Reader) ([]byte, error)
+}{
+	{"LZ77", compress.CompressLZ77, compress.DecompressLZ77},
+	{"LZ77VRAM", compress.CompressLZ77VRAM, compress.DecompressLZ77},
+	{"Huffman4", func(b []byte) ([]byte, error) { return compress.CompressHuffman(b, 4) }, compress.DecompressHuffman},
+	{"Huffman8", func(b []byte) ([]byte, error) { return compress.CompressHuffman(b, 8) }, compress.DecompressHuffman},
+	{"RLE", compress.CompressRLE, compress.DecompressRLE},
+	{"Diff8", compress.FilterDiff8, compress.UnfilterDiff},
+	{"Diff16", compress.FilterDiff16, compress.UnfilterDiff},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for name, in := range inputs() {
+		for _, f := range formats {
+			if f.name == "Diff16" && len(in)%2 != 0 {
+				continue
+			}
+			c, err := f.compress(in)
+			if err != nil {
+				t.Errorf("%s: %s: compress: %s", f.name, name, err)
+				continue
+			}
+			out, err := f.decompress(bytes.NewReader(c))
+			if err != nil {
+				t.Errorf("%s: %s: decompress: %s", f.name, name, err)
+			} else if !bytes.Equal(out, in) {
+				t.Errorf("%s: %s: decompressed data does not match", f.name, name)
+			}
+			// Decompress detects the format, and reads no further than the
+			// compressed data.
+			r := bytes.NewReader(append(c, 0xAA))
+			if out, err := compress.Decompress(r); err != nil || !bytes.Equal(out, in) {
+				t.Errorf("%s: %s: Decompress: unexpected result (%v)", f.name, name, err)
+			} else if r.Len() != 1 {
+				t.Errorf("%s: %s: Decompress: read %d bytes beyond data", f.name, name, 1-r.Len())
+			}
+		}
+	}
+}
+
+func TestLZ77(t *testing.T) {
+	in := inputs()["zeros"]
+	c, _ := compress.CompressLZ77(in)
+	if len(c) > len(in)/8 {
+		t.Errorf("CompressLZ77: unexpected size %d", len(c))
+	}
+
+	// A VRAM-safe reference never points to the previous byte.
+	c, _ = compress.CompressLZ77VRAM(in)
+	for p := 5; p < len(c); {
+		flags := c[p-1]
+		for i := 0; i < 8 && p < len(c); i++ {
+			if flags&(0x80>>uint(i)) == 0 {
+				p++
+				continue
+			}
+			if disp := int(c[p]&0xF)<<8 | int(c[p+1]); disp == 0 {
+				t.Fatalf("CompressLZ77VRAM: reference to previous byte at %d", p)
+			}
+			p += 2
+		}
+		p++
+	}
+
+	// Reference before the start of the data.
+	if _, err := compress.Decompress(bytes.NewReader([]byte{0x10, 4, 0, 0, 0x80, 0x10, 0x00})); !errors.Is(err, compress.ErrFormat) {
+		t.Errorf("DecompressLZ77: expected ErrFormat, got %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c, _ := compress.CompressRLE(inputs()["runs"])
+	if _, err := compress.DecompressLZ77(bytes.NewReader(c)); !errors.Is(err, compress.ErrType) {
+		t.Errorf("DecompressLZ77: expected ErrType, got %v", err)
+	}
+	if _, err := compress.Decompress(bytes.NewReader([]byte{0x50, 1, 0, 0})); !errors.Is(err, compress.ErrType) {
+		t.Errorf("Decompress: expected ErrType, got %v", err)
+	}
+	if _, err := compress.Decompress(bytes.NewReader(nil)); err != io.EOF {
+		t.Errorf("Decompress: expected EOF, got %v", err)
+	}
+	for _, f := range formats {
+		c, _ := f.compress(inputs()["tiles"])
+		if _, err := f.decompress(bytes.NewReader(c[:len(c)/2])); err != io.ErrUnexpectedEOF {
+			t.Errorf("%s: expected ErrUnexpectedEOF, got %v", f.name, err)
+		}
+	}
+	if _, err := compress.CompressHuffman(nil, 2); err == nil {
+		t.Errorf("CompressHuffman: expected error")
+	}
+	if _, err := compress.FilterDiff16([]byte{1, 2, 3}); err == nil {
+		t.Errorf("FilterDiff16: expected error")
+	}
+}
+
+func TestHeader(t *testing.T) {
+	c, _ := compress.CompressHuffman(make([]byte, 0x123456), 4)
+	h, err := compress.ReadHeader(bytes.NewReader(c))
+	if err != nil {
+		t.Fatalf("ReadHeader: %s", err)
+	}
+	if h.Type != compress.Huffman || h.Param != 4 || h.Size != 0x123456 {
+		t.Errorf("ReadHeader: unexpected result %+v", h)
+	}
+	if _, err := compress.CompressRLE(make([]byte, compress.MaxSize+1)); err != compress.ErrSize {
+		t.Errorf("CompressRLE: expected ErrSize, got %v", err)
+	}
+}
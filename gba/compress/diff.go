@@ -0,0 +1,77 @@
+package compress
+
+import (
+	"fmt"
+	"io"
+)
+
+// UnfilterDiff reads data filtered with Diff8 or Diff16, returning the
+// unfiltered data. Returns ErrType if the data is not filtered.
+func UnfilterDiff(r io.Reader) ([]byte, error) {
+	return decompressType(Diff, r)
+}
+
+// Each unit of data, except the first, is stored as the difference from the
+// previous unit. The parameter of the header is the size of each unit in
+// bytes.
+func unfilterDiff(h Header, r io.ByteReader) ([]byte, error) {
+	if h.Param != 1 && h.Param != 2 {
+		return nil, fmt.Errorf("%w: Diff unit size %d", ErrFormat, h.Param)
+	}
+	b := make([]byte, h.Size)
+	for i := range b {
+		v, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		b[i] = v
+	}
+	if h.Param == 1 {
+		for i := 1; i < len(b); i++ {
+			b[i] += b[i-1]
+		}
+		return b, nil
+	}
+	for i := 2; i+1 < len(b); i += 2 {
+		v := uint16(b[i]) | uint16(b[i+1])<<8
+		v += uint16(b[i-2]) | uint16(b[i-1])<<8
+		b[i], b[i+1] = byte(v), byte(v>>8)
+	}
+	return b, nil
+}
+
+// FilterDiff8 returns b filtered with Diff8, where each byte is stored as the
+// difference from the previous byte.
+func FilterDiff8(b []byte) ([]byte, error) {
+	if err := checkSize(len(b)); err != nil {
+		return nil, err
+	}
+	out := appendHeader(nil, Header{Type: Diff, Param: 1, Size: len(b)})
+	var prev byte
+	for _, v := range b {
+		out = append(out, v-prev)
+		prev = v
+	}
+	return out, nil
+}
+
+// FilterDiff16 returns b filtered with Diff16, where each 16-bit unit is
+// stored as the difference from the previous unit. The length of b must be
+// even.
+func FilterDiff16(b []byte) ([]byte, error) {
+	if len(b)%2 != 0 {
+		return nil, fmt.Errorf("odd length %d for Diff16", len(b))
+	}
+	if err := checkSize(len(b)); err != nil {
+		return nil, err
+	}
+	out := appendHeader(nil, Header{Type: Diff, Param: 2, Size: len(b)})
+	var prev uint16
+	for i := 0; i < len(b); i += 2 {
+		v := uint16(b[i]) | uint16(b[i+1])<<8
+		d := v - prev
+		out = append(out, byte(d), byte(d>>8))
+		prev = v
+	}
+	return out, nil
+}
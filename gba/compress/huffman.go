@@ -0,0 +1,253 @@
+package compress
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Maximum offset of a tree node to its children.
+const huffMaxOffset = 0x3F
+
+// Flag of a tree node indicating that its first child is a data node. The
+// flag of the second child is the next lower bit.
+const huffData0 = 0x80
+
+// DecompressHuffman reads Huffman-compressed data, returning the decompressed
+// data. Returns ErrType if the data is not Huffman-compressed.
+func DecompressHuffman(r io.Reader) ([]byte, error) {
+	return decompressType(Huffman, r)
+}
+
+// The header is followed by a tree table, then a bitstream of 32-bit units.
+// The first byte of the table is the size of the table in 2-byte units, minus
+// one. The second byte is the root node.
+//
+// The children of a node are adjacent. The lower 6 bits of a node are an
+// offset to its children, which are located at (addr &^ 1) + offset*2 + 2.
+// The upper 2 bits indicate whether each child is a data node, which contains
+// a decompressed value, rather than another node.
+//
+// Each bit of the bitstream, starting from the most significant bit of each
+// unit, selects a child, starting from the root, until a data node is reached.
+func decompressHuffman(h Header, r io.Reader) ([]byte, error) {
+	if h.Param != 4 && h.Param != 8 {
+		return nil, fmt.Errorf("%w: Huffman data size %d", ErrFormat, h.Param)
+	}
+	var size [1]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	tree := make([]byte, (int(size[0])+1)*2)
+	if _, err := io.ReadFull(r, tree[1:]); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, h.Size)
+	// Number of values to decode.
+	n := h.Size
+	if h.Param == 4 {
+		n *= 2
+	}
+	node := 1
+	var unit [4]byte
+	for i := 0; i < n; {
+		if _, err := io.ReadFull(r, unit[:]); err != nil {
+			return nil, err
+		}
+		bits := binary.LittleEndian.Uint32(unit[:])
+		for j := 0; j < 32 && i < n; j++ {
+			bit := int(bits >> 31)
+			bits <<= 1
+			child := node&^1 + int(tree[node]&huffMaxOffset)*2 + 2 + bit
+			if child >= len(tree) {
+				return nil, ErrFormat
+			}
+			if tree[node]&(huffData0>>uint(bit)) == 0 {
+				node = child
+				continue
+			}
+			if h.Param == 4 {
+				// Values fill the lower half of each byte first.
+				b[i/2] |= (tree[child] & 0xF) << (uint(i%2) * 4)
+			} else {
+				b[i] = tree[child]
+			}
+			i++
+			node = 1
+		}
+	}
+	return b, nil
+}
+
+// A node of a Huffman tree.
+type huffNode struct {
+	weight int
+	// Order in which the node was created, to break ties between weights.
+	order int
+	// Data nodes have no children.
+	value    byte
+	children [2]*huffNode
+}
+
+func (n *huffNode) data() bool {
+	return n.children[0] == nil
+}
+
+// Assigns a code to each data node below n.
+func (n *huffNode) codes(codes []huffCode, c huffCode) {
+	if n.data() {
+		codes[n.value] = c
+		return
+	}
+	for i, child := range n.children {
+		child.codes(codes, huffCode{bits: c.bits<<1 | uint64(i), n: c.n + 1})
+	}
+}
+
+// The code of a value, as a sequence of n bits.
+type huffCode struct {
+	bits uint64
+	n    int
+}
+
+// CompressHuffman returns b compressed with Huffman. bits is the size of each
+// data unit, and must be 4 or 8.
+func CompressHuffman(b []byte, bits int) ([]byte, error) {
+	if bits != 4 && bits != 8 {
+		return nil, fmt.Errorf("invalid Huffman data size %d", bits)
+	}
+	if err := checkSize(len(b)); err != nil {
+		return nil, err
+	}
+
+	values := make([]byte, 0, len(b)*8/bits)
+	for _, v := range b {
+		if bits == 4 {
+			values = append(values, v&0xF, v>>4)
+		} else {
+			values = append(values, v)
+		}
+	}
+
+	root := buildHuffTree(values, 1<<uint(bits))
+	tree, err := encodeHuffTree(root)
+	if err != nil {
+		return nil, err
+	}
+	out := appendHeader(nil, Header{Type: Huffman, Param: byte(bits), Size: len(b)})
+	out = append(out, tree...)
+
+	codes := make([]huffCode, 1<<uint(bits))
+	root.codes(codes, huffCode{})
+	var unit uint32
+	var n uint
+	for _, v := range values {
+		c := codes[v]
+		for i := c.n - 1; i >= 0; i-- {
+			unit |= uint32(c.bits>>uint(i)&1) << (31 - n)
+			if n++; n == 32 {
+				out = appendUint32(out, unit)
+				unit, n = 0, 0
+			}
+		}
+	}
+	if n > 0 {
+		out = appendUint32(out, unit)
+	}
+	return out, nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+// Builds a Huffman tree of the values in a space of the given size.
+func buildHuffTree(values []byte, space int) *huffNode {
+	weights := make([]int, space)
+	for _, v := range values {
+		weights[v]++
+	}
+	var nodes []*huffNode
+	for v, w := range weights {
+		if w > 0 {
+			nodes = append(nodes, &huffNode{weight: w, order: len(nodes), value: byte(v)})
+		}
+	}
+	// The root must have two children, so data with fewer than two distinct
+	// values is padded with unused values.
+	for v := 0; len(nodes) < 2; v++ {
+		if weights[v] == 0 {
+			nodes = append(nodes, &huffNode{order: len(nodes), value: byte(v)})
+		}
+	}
+	order := len(nodes)
+	// Removes and returns the node with the lowest weight.
+	pop := func() *huffNode {
+		min := 0
+		for i, n := range nodes {
+			if n.weight < nodes[min].weight || n.weight == nodes[min].weight && n.order < nodes[min].order {
+				min = i
+			}
+		}
+		n := nodes[min]
+		nodes = append(nodes[:min], nodes[min+1:]...)
+		return n
+	}
+	for len(nodes) > 1 {
+		a, b := pop(), pop()
+		nodes = append(nodes, &huffNode{weight: a.weight + b.weight, order: order, children: [2]*huffNode{a, b}})
+		order++
+	}
+	return nodes[0]
+}
+
+// Encodes a tree table, including the size. Children are placed so that the
+// offset from each node does not exceed huffMaxOffset. Children are placed
+// depth-first to keep the number of unplaced nodes low, except when older
+// unplaced nodes would otherwise run out of room.
+func encodeHuffTree(root *huffNode) ([]byte, error) {
+	type pending struct {
+		slot int
+		node *huffNode
+	}
+	tree := []byte{0, 0}
+	queue := []pending{{slot: 1, node: root}}
+	for len(queue) > 0 {
+		// Index of the pair of slots to be placed.
+		pair := len(tree) / 2
+		// The queue is ordered by slot. The newest node is placed unless
+		// doing so would leave too little room for the older nodes to be
+		// placed in order.
+		i := len(queue) - 1
+		for k, p := range queue[:len(queue)-1] {
+			if pair+k+1 > p.slot/2+1+huffMaxOffset {
+				i = 0
+				break
+			}
+		}
+		p := queue[i]
+		queue = append(queue[:i], queue[i+1:]...)
+
+		offset := pair - p.slot/2 - 1
+		if offset > huffMaxOffset {
+			return nil, fmt.Errorf("%w: Huffman tree too deep", ErrSize)
+		}
+		tree[p.slot] = byte(offset)
+		for j, child := range p.node.children {
+			if child.data() {
+				tree[p.slot] |= huffData0 >> uint(j)
+				tree = append(tree, child.value)
+				continue
+			}
+			tree = append(tree, 0)
+			queue = append(queue, pending{slot: len(tree) - 1, node: child})
+		}
+	}
+	// The bitstream is aligned to 4 bytes.
+	for len(tree)%4 != 0 {
+		tree = append(tree, 0)
+	}
+	tree[0] = byte(len(tree)/2 - 1)
+	return tree, nil
+}
@@ -0,0 +1,137 @@
+package compress
+
+import (
+	"io"
+)
+
+const (
+	// Range of the length of a reference.
+	lz77MinLength = 3
+	lz77MaxLength = 18
+	// Maximum distance of a reference.
+	lz77MaxDisp = 4096
+	// Size of the hash table used to find references.
+	lz77HashSize = 1 << 14
+)
+
+// DecompressLZ77 reads LZ77-compressed data, returning the decompressed data.
+// Returns ErrType if the data is not LZ77-compressed.
+func DecompressLZ77(r io.Reader) ([]byte, error) {
+	return decompressType(LZ77, r)
+}
+
+// Data is a sequence of blocks, each starting with a byte of flags. Each
+// flag, starting from the most significant bit, indicates whether the next
+// unit is a literal byte (0) or a 2-byte reference to previous output (1).
+func decompressLZ77(h Header, r io.ByteReader) ([]byte, error) {
+	b := make([]byte, h.Size)
+	for p := 0; p < len(b); {
+		flags, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < 8 && p < len(b); i++ {
+			if flags&(0x80>>uint(i)) == 0 {
+				if b[p], err = r.ReadByte(); err != nil {
+					return nil, err
+				}
+				p++
+				continue
+			}
+			hi, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			lo, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			n := int(hi>>4) + lz77MinLength
+			src := p - (int(hi&0xF)<<8 | int(lo)) - 1
+			if src < 0 {
+				return nil, ErrFormat
+			}
+			// The reference may overlap the output it produces.
+			for j := 0; j < n && p < len(b); j++ {
+				b[p] = b[src+j]
+				p++
+			}
+		}
+	}
+	return b, nil
+}
+
+// CompressLZ77 returns b compressed with LZ77.
+func CompressLZ77(b []byte) ([]byte, error) {
+	return compressLZ77(b, 1)
+}
+
+// CompressLZ77VRAM returns b compressed with LZ77, such that the data can be
+// decompressed directly to VRAM. VRAM is written in 16-bit units, so no
+// reference points to the byte immediately preceding it.
+func CompressLZ77VRAM(b []byte) ([]byte, error) {
+	return compressLZ77(b, 2)
+}
+
+// Compresses b with references that have a distance of at least minDisp.
+func compressLZ77(b []byte, minDisp int) ([]byte, error) {
+	if err := checkSize(len(b)); err != nil {
+		return nil, err
+	}
+	out := appendHeader(nil, Header{Type: LZ77, Size: len(b)})
+
+	// Positions are chained by the hash of the bytes that start at them.
+	head := make([]int, lz77HashSize)
+	for i := range head {
+		head[i] = -1
+	}
+	prev := make([]int, len(b))
+	hash := func(p int) int {
+		return (int(b[p])<<10 ^ int(b[p+1])<<5 ^ int(b[p+2])) & (lz77HashSize - 1)
+	}
+	insert := func(p int) {
+		if p+lz77MinLength <= len(b) {
+			h := hash(p)
+			prev[p] = head[h]
+			head[h] = p
+		}
+	}
+
+	for p := 0; p < len(b); {
+		flagPos := len(out)
+		out = append(out, 0)
+		for i := 0; i < 8 && p < len(b); i++ {
+			length, disp := 0, 0
+			if p+lz77MinLength <= len(b) {
+				for q := head[hash(p)]; q >= 0 && p-q <= lz77MaxDisp; q = prev[q] {
+					if p-q < minDisp {
+						continue
+					}
+					n := 0
+					for n < lz77MaxLength && p+n < len(b) && b[q+n] == b[p+n] {
+						n++
+					}
+					if n > length {
+						length, disp = n, p-q
+						if n == lz77MaxLength {
+							break
+						}
+					}
+				}
+			}
+			if length < lz77MinLength {
+				out = append(out, b[p])
+				insert(p)
+				p++
+				continue
+			}
+			out[flagPos] |= 0x80 >> uint(i)
+			out = append(out, byte((length-lz77MinLength)<<4|(disp-1)>>8), byte(disp-1))
+			for j := 0; j < length; j++ {
+				insert(p + j)
+			}
+			p += length
+		}
+	}
+	return out, nil
+}
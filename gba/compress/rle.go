@@ -0,0 +1,85 @@
+package compress
+
+import (
+	"io"
+)
+
+const (
+	// Range of the length of a run of identical bytes.
+	rleMinRun = 3
+	rleMaxRun = 0x7F + rleMinRun
+	// Maximum length of a sequence of literal bytes.
+	rleMaxLiteral = 0x80
+)
+
+// DecompressRLE reads RLE-compressed data, returning the decompressed data.
+// Returns ErrType if the data is not RLE-compressed.
+func DecompressRLE(r io.Reader) ([]byte, error) {
+	return decompressType(RLE, r)
+}
+
+// Data is a sequence of blocks, each starting with a flag byte. If the most
+// significant bit is set, the lower bits are the length of a run, minus 3,
+// followed by the repeated byte. Otherwise, the lower bits are the number of
+// literal bytes that follow, minus 1.
+func decompressRLE(h Header, r io.ByteReader) ([]byte, error) {
+	b := make([]byte, h.Size)
+	for p := 0; p < len(b); {
+		flag, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if flag&0x80 != 0 {
+			v, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			for n := int(flag&0x7F) + rleMinRun; n > 0 && p < len(b); n-- {
+				b[p] = v
+				p++
+			}
+			continue
+		}
+		for n := int(flag&0x7F) + 1; n > 0 && p < len(b); n-- {
+			if b[p], err = r.ReadByte(); err != nil {
+				return nil, err
+			}
+			p++
+		}
+	}
+	return b, nil
+}
+
+// Returns the length of the run of identical bytes at the start of b, up to
+// rleMaxRun.
+func runLength(b []byte) int {
+	n := 1
+	for n < len(b) && n < rleMaxRun && b[n] == b[0] {
+		n++
+	}
+	return n
+}
+
+// CompressRLE returns b compressed with RLE.
+func CompressRLE(b []byte) ([]byte, error) {
+	if err := checkSize(len(b)); err != nil {
+		return nil, err
+	}
+	out := appendHeader(nil, Header{Type: RLE, Size: len(b)})
+	for p := 0; p < len(b); {
+		if n := runLength(b[p:]); n >= rleMinRun {
+			out = append(out, 0x80|byte(n-rleMinRun), b[p])
+			p += n
+			continue
+		}
+		// Literal bytes up to the next run.
+		n := 0
+		for p+n < len(b) && n < rleMaxLiteral && runLength(b[p+n:]) < rleMinRun {
+			n++
+		}
+		out = append(out, byte(n-1))
+		out = append(out, b[p:p+n]...)
+		p += n
+	}
+	return out, nil
+}
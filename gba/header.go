@@ -0,0 +1,202 @@
+// Package gba reads the cartridge header of GameBoy Advance ROM images. The
+// header is independent of the game, and is checked by the GBA before a game
+// is run.
+package gba
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/anaminus/pkm"
+	"io"
+)
+
+// HeaderSize is the size of the cartridge header, which starts at the
+// beginning of a ROM.
+const HeaderSize = 0xC0
+
+// FixedValue is the value of Header.Fixed required by the GBA.
+const FixedValue = 0x96
+
+// Logo is the compressed Nintendo logo that must appear in the header.
+var Logo = [156]byte{
+	0x24, 0xFF, 0xAE, 0x51, 0x69, 0x9A, 0xA2, 0x21, 0x3D, 0x84, 0x82, 0x0A,
+	0x84, 0xE4, 0x09, 0xAD, 0x11, 0x24, 0x8B, 0x98, 0xC0, 0x81, 0x7F, 0x21,
+	0xA3, 0x52, 0xBE, 0x19, 0x93, 0x09, 0xCE, 0x20, 0x10, 0x46, 0x4A, 0x4A,
+	0xF8, 0x27, 0x31, 0xEC, 0x58, 0xC7, 0xE8, 0x33, 0x82, 0xE3, 0xCE, 0xBF,
+	0x85, 0xF4, 0xDF, 0x94, 0xCE, 0x4B, 0x09, 0xC1, 0x94, 0x56, 0x8A, 0xC0,
+	0x13, 0x72, 0xA7, 0xFC, 0x9F, 0x84, 0x4D, 0x73, 0xA3, 0xCA, 0x9A, 0x61,
+	0x58, 0x97, 0xA3, 0x27, 0xFC, 0x03, 0x98, 0x76, 0x23, 0x1D, 0xC7, 0x61,
+	0x03, 0x04, 0xAE, 0x56, 0xBF, 0x38, 0x84, 0x00, 0x40, 0xA7, 0x0E, 0xFD,
+	0xFF, 0x52, 0xFE, 0x03, 0x6F, 0x95, 0x30, 0xF1, 0x97, 0xFB, 0xC0, 0x85,
+	0x60, 0xD6, 0x80, 0x25, 0xA9, 0x63, 0xBE, 0x03, 0x01, 0x4E, 0x38, 0xE2,
+	0xF9, 0xA2, 0x34, 0xFF, 0xBB, 0x3E, 0x03, 0x44, 0x78, 0x00, 0x90, 0xCB,
+	0x88, 0x11, 0x3A, 0x94, 0x65, 0xC0, 0x7C, 0x63, 0x87, 0xF0, 0x3C, 0xAF,
+	0xD6, 0x25, 0xE4, 0x8B, 0x38, 0x0A, 0xAC, 0x72, 0x21, 0xD4, 0xF8, 0x07,
+}
+
+var (
+	// ErrLogo is returned when a header does not contain the Nintendo logo.
+	ErrLogo = errors.New("header does not contain the Nintendo logo")
+	// ErrFixed is returned when the fixed value of a header is not
+	// FixedValue.
+	ErrFixed = errors.New("header fixed value is not 0x96")
+	// ErrComplement is returned when the complement check of a header does
+	// not match the contents of the header.
+	ErrComplement = errors.New("header complement check mismatch")
+)
+
+// Address at which the ROM is mapped.
+const addrROM = 0x08000000
+
+// Range of bytes covered by the complement check.
+const (
+	complementStart = 0xA0
+	complementEnd   = 0xBD
+)
+
+// Header is the cartridge header of a ROM.
+type Header struct {
+	// ARM instruction at the entry point, which is normally a branch to the
+	// start of the program.
+	Entry uint32
+	Logo  [156]byte
+	// Title of the game, in uppercase ASCII, padded with zeros.
+	Title    [12]byte
+	GameCode pkm.GameCode
+	// Code of the developer of the game.
+	MakerCode [2]byte
+	// Must be FixedValue.
+	Fixed      byte
+	UnitCode   byte
+	DeviceType byte
+	Reserved   [7]byte
+	// Version of the game.
+	Version byte
+	// Complement check of the bytes from Title to Version.
+	Complement byte
+	Reserved2  [2]byte
+}
+
+// NewHeader returns a valid header with the given game code and title. The
+// entry point branches to the end of the header.
+func NewHeader(gc pkm.GameCode, title string) Header {
+	h := Header{
+		// b 0x080000C0
+		Entry:    0xEA000000 | (HeaderSize-8)/4,
+		Logo:     Logo,
+		GameCode: gc,
+		Fixed:    FixedValue,
+	}
+	copy(h.Title[:], title)
+	h.Complement = h.ComputeComplement()
+	return h
+}
+
+// DecodeHeader decodes a header from the first HeaderSize bytes of b.
+func DecodeHeader(b []byte) (h Header, err error) {
+	if len(b) < HeaderSize {
+		return h, fmt.Errorf("header requires %d bytes, got %d", HeaderSize, len(b))
+	}
+	h.Entry = uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+	copy(h.Logo[:], b[0x04:])
+	copy(h.Title[:], b[0xA0:])
+	copy(h.GameCode[:], b[0xAC:])
+	copy(h.MakerCode[:], b[0xB0:])
+	h.Fixed = b[0xB2]
+	h.UnitCode = b[0xB3]
+	h.DeviceType = b[0xB4]
+	copy(h.Reserved[:], b[0xB5:])
+	h.Version = b[0xBC]
+	h.Complement = b[0xBD]
+	copy(h.Reserved2[:], b[0xBE:])
+	return h, nil
+}
+
+// ReadHeader reads a header from the start of r.
+func ReadHeader(r io.ReaderAt) (h Header, err error) {
+	b := make([]byte, HeaderSize)
+	if n, err := r.ReadAt(b, 0); n < len(b) {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return h, err
+	}
+	return DecodeHeader(b)
+}
+
+// Bytes returns the encoded header.
+func (h Header) Bytes() []byte {
+	b := make([]byte, HeaderSize)
+	b[0], b[1], b[2], b[3] = byte(h.Entry), byte(h.Entry>>8), byte(h.Entry>>16), byte(h.Entry>>24)
+	copy(b[0x04:], h.Logo[:])
+	copy(b[0xA0:], h.Title[:])
+	copy(b[0xAC:], h.GameCode[:])
+	copy(b[0xB0:], h.MakerCode[:])
+	b[0xB2] = h.Fixed
+	b[0xB3] = h.UnitCode
+	b[0xB4] = h.DeviceType
+	copy(b[0xB5:], h.Reserved[:])
+	b[0xBC] = h.Version
+	b[0xBD] = h.Complement
+	copy(b[0xBE:], h.Reserved2[:])
+	return b
+}
+
+// TitleString returns the title without padding.
+func (h Header) TitleString() string {
+	return string(bytes.TrimRight(h.Title[:], "\x00"))
+}
+
+// EntryPoint returns the address to which the entry point branches. ok is
+// false if the entry point is not a branch instruction.
+func (h Header) EntryPoint() (addr uint32, ok bool) {
+	if h.Entry>>24 != 0xEA {
+		return 0, false
+	}
+	// Signed 24-bit offset in words, relative to the instruction after next.
+	off := int32(h.Entry<<8) >> 6
+	return uint32(int32(addrROM+8) + off), true
+}
+
+// ComputeComplement returns the complement check of the header, computed from
+// its contents.
+func (h Header) ComputeComplement() byte {
+	var c byte
+	for _, v := range h.Bytes()[complementStart:complementEnd] {
+		c -= v
+	}
+	return c - 0x19
+}
+
+// Validate returns an error if the header would be rejected by the GBA.
+func (h Header) Validate() error {
+	if h.Logo != Logo {
+		return ErrLogo
+	}
+	if h.Fixed != FixedValue {
+		return fmt.Errorf("%w: 0x%02X", ErrFixed, h.Fixed)
+	}
+	if c := h.ComputeComplement(); h.Complement != c {
+		return fmt.Errorf("%w: 0x%02X, expected 0x%02X", ErrComplement, h.Complement, c)
+	}
+	return nil
+}
+
+// FixComplement recomputes the complement check of the header of a ROM, and
+// writes it to the ROM if it differs.
+func FixComplement(rom interface {
+	io.ReaderAt
+	io.WriterAt
+}) error {
+	h, err := ReadHeader(rom)
+	if err != nil {
+		return err
+	}
+	c := h.ComputeComplement()
+	if h.Complement == c {
+		return nil
+	}
+	_, err = rom.WriteAt([]byte{c}, complementEnd)
+	return err
+}
@@ -0,0 +1,74 @@
+package gba_test
+
+import (
+	"errors"
+	"github.com/anaminus/pkm"
+	"github.com/anaminus/pkm/gba"
+	"github.com/anaminus/pkm/gen3"
+	"testing"
+)
+
+func TestHeader(t *testing.T) {
+	h := gba.NewHeader(pkm.GameCode{'B', 'P', 'E', 'E'}, "POKEMON EMER")
+	if err := h.Validate(); err != nil {
+		t.Fatalf("Validate: unexpected error: %s", err)
+	}
+	// Complement of the header of Pokemon Emerald (English).
+	h.MakerCode = [2]byte{'0', '1'}
+	if v := h.ComputeComplement(); v != 0x72 {
+		t.Errorf("ComputeComplement: unexpected result 0x%02X", v)
+	}
+	if v := h.TitleString(); v != "POKEMON EMER" {
+		t.Errorf("TitleString: unexpected result %q", v)
+	}
+	if addr, ok := h.EntryPoint(); !ok || addr != 0x080000C0 {
+		t.Errorf("EntryPoint: unexpected result 0x%08X, %t", addr, ok)
+	}
+
+	b := h.Bytes()
+	if len(b) != gba.HeaderSize {
+		t.Fatalf("Bytes: unexpected length %d", len(b))
+	}
+	d, err := gba.DecodeHeader(b)
+	if err != nil {
+		t.Fatalf("DecodeHeader: unexpected error: %s", err)
+	}
+	if d != h {
+		t.Errorf("DecodeHeader: result does not match encoded header")
+	}
+	if _, err := gba.DecodeHeader(b[:0xBF]); err == nil {
+		t.Errorf("DecodeHeader: expected error for short header")
+	}
+
+	if err := d.Validate(); !errors.Is(err, gba.ErrComplement) {
+		t.Errorf("Validate: expected ErrComplement, got %v", err)
+	}
+	d.Fixed = 0
+	if err := d.Validate(); !errors.Is(err, gba.ErrFixed) {
+		t.Errorf("Validate: expected ErrFixed, got %v", err)
+	}
+	d.Logo[0] = 0
+	if err := d.Validate(); !errors.Is(err, gba.ErrLogo) {
+		t.Errorf("Validate: expected ErrLogo, got %v", err)
+	}
+}
+
+func TestFixComplement(t *testing.T) {
+	b := make([]byte, 0x200)
+	copy(b, gba.NewHeader(pkm.GameCode{'B', 'P', 'E', 'E'}, "POKEMON EMER").Bytes())
+	b[0xBC] = 1
+	buf := gen3.NewBuffer(b)
+	if _, err := gba.ReadHeader(buf); err != nil {
+		t.Fatalf("ReadHeader: unexpected error: %s", err)
+	}
+	if err := gba.FixComplement(buf); err != nil {
+		t.Fatalf("FixComplement: unexpected error: %s", err)
+	}
+	h, _ := gba.ReadHeader(buf)
+	if err := h.Validate(); err != nil {
+		t.Errorf("FixComplement: unexpected error after fix: %s", err)
+	}
+	if _, err := gba.ReadHeader(gen3.NewBuffer(b[:0x80])); err == nil {
+		t.Errorf("ReadHeader: expected error for short ROM")
+	}
+}
@@ -21,6 +21,20 @@ func (a Ability) Name() string {
 	return decodeTextString(b)
 }
 
+// SetName sets the name of the ability. Returns a *NameError if the name does
+// not fit.
+func (a Ability) SetName(name string) error {
+	b, err := encodeName(name, structAbilityName.Size())
+	if err != nil {
+		return err
+	}
+	w, err := a.v.writer()
+	if err != nil {
+		return err
+	}
+	return writeStruct(w, a.v.AddrAbilityName, a.i, structAbilityName, b)
+}
+
 func (a Ability) Index() int {
 	return a.i
 }
@@ -32,6 +46,5 @@ func (a Ability) Description() string {
 		a.i,
 		structPtr,
 	)
-	a.v.ROM.Seek(decPtr(b).ROM(), 0)
-	return readTextString(a.v.ROM)
+	return readTextString(a.v.reader(decPtr(b).ROM()))
 }
@@ -2,13 +2,14 @@ package gen3_test
 
 import (
 	"github.com/anaminus/pkm/gen3"
+	"github.com/anaminus/pkm/gen3/romtest"
 	"testing"
 )
 
 func TestAbility(t *testing.T) {
-	ver := gen3.OpenROM(ROM(t))
-	if ver == nil {
-		t.Fatalf("failed to open ROM")
+	ver, err := gen3.OpenROM(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
 	}
 
 	ability := ver.AbilityByIndex(1)
@@ -22,3 +23,21 @@ func TestAbility(t *testing.T) {
 		t.Errorf("Description: unexpected result \"%s\"", v)
 	}
 }
+
+func TestAbilitySynthetic(t *testing.T) {
+	ver, err := gen3.OpenROM(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	ability := ver.AbilityByIndex(1)
+	if v := ability.Index(); v != 1 {
+		t.Errorf("Index: unexpected result %d", v)
+	}
+	if v := ability.Name(); v != romtest.AbilityName(1) {
+		t.Errorf("Name: unexpected result \"%s\"", v)
+	}
+	if v := ability.Description(); v != romtest.AbilityDescription(1) {
+		t.Errorf("Description: unexpected result \"%s\"", v)
+	}
+}
@@ -0,0 +1,124 @@
+package gen3
+
+import (
+	"errors"
+	"io"
+)
+
+// Buffer is an in-memory ROM image that implements io.ReadWriteSeeker,
+// io.ReaderAt and io.WriterAt. It can be passed to OpenROM to create a
+// writable Version without modifying the original file.
+//
+// ReadAt is safe for concurrent use, but only while the Buffer is not being
+// written to.
+type Buffer struct {
+	b   []byte
+	off int64
+}
+
+// NewBuffer creates a Buffer that uses b as its contents.
+func NewBuffer(b []byte) *Buffer {
+	return &Buffer{b: b}
+}
+
+// ReadBuffer creates a Buffer from the entire contents of r.
+func ReadBuffer(r io.Reader) (*Buffer, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Buffer{b: b}, nil
+}
+
+// Bytes returns the contents of the buffer.
+func (b *Buffer) Bytes() []byte {
+	return b.b
+}
+
+// Len returns the size of the buffer.
+func (b *Buffer) Len() int {
+	return len(b.b)
+}
+
+// Size returns the size of the buffer.
+func (b *Buffer) Size() int64 {
+	return int64(len(b.b))
+}
+
+func (b *Buffer) Read(p []byte) (n int, err error) {
+	if b.off >= int64(len(b.b)) {
+		return 0, io.EOF
+	}
+	n = copy(p, b.b[b.off:])
+	b.off += int64(n)
+	return n, nil
+}
+
+// ReadAt reads len(p) bytes starting at offset off.
+func (b *Buffer) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if off >= int64(len(b.b)) {
+		return 0, io.EOF
+	}
+	n = copy(p, b.b[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Write writes p at the current offset, growing the buffer if needed.
+func (b *Buffer) Write(p []byte) (n int, err error) {
+	n, err = b.WriteAt(p, b.off)
+	b.off += int64(n)
+	return n, err
+}
+
+// WriteAt writes p starting at offset off, growing the buffer if needed. The
+// offset of the buffer is not changed.
+func (b *Buffer) WriteAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if end := off + int64(len(p)); end > int64(len(b.b)) {
+		if end > int64(cap(b.b)) {
+			nb := make([]byte, end, end*2)
+			copy(nb, b.b)
+			b.b = nb
+		} else {
+			b.b = b.b[:end]
+		}
+	}
+	return copy(b.b[off:], p), nil
+}
+
+func (b *Buffer) Seek(offset int64, whence int) (int64, error) {
+	var off int64
+	switch whence {
+	case io.SeekStart:
+		off = offset
+	case io.SeekCurrent:
+		off = b.off + offset
+	case io.SeekEnd:
+		off = int64(len(b.b)) + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if off < 0 {
+		return 0, errors.New("negative position")
+	}
+	b.off = off
+	return off, nil
+}
+
+// WriteTo writes the contents of the buffer, from the current offset, to w.
+func (b *Buffer) WriteTo(w io.Writer) (n int64, err error) {
+	if b.off >= int64(len(b.b)) {
+		return 0, nil
+	}
+	m, err := w.Write(b.b[b.off:])
+	b.off += int64(m)
+	return int64(m), err
+}
@@ -0,0 +1,186 @@
+package gen3
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Option configures a Version opened by OpenROM.
+type Option func(v *Version)
+
+// Cached returns an Option that causes the tables of the ROM to be read into
+// memory. Each table of species, moves, items and pokedex data is read the
+// first time it is accessed, after which the entries of the table are read
+// from memory. The names and descriptions of species, moves and items are
+// decoded once, and the header location of each map is resolved once.
+//
+// A write made through the Version discards everything that was read, so
+// that each table is read again when it is next accessed. Changes made to the
+// ROM by other means are not seen by a cached Version.
+func Cached() Option {
+	return func(v *Version) {
+		v.cache = &tableCache{}
+		v.cache.reset()
+	}
+}
+
+// Identifies a table that is read into memory by a cached Version.
+type table int
+
+const (
+	tableSpeciesName table = iota
+	tableSpeciesData
+	tableSpeciesEvo
+	tableSpeciesTM
+	tableLevelMovePtr
+	tablePokedexData
+	tableMoveName
+	tableMoveData
+	tableMoveDescPtr
+	tableItemData
+	tableCount
+)
+
+// Returns the address and size of a table.
+func (v *Version) tableRegion(t table) (addr ptr, size int) {
+	switch t {
+	case tableSpeciesName:
+		return v.AddrSpeciesName, structSpeciesName.Size() * indexSizeSpecies
+	case tableSpeciesData:
+		return v.AddrSpeciesData, structSpeciesData.Size() * indexSizeSpecies
+	case tableSpeciesEvo:
+		return v.AddrSpeciesEvo, structEvolution.Size() * indexSizeSpecies
+	case tableSpeciesTM:
+		return v.AddrSpeciesTM, structSpeciesTM.Size() * indexSizeSpecies
+	case tableLevelMovePtr:
+		return v.AddrLevelMovePtr, structPtr.Size() * indexSizeSpecies
+	case tablePokedexData:
+		return v.AddrPokedexData, v.dexData.Size() * (v.pokedex[0].Size + 1)
+	case tableMoveName:
+		return v.AddrMoveName, structMoveName.Size() * indexSizeMove
+	case tableMoveData:
+		return v.AddrMoveData, structMoveData.Size() * indexSizeMove
+	case tableMoveDescPtr:
+		return v.AddrMoveDescPtr, structPtr.Size() * (indexSizeMove - 1)
+	case tableItemData:
+		return v.AddrItemData, structItemData.Size() * indexSizeItem
+	}
+	panic("unknown table")
+}
+
+// Returns a reader of the ROM from which the entries of a table are read. For
+// a cached Version, the table is read into memory the first time.
+func (v *Version) table(t table) io.ReaderAt {
+	if v.cache == nil {
+		return v.ROM
+	}
+	return v.cache.get().table(v, t)
+}
+
+// Holds what a cached Version has read from the ROM. Each write replaces the
+// set of read values, so that values are never read while being discarded.
+type tableCache struct {
+	cur atomic.Value // *tableSet
+}
+
+func (c *tableCache) get() *tableSet {
+	return c.cur.Load().(*tableSet)
+}
+
+func (c *tableCache) reset() {
+	c.cur.Store(&tableSet{})
+}
+
+// Values read from the ROM by a cached Version.
+type tableSet struct {
+	tables [tableCount]struct {
+		once sync.Once
+		r    io.ReaderAt
+	}
+	texts [textCount]struct {
+		once  sync.Once
+		texts []string
+	}
+	headers struct {
+		once sync.Once
+		ptrs [][]ptr // By bank and map index.
+	}
+}
+
+// Identifies a kind of text that is decoded by a cached Version.
+type text int
+
+const (
+	textSpeciesName text = iota
+	textSpeciesCategory
+	textSpeciesDescription
+	textMoveName
+	textMoveDescription
+	textItemName
+	textItemDescription
+	textCount
+)
+
+// Returns the text of entry i of n entries, which is decoded by calling
+// decode with the index of the entry. For a cached Version, the text of every
+// entry is decoded the first time.
+func (v *Version) text(t text, i, n int, decode func(i int) string) string {
+	if v.cache == nil {
+		return decode(i)
+	}
+	e := &v.cache.get().texts[t]
+	e.once.Do(func() {
+		e.texts = make([]string, n)
+		for j := range e.texts {
+			e.texts[j] = decode(j)
+		}
+	})
+	return e.texts[i]
+}
+
+func (s *tableSet) table(v *Version, t table) io.ReaderAt {
+	e := &s.tables[t]
+	e.once.Do(func() {
+		addr, size := v.tableRegion(t)
+		if !addr.ValidROM() {
+			e.r = v.ROM
+			return
+		}
+		b := make([]byte, size)
+		n, _ := v.ROM.ReadAt(b, addr.ROM())
+		e.r = tableReader{rom: v.ROM, off: addr.ROM(), b: b[:n]}
+	})
+	return e.r
+}
+
+// Returns the header pointer of each map, by bank and map index. The pointers
+// are resolved the first time.
+func (s *tableSet) headerPtrs(v *Version) [][]ptr {
+	s.headers.once.Do(func() {
+		s.headers.ptrs = make([][]ptr, v.BankIndexSize())
+		for b := range s.headers.ptrs {
+			s.headers.ptrs[b] = make([]ptr, v.sizeMapTable[b])
+			for i := range s.headers.ptrs[b] {
+				s.headers.ptrs[b][i] = Map{v: v, b: b, i: i}.readHeaderPtr()
+			}
+		}
+	})
+	return s.headers.ptrs
+}
+
+// Reads a table from memory. Reads outside of the table, such as those
+// of a table that was read short at the end of the ROM, are made from the
+// ROM.
+type tableReader struct {
+	rom io.ReaderAt
+	off int64
+	b   []byte
+}
+
+func (r tableReader) ReadAt(p []byte, off int64) (n int, err error) {
+	if i := off - r.off; i >= 0 && i+int64(len(p)) <= int64(len(r.b)) {
+		return copy(p, r.b[i:]), nil
+	}
+	return r.rom.ReadAt(p, off)
+}
@@ -0,0 +1,96 @@
+package gen3_test
+
+import (
+	"bytes"
+	"github.com/anaminus/pkm/gen3"
+	"github.com/anaminus/pkm/gen3/romtest"
+	"testing"
+)
+
+func TestCached(t *testing.T) {
+	_, buf := emptyROM(t, 0x1000000)
+	v, err := gen3.OpenROM(buf, gen3.Cached())
+	if err != nil {
+		t.Fatalf("OpenROM: unexpected error: %s", err)
+	}
+	ver := v.(*gen3.Version)
+
+	// Writes must update the cache.
+	species := ver.SpeciesByIndex(1).(gen3.Species)
+	species.Name()
+	if err := species.SetName("TEST"); err != nil {
+		t.Fatalf("SetName: unexpected error: %s", err)
+	}
+	if v := species.Name(); v != "TEST" {
+		t.Errorf("SetName: unexpected cached result %q", v)
+	}
+	item := ver.ItemByIndex(1).(gen3.Item)
+	item.Name()
+	if err := item.SetName("ITEM"); err != nil {
+		t.Fatalf("SetName: unexpected error: %s", err)
+	}
+	if v := item.Name(); v != "ITEM" {
+		t.Errorf("SetName: unexpected cached result %q", v)
+	}
+	uncached := openVersion(t, bytes.NewReader(buf.Bytes()))
+	if v := uncached.SpeciesByIndex(1).Name(); v != "TEST" {
+		t.Errorf("SetName: unexpected written result %q", v)
+	}
+
+	// Writes that extend the ROM must be visible.
+	f, err := ver.FreeSpace()
+	if err != nil {
+		t.Fatalf("FreeSpace: unexpected error: %s", err)
+	}
+	if err := f.Expand(0x1001000); err != nil {
+		t.Fatalf("Expand: unexpected error: %s", err)
+	}
+	var out bytes.Buffer
+	if n, err := ver.WriteTo(&out); err != nil {
+		t.Fatalf("WriteTo: unexpected error: %s", err)
+	} else if n != 0x1001000 {
+		t.Errorf("WriteTo: unexpected size %#x", n)
+	}
+	if !bytes.Equal(out.Bytes(), buf.Bytes()) {
+		t.Errorf("WriteTo: cached contents differ from ROM")
+	}
+}
+
+func TestCachedSynthetic(t *testing.T) {
+	b := romtest.Build()
+	v, err := gen3.OpenROM(bytes.NewReader(b), gen3.Cached())
+	if err != nil {
+		t.Fatalf("OpenROM: unexpected error: %s", err)
+	}
+	ver := v.(*gen3.Version)
+	uncached := openVersion(t, bytes.NewReader(b))
+
+	// Each value is read twice, so that the second read is from memory.
+	for n := 0; n < 2; n++ {
+		for i := 0; i < ver.SpeciesIndexSize(); i++ {
+			s, u := ver.SpeciesByIndex(i), uncached.SpeciesByIndex(i)
+			if s.Name() != u.Name() || s.Category() != u.Category() || s.Description() != u.Description() || s.BaseStats() != u.BaseStats() {
+				t.Errorf("species %d: cached result differs", i)
+			}
+		}
+		for i := 0; i < ver.MoveIndexSize(); i++ {
+			m, u := ver.MoveByIndex(i), uncached.MoveByIndex(i)
+			if m.Name() != u.Name() || m.Description() != u.Description() || m.BasePower() != u.BasePower() {
+				t.Errorf("move %d: cached result differs", i)
+			}
+		}
+		for i := 0; i < ver.ItemIndexSize(); i++ {
+			m, u := ver.ItemByIndex(i), uncached.ItemByIndex(i)
+			if m.Name() != u.Name() || m.Description() != u.Description() {
+				t.Errorf("item %d: cached result differs", i)
+			}
+		}
+		ver.ScanBanks()
+		uncached.ScanBanks()
+		for i, m := range ver.AllMaps() {
+			if u := uncached.AllMaps()[i]; m.Name() != u.Name() {
+				t.Errorf("map %d.%d: cached result differs", m.BankIndex(), m.Index())
+			}
+		}
+	}
+}
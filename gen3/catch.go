@@ -0,0 +1,149 @@
+package gen3
+
+import (
+	"github.com/anaminus/pkm"
+)
+
+// Item indices of poke balls.
+const (
+	itemMasterBall  = 1
+	itemUltraBall   = 2
+	itemGreatBall   = 3
+	itemPokeBall    = 4
+	itemSafariBall  = 5
+	itemNetBall     = 6
+	itemDiveBall    = 7
+	itemNestBall    = 8
+	itemRepeatBall  = 9
+	itemTimerBall   = 10
+	itemLuxuryBall  = 11
+	itemPremierBall = 12
+)
+
+// CatchContext describes the circumstances of a capture attempt that affect
+// the effectiveness of certain balls.
+type CatchContext struct {
+	// The number of turns that have passed in the battle. Affects the Timer
+	// Ball.
+	Turn int
+	// Whether the battle takes place underwater. Affects the Dive Ball.
+	Underwater bool
+	// Whether the species has already been caught. Affects the Repeat Ball.
+	Caught bool
+}
+
+// Returns the catch rate multiplier of a ball, times ten. Returns false if the
+// item is not a ball.
+func ballBonus(species pkm.Species, level int, ball pkm.Item, ctx CatchContext) (bonus int, ok bool) {
+	switch ball.Index() {
+	case itemMasterBall, itemPokeBall, itemLuxuryBall, itemPremierBall:
+		bonus = 10
+	case itemUltraBall:
+		bonus = 20
+	case itemGreatBall, itemSafariBall:
+		bonus = 15
+	case itemNetBall:
+		bonus = 10
+		for _, t := range species.Type() {
+			if t == pkm.TypeWater || t == pkm.TypeBug {
+				bonus = 30
+			}
+		}
+	case itemDiveBall:
+		bonus = 10
+		if ctx.Underwater {
+			bonus = 35
+		}
+	case itemNestBall:
+		bonus = 10
+		if level < 40 && 40-level > 10 {
+			bonus = 40 - level
+		}
+	case itemRepeatBall:
+		bonus = 10
+		if ctx.Caught {
+			bonus = 30
+		}
+	case itemTimerBall:
+		bonus = ctx.Turn + 10
+		if bonus > 40 {
+			bonus = 40
+		}
+	default:
+		return 0, false
+	}
+	return bonus, true
+}
+
+// Floor of the square root of n.
+func isqrt(n int) int {
+	r := 0
+	for (r+1)*(r+1) <= n {
+		r++
+	}
+	return r
+}
+
+// Returns the probability (0-1) that a single shake check succeeds.
+func shakeChance(species pkm.Species, level int, hpPct float64, status pkm.Status, ball pkm.Item, ctx CatchContext) float64 {
+	bonus, ok := ballBonus(species, level, ball, ctx)
+	if !ok {
+		return 0
+	}
+	if ball.Index() == itemMasterBall {
+		return 1
+	}
+	if hpPct < 0 {
+		hpPct = 0
+	} else if hpPct > 1 {
+		hpPct = 1
+	}
+
+	odds := int(species.CatchRate()) * bonus / 10
+	odds = int(float64(odds) * (3 - 2*hpPct) / 3)
+	switch status {
+	case pkm.StatusSleep, pkm.StatusFreeze:
+		odds *= 2
+	case pkm.StatusPoison, pkm.StatusBurn, pkm.StatusParalysis, pkm.StatusToxic:
+		odds = odds * 15 / 10
+	}
+	if odds > 254 {
+		return 1
+	}
+	if odds <= 0 {
+		return 0
+	}
+	// Each shake succeeds if a random 16-bit value is less than the shake
+	// odds.
+	odds = 1048560 / isqrt(isqrt(16711680/odds))
+	if odds >= 65536 {
+		return 1
+	}
+	return float64(odds) / 65536
+}
+
+// CatchChance returns the probability (0-1) that a wild pokemon of a given
+// species and level will be caught with a given ball. hpPct is the fraction
+// (0-1) of the pokemon's remaining HP. Returns 0 if the item is not a ball.
+//
+// The Safari Ball uses the species' catch rate, which corresponds to a
+// Safari Zone encounter in which no bait or rocks have been thrown.
+func CatchChance(species pkm.Species, level int, hpPct float64, status pkm.Status, ball pkm.Item, ctx CatchContext) float64 {
+	p := shakeChance(species, level, hpPct, status, ball, ctx)
+	return p * p * p * p
+}
+
+// ShakeChances is like CatchChance, but returns the probability of each
+// outcome of the four shake checks. The value at index n is the probability
+// that the ball shakes n times before the pokemon breaks free, while the value
+// at index 4 is the probability that the pokemon is caught.
+func ShakeChances(species pkm.Species, level int, hpPct float64, status pkm.Status, ball pkm.Item, ctx CatchContext) (chances [5]float64) {
+	p := shakeChance(species, level, hpPct, status, ball, ctx)
+	q := 1.0
+	for i := 0; i < 4; i++ {
+		chances[i] = q * (1 - p)
+		q *= p
+	}
+	chances[4] = q
+	return
+}
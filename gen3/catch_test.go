@@ -0,0 +1,113 @@
+package gen3_test
+
+import (
+	"github.com/anaminus/pkm"
+	"github.com/anaminus/pkm/gen3"
+	"math"
+	"testing"
+)
+
+func TestCatchChance(t *testing.T) {
+	ver, err := gen3.OpenROM(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	bulbasaur := ver.SpeciesByName("BULBASAUR")
+	pokeBall := ver.ItemByIndex(4)
+	ctx := gen3.CatchContext{}
+
+	// Catch rate 45, full HP: odds 15, shake odds 32767.
+	p := 32767.0 / 65536
+	if v := gen3.CatchChance(bulbasaur, 5, 1, pkm.StatusNone, pokeBall, ctx); math.Abs(v-p*p*p*p) > 1e-9 {
+		t.Errorf("CatchChance: unexpected result %g", v)
+	}
+	if v := gen3.CatchChance(bulbasaur, 5, 1, pkm.StatusNone, ver.ItemByName("MASTER BALL"), ctx); v != 1 {
+		t.Errorf("CatchChance: Master Ball: unexpected result %g", v)
+	}
+	if v := gen3.CatchChance(bulbasaur, 5, 1, pkm.StatusNone, ver.ItemByName("POTION"), ctx); v != 0 {
+		t.Errorf("CatchChance: Potion: unexpected result %g", v)
+	}
+
+	low := gen3.CatchChance(bulbasaur, 5, 0.1, pkm.StatusNone, pokeBall, ctx)
+	asleep := gen3.CatchChance(bulbasaur, 5, 0.1, pkm.StatusSleep, pokeBall, ctx)
+	if !(asleep > low && low > p*p*p*p) {
+		t.Errorf("CatchChance: expected lower HP and status to increase chance")
+	}
+
+	timer := ver.ItemByName("TIMER BALL")
+	if gen3.CatchChance(bulbasaur, 5, 1, pkm.StatusNone, timer, gen3.CatchContext{Turn: 30}) <=
+		gen3.CatchChance(bulbasaur, 5, 1, pkm.StatusNone, timer, gen3.CatchContext{Turn: 0}) {
+		t.Errorf("CatchChance: expected Timer Ball to improve with turns")
+	}
+	nest := ver.ItemByName("NEST BALL")
+	if gen3.CatchChance(bulbasaur, 5, 1, pkm.StatusNone, nest, ctx) <=
+		gen3.CatchChance(bulbasaur, 50, 1, pkm.StatusNone, nest, ctx) {
+		t.Errorf("CatchChance: expected Nest Ball to favor lower levels")
+	}
+
+	shakes := gen3.ShakeChances(bulbasaur, 5, 1, pkm.StatusNone, pokeBall, ctx)
+	sum := 0.0
+	for _, v := range shakes {
+		sum += v
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("ShakeChances: probabilities sum to %g", sum)
+	}
+	if math.Abs(shakes[4]-p*p*p*p) > 1e-9 {
+		t.Errorf("ShakeChances: unexpected catch probability %g", shakes[4])
+	}
+}
+
+func TestCatchChanceSynthetic(t *testing.T) {
+	ver, err := gen3.OpenROM(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	// Catch rate 45.
+	species := ver.SpeciesByIndex(45)
+	pokeBall := ver.ItemByIndex(4)
+	ctx := gen3.CatchContext{}
+
+	// Full HP: odds 15, shake odds 32767.
+	p := 32767.0 / 65536
+	if v := gen3.CatchChance(species, 5, 1, pkm.StatusNone, pokeBall, ctx); math.Abs(v-p*p*p*p) > 1e-9 {
+		t.Errorf("CatchChance: unexpected result %g", v)
+	}
+	if v := gen3.CatchChance(species, 5, 1, pkm.StatusNone, ver.ItemByIndex(1), ctx); v != 1 {
+		t.Errorf("CatchChance: Master Ball: unexpected result %g", v)
+	}
+	if v := gen3.CatchChance(species, 5, 1, pkm.StatusNone, ver.ItemByIndex(13), ctx); v != 0 {
+		t.Errorf("CatchChance: Potion: unexpected result %g", v)
+	}
+
+	low := gen3.CatchChance(species, 5, 0.1, pkm.StatusNone, pokeBall, ctx)
+	asleep := gen3.CatchChance(species, 5, 0.1, pkm.StatusSleep, pokeBall, ctx)
+	if !(asleep > low && low > p*p*p*p) {
+		t.Errorf("CatchChance: expected lower HP and status to increase chance")
+	}
+
+	timer := ver.ItemByIndex(10)
+	if gen3.CatchChance(species, 5, 1, pkm.StatusNone, timer, gen3.CatchContext{Turn: 30}) <=
+		gen3.CatchChance(species, 5, 1, pkm.StatusNone, timer, gen3.CatchContext{Turn: 0}) {
+		t.Errorf("CatchChance: expected Timer Ball to improve with turns")
+	}
+	nest := ver.ItemByIndex(8)
+	if gen3.CatchChance(species, 5, 1, pkm.StatusNone, nest, ctx) <=
+		gen3.CatchChance(species, 50, 1, pkm.StatusNone, nest, ctx) {
+		t.Errorf("CatchChance: expected Nest Ball to favor lower levels")
+	}
+
+	shakes := gen3.ShakeChances(species, 5, 1, pkm.StatusNone, pokeBall, ctx)
+	sum := 0.0
+	for _, v := range shakes {
+		sum += v
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("ShakeChances: probabilities sum to %g", sum)
+	}
+	if math.Abs(shakes[4]-p*p*p*p) > 1e-9 {
+		t.Errorf("ShakeChances: unexpected catch probability %g", shakes[4])
+	}
+}
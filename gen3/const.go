@@ -8,6 +8,8 @@ const (
 	indexSizeTM      = 58
 	indexSizeBank    = 34
 	indexSizeMapName = 213
+	indexSizeTrainer = 855
+	indexSizeStarter = 3
 )
 
 var mapIndexSize = [indexSizeBank]int{
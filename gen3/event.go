@@ -0,0 +1,133 @@
+package gen3
+
+import (
+	"bytes"
+	"github.com/anaminus/pkm"
+)
+
+var (
+	structMapEvents = makeStruct(
+		1, // 0 Object event count
+		1, // 1 Warp count
+		1, // 2 Coord event count
+		1, // 3 Background event count
+		4, // 4 Pointer to object events
+		4, // 5 Pointer to warps
+		4, // 6 Pointer to coord events
+		4, // 7 Pointer to background events
+	)
+	structObjectEvent = makeStruct(
+		1, // 00 Local ID
+		1, // 01 Graphics ID
+		1, // 02 Kind
+		1, // 03 Padding
+		2, // 04 X
+		2, // 05 Y
+		1, // 06 Elevation
+		1, // 07 Movement type
+		2, // 08 Movement range
+		2, // 09 Trainer type
+		2, // 10 Trainer range
+		4, // 11 Pointer to script
+		2, // 12 Flag
+		2, // 13 Padding
+	)
+	structBgEvent = makeStruct(
+		2, // 0 X
+		2, // 1 Y
+		1, // 2 Elevation
+		1, // 3 Kind
+		2, // 4 Padding
+		2, // 5 Item, or low half of pointer to script
+		2, // 6 Flag, or high half of pointer to script
+	)
+)
+
+// Kind of background event that contains a hidden item.
+const bgEventHiddenItem = 7
+
+// The start of a script produced by the finditem macro, which is used by item
+// balls. The item index follows the prefix.
+var findItemPrefix = []byte{
+	0x1A, 0x00, 0x80, // setorcopyvar VAR_0x8000, <item>
+}
+
+// The remainder of a finditem script, following the item index.
+var findItemSuffix = []byte{
+	0x1A, 0x01, 0x80, // setorcopyvar VAR_0x8001, <amount>
+}
+
+// FieldItem is an item that can be found on a map, either within an item ball
+// or hidden.
+type FieldItem struct {
+	v *Version
+	// Location of the item index.
+	p ptr
+	// Whether the item is hidden.
+	hidden bool
+}
+
+// Hidden returns whether the item is hidden, rather than within an item ball.
+func (f FieldItem) Hidden() bool {
+	return f.hidden
+}
+
+func (f FieldItem) Item() pkm.Item {
+	b := make([]byte, 2)
+	f.v.ROM.ReadAt(b, f.p.ROM())
+	return Item{v: f.v, i: int(decUint16(b))}
+}
+
+func (f FieldItem) SetItem(item pkm.Item) error {
+	w, err := f.v.writer()
+	if err != nil {
+		return err
+	}
+	return writeBytes(w, f.p, encUint16(uint16(item.Index())))
+}
+
+// FieldItems returns the items that can be found on the map. Items within
+// item balls are found by inspecting the script of each object event.
+func (m Map) FieldItems() []FieldItem {
+	b := readStruct(
+		m.v.ROM,
+		m.headerPtr(),
+		0,
+		structMapHeader,
+		1,
+	)
+	events := decPtr(b)
+	if !events.ValidROM() {
+		return nil
+	}
+	b = readStruct(
+		m.v.ROM,
+		events,
+		0,
+		structMapEvents,
+	)
+
+	var items []FieldItem
+	objects := decPtr(b[4:8])
+	for i := 0; i < int(b[0]); i++ {
+		script := decPtr(readStruct(m.v.ROM, objects, i, structObjectEvent, 11))
+		if !script.ValidROM() {
+			continue
+		}
+		q := make([]byte, len(findItemPrefix)+2+len(findItemSuffix))
+		m.v.ROM.ReadAt(q, script.ROM())
+		if bytes.HasPrefix(q, findItemPrefix) && bytes.HasSuffix(q, findItemSuffix) {
+			items = append(items, FieldItem{v: m.v, p: script + ptr(len(findItemPrefix))})
+		}
+	}
+
+	bgs := decPtr(b[16:20])
+	for i := 0; i < int(b[3]); i++ {
+		e := readStruct(m.v.ROM, bgs, i, structBgEvent, 3)
+		if e[0] == bgEventHiddenItem {
+			p := bgs + ptr(i*structBgEvent.Size()+structBgEvent.FieldOffset(5))
+			items = append(items, FieldItem{v: m.v, p: p, hidden: true})
+		}
+	}
+	return items
+}
@@ -6,9 +6,9 @@ import (
 )
 
 func TestEvolution(t *testing.T) {
-	ver := gen3.OpenROM(ROM(t))
-	if ver == nil {
-		t.Fatalf("failed to open ROM")
+	ver, err := gen3.OpenROM(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
 	}
 
 	evo := ver.SpeciesByIndex(1).Evolutions()[0]
@@ -56,3 +56,56 @@ func TestEvolution(t *testing.T) {
 		}
 	}
 }
+
+func TestEvolutionSynthetic(t *testing.T) {
+	ver, err := gen3.OpenROM(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	evo := ver.SpeciesByIndex(1).Evolutions()[0]
+	if v := evo.Target(); v != ver.SpeciesByIndex(2) {
+		if v == nil {
+			t.Errorf("Target: unexpected result <nil>")
+		} else {
+			t.Errorf("Target: unexpected result %d (%s)", v.Index(), v.Name())
+		}
+	}
+	if v := evo.Method(); v != 4 {
+		t.Errorf("Method: unexpected result %d", v)
+	}
+	if v := evo.Param(); v != 16 {
+		t.Errorf("Param: unexpected result %d", v)
+	}
+
+	type ev struct {
+		species int
+		evo     int
+		method  string
+	}
+	expected := []ev{
+		{1, 0, "Level 16"},
+		{133, 0, "Use ITEM96"},
+		{133, 3, "Friendship (Day)"},
+		{133, 4, "Friendship (Night)"},
+		{301, 0, "Friendship"},
+		{304, 0, "Level 14"},
+		{305, 0, "Trade"},
+		{306, 0, "Trade holding ITEM16"},
+		{307, 0, "Use ITEM17"},
+		{308, 0, "Level 18 if ATK > DEF"},
+		{309, 0, "Level 19 if ATK = DEF"},
+		{310, 0, "Level 20 if ATK < DEF"},
+		{311, 0, "Personality[1] (21)"},
+		{312, 0, "Personality[2] (22)"},
+		{313, 0, "Level 23 (Spawns extra)"},
+		{314, 0, "Level 24 (Spawned)"},
+		{315, 0, "Beauty (25)"},
+	}
+	for _, e := range expected {
+		evo := ver.SpeciesByIndex(e.species).Evolutions()[e.evo]
+		if evo.MethodString() != e.method {
+			t.Errorf("MethodString: unexpected result: %d (%d) -> \"%s\"", e.species, e.evo, evo.MethodString())
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package gen3
+
+// DexDataSize returns the size of an entry of the pokedex data table of v.
+func DexDataSize(v *Version) int {
+	return v.dexData.Size()
+}
+
+// EncounterList returns the address of the encounter list of v.
+func EncounterList(v *Version) uint32 {
+	return uint32(v.encounterList())
+}
@@ -0,0 +1,378 @@
+package gen3
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	// The value of unused bytes.
+	freeByte = 0xFF
+	// The minimum length of a run of unused bytes found by scanning.
+	freeMinRun = 64
+	// The number of bytes skipped at the start of a scanned run, which may
+	// belong to a terminator of preceding data.
+	freeMargin = 4
+	// The alignment of allocated space.
+	freeAlign = 4
+)
+
+// ErrNoFreeSpace is returned when an allocation cannot be satisfied.
+var ErrNoFreeSpace = errors.New("not enough free space")
+
+// A region of free space.
+type freeRegion struct {
+	addr ptr
+	size int
+	// Number of unused bytes preceding the region that were skipped by
+	// scanning.
+	margin int
+}
+
+// FreeSpace manages the unused space of a writable ROM. Unused space is
+// identified by runs of 0xFF bytes. Addresses are GBA addresses, where the ROM
+// starts at 0x08000000.
+type FreeSpace struct {
+	v *Version
+	// Sorted by address, and never overlapping or adjacent.
+	regions []freeRegion
+
+	// If true, then the ROM is expanded when an allocation cannot otherwise
+	// be satisfied.
+	AutoExpand bool
+}
+
+// Returns the size of the ROM.
+func (v *Version) romSize() (int64, error) {
+	return romSize(v.ROM)
+}
+
+// FreeSpace returns the free space manager of a writable Version. The ROM is
+// scanned for free space the first time this is called.
+func (v *Version) FreeSpace() (*FreeSpace, error) {
+	if _, err := v.writer(); err != nil {
+		return nil, err
+	}
+	if v.free != nil {
+		return v.free, nil
+	}
+	f := &FreeSpace{v: v}
+	if err := f.scan(); err != nil {
+		return nil, err
+	}
+	v.free = f
+	return f, nil
+}
+
+// Find runs of unused bytes.
+func (f *FreeSpace) scan() error {
+	size, err := f.v.romSize()
+	if err != nil {
+		return err
+	}
+	r := f.v.reader(0)
+	buf := make([]byte, 1<<16)
+	start := int64(-1)
+	addRun := func(end int64) {
+		s := alignUp(start+freeMargin, freeAlign)
+		if end-start >= freeMinRun && end > s {
+			f.regions = append(f.regions, freeRegion{addr: ptr(addrROM + s), size: int(end - s), margin: int(s - start)})
+		}
+	}
+	for off := int64(0); off < size; {
+		n, err := r.Read(buf)
+		for i := 0; i < n; i++ {
+			if buf[i] == freeByte {
+				if start < 0 {
+					start = off + int64(i)
+				}
+			} else if start >= 0 {
+				addRun(off + int64(i))
+				start = -1
+			}
+		}
+		off += int64(n)
+		if err == io.EOF || n == 0 {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	if start >= 0 {
+		addRun(size)
+	}
+	return nil
+}
+
+func alignUp(n, align int64) int64 {
+	return (n + align - 1) / align * align
+}
+
+// Regions returns the address and size of each free region.
+func (f *FreeSpace) Regions() (addrs []uint32, sizes []int) {
+	for _, r := range f.regions {
+		addrs = append(addrs, uint32(r.addr))
+		sizes = append(sizes, r.size)
+	}
+	return
+}
+
+// Size returns the total amount of free space.
+func (f *FreeSpace) Size() int {
+	n := 0
+	for _, r := range f.regions {
+		n += r.size
+	}
+	return n
+}
+
+// Alloc allocates a given number of bytes, returning the address of the
+// allocated space. The allocated space retains its unused value until it is
+// written to.
+func (f *FreeSpace) Alloc(size int) (addr uint32, err error) {
+	p, err := f.alloc(size)
+	return uint32(p), err
+}
+
+func (f *FreeSpace) alloc(size int) (ptr, error) {
+	if size <= 0 {
+		return 0, fmt.Errorf("invalid allocation size %d", size)
+	}
+	for {
+		for i, r := range f.regions {
+			a := ptr(alignUp(int64(r.addr), freeAlign))
+			pad := int(a - r.addr)
+			if r.size-pad < size {
+				continue
+			}
+			// Split the region around the allocated space.
+			var split []freeRegion
+			if pad > 0 {
+				split = append(split, freeRegion{addr: r.addr, size: pad, margin: r.margin})
+			}
+			if rest := r.size - pad - size; rest > 0 {
+				split = append(split, freeRegion{addr: a + ptr(size), size: rest})
+			}
+			f.regions = append(f.regions[:i], append(split, f.regions[i+1:]...)...)
+			return a, nil
+		}
+		if !f.AutoExpand {
+			return 0, ErrNoFreeSpace
+		}
+		cur, err := f.v.romSize()
+		if err != nil {
+			return 0, err
+		}
+		// Expand to the next MiB that fits the allocation, extending any free
+		// space already at the end of the ROM.
+		tail := int64(0)
+		if n := len(f.regions); n > 0 {
+			if r := f.regions[n-1]; int64(r.addr)-addrROM+int64(r.size) == cur {
+				tail = int64(r.size)
+			}
+		}
+		need := alignUp(cur-tail+int64(size)+freeAlign, 1<<20)
+		if need > sizeROM || cur >= sizeROM {
+			return 0, ErrNoFreeSpace
+		}
+		if err := f.Expand(int(need)); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Allocates the given space, if the space is entirely unused. Returns whether
+// the space was allocated. Because the space is known to follow the end of
+// data, it may include the unused bytes skipped at the start of a scanned
+// region.
+func (f *FreeSpace) take(addr ptr, size int) bool {
+	for i, r := range f.regions {
+		if addr < r.addr-ptr(r.margin) || addr+ptr(size) > r.addr+ptr(r.size) {
+			continue
+		}
+		var split []freeRegion
+		if n := int(addr - r.addr); addr > r.addr {
+			split = append(split, freeRegion{addr: r.addr, size: n, margin: r.margin})
+		}
+		if n := int(r.addr + ptr(r.size) - addr - ptr(size)); n > 0 {
+			split = append(split, freeRegion{addr: addr + ptr(size), size: n})
+		}
+		f.regions = append(f.regions[:i], append(split, f.regions[i+1:]...)...)
+		return true
+	}
+	return false
+}
+
+// Free marks the given space as unused, overwriting it with 0xFF bytes.
+func (f *FreeSpace) Free(addr uint32, size int) error {
+	return f.free(ptr(addr), size)
+}
+
+func (f *FreeSpace) free(addr ptr, size int) error {
+	if size <= 0 {
+		return nil
+	}
+	if !addr.ValidROM() || !(addr + ptr(size) - 1).ValidROM() {
+		return fmt.Errorf("invalid free region %08X (%d bytes)", uint32(addr), size)
+	}
+	w, err := f.v.writer()
+	if err != nil {
+		return err
+	}
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = freeByte
+	}
+	if err := writeBytes(w, addr, b); err != nil {
+		return err
+	}
+	f.insert(freeRegion{addr: addr, size: size})
+	return nil
+}
+
+// Adds a region, merging it with overlapping or adjacent regions.
+func (f *FreeSpace) insert(r freeRegion) {
+	f.regions = append(f.regions, r)
+	sort.Slice(f.regions, func(i, j int) bool {
+		return f.regions[i].addr < f.regions[j].addr
+	})
+	merged := f.regions[:1]
+	for _, r := range f.regions[1:] {
+		last := &merged[len(merged)-1]
+		if end := last.addr + ptr(last.size); r.addr <= end {
+			if rend := r.addr + ptr(r.size); rend > end {
+				last.size = int(rend - last.addr)
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	f.regions = merged
+}
+
+// Expand grows the ROM to a given size, filling the new space with 0xFF bytes
+// and marking it as unused. The size cannot exceed 32 MiB.
+func (f *FreeSpace) Expand(size int) error {
+	if size > sizeROM {
+		return fmt.Errorf("cannot expand ROM beyond %d bytes", sizeROM)
+	}
+	cur, err := f.v.romSize()
+	if err != nil {
+		return err
+	}
+	if int64(size) <= cur {
+		return nil
+	}
+	return f.free(ptr(addrROM+cur), size-int(cur))
+}
+
+// Repoint replaces every reference to one address with a reference to another
+// address, returning the number of references replaced. A reference is any
+// 4-byte aligned pointer whose value is equal to the old address.
+func (f *FreeSpace) Repoint(from, to uint32) (n int, err error) {
+	return f.repoint(ptr(from), ptr(to))
+}
+
+func (f *FreeSpace) repoint(from, to ptr) (n int, err error) {
+	w, err := f.v.writer()
+	if err != nil {
+		return 0, err
+	}
+	found, err := f.v.references(from)
+	if err != nil {
+		return 0, err
+	}
+	b := encUint32(uint32(to))
+	for _, ref := range found {
+		if err := writeBytes(w, ref, b); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Returns the location of every 4-byte aligned pointer within the ROM whose
+// value is equal to the given address.
+func (v *Version) references(addr ptr) ([]ptr, error) {
+	size, err := v.romSize()
+	if err != nil {
+		return nil, err
+	}
+	ps := int64(structPtr.Size())
+	buf := make([]byte, 1<<16)
+	var found []ptr
+	for off := int64(0); off < size; off += int64(len(buf)) {
+		m, err := v.ROM.ReadAt(buf, off)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return nil, err
+		}
+		for i := int64(0); i+ps <= int64(m); i += ps {
+			if decPtr(buf[i:i+ps]) == addr {
+				found = append(found, ptr(addrROM+off+i))
+			}
+		}
+	}
+	return found, nil
+}
+
+// Writes data referenced by an entry of a pointer table. If the data does not
+// fit within the size of the old data, then it is moved to newly allocated
+// space, and the old data is freed.
+//
+// The old data may be shared with other entries, such as species that have
+// the same learned moves. If any other reference to the old data exists, then
+// the data is written to newly allocated space, only the entry is changed, and
+// the old data is left in place.
+func (v *Version) writePointed(table ptr, index int, b []byte, oldSize int) error {
+	w, err := v.writer()
+	if err != nil {
+		return err
+	}
+	f, err := v.FreeSpace()
+	if err != nil {
+		return err
+	}
+	entry := table + ptr(index*structPtr.Size())
+	p, err := readStructErr(v.ROM, table, index, structPtr)
+	if err != nil {
+		return err
+	}
+	old := decPtr(p)
+	shared := false
+	if old.ValidROM() {
+		refs, err := v.references(old)
+		if err != nil {
+			return err
+		}
+		for _, ref := range refs {
+			if ref != entry {
+				shared = true
+				break
+			}
+		}
+	}
+	if old.ValidROM() && !shared && len(b) <= oldSize {
+		if err := writeBytes(w, old, b); err != nil {
+			return err
+		}
+		return f.free(old+ptr(len(b)), oldSize-len(b))
+	}
+
+	addr, err := f.alloc(len(b))
+	if err != nil {
+		return err
+	}
+	if err := writeBytes(w, addr, b); err != nil {
+		return err
+	}
+	if err := writeStruct(w, table, index, structPtr, encUint32(uint32(addr))); err != nil {
+		return err
+	}
+	if !old.ValidROM() || shared {
+		return nil
+	}
+	return f.free(old, oldSize)
+}
@@ -0,0 +1,219 @@
+package gen3_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/anaminus/pkm"
+	"github.com/anaminus/pkm/gba"
+	"github.com/anaminus/pkm/gen3"
+	"testing"
+)
+
+// Creates a writable Emerald version from an empty image.
+func emptyROM(t *testing.T, size int) (*gen3.Version, *gen3.Buffer) {
+	b := make([]byte, size)
+	copy(b, gba.NewHeader(gen3.CodeEmeraldEN, "POKEMON EMER").Bytes())
+	buf := gen3.NewBuffer(b)
+	return openVersion(t, buf), buf
+}
+
+func fill(b []byte, v byte) {
+	for i := range b {
+		b[i] = v
+	}
+}
+
+func TestFreeSpace(t *testing.T) {
+	ver, buf := emptyROM(t, 0x200000)
+	if _, err := openVersion(t, bytes.NewReader(buf.Bytes())).FreeSpace(); err != gen3.ErrReadOnly {
+		t.Errorf("FreeSpace: expected ErrReadOnly, got %v", err)
+	}
+
+	fill(buf.Bytes()[0x100000:0x101000], 0xFF)
+	fill(buf.Bytes()[0x110000:0x110010], 0xFF)
+	f, err := ver.FreeSpace()
+	if err != nil {
+		t.Fatalf("FreeSpace: unexpected error: %s", err)
+	}
+	if addrs, sizes := f.Regions(); len(addrs) != 1 || addrs[0] != 0x08100004 || sizes[0] != 0xFFC {
+		t.Fatalf("Regions: unexpected result %08X %d", addrs, sizes)
+	}
+
+	a, err := f.Alloc(10)
+	if err != nil || a != 0x08100004 {
+		t.Errorf("Alloc: unexpected result %08X (%v)", a, err)
+	}
+	b, err := f.Alloc(8)
+	if err != nil || b != 0x08100010 {
+		t.Errorf("Alloc: unexpected result %08X (%v)", b, err)
+	}
+	if v := f.Size(); v != 0xFFC-18 {
+		t.Errorf("Size: unexpected result %d", v)
+	}
+	if _, err := f.Alloc(0x1000); err != gen3.ErrNoFreeSpace {
+		t.Errorf("Alloc: expected ErrNoFreeSpace, got %v", err)
+	}
+
+	copy(buf.Bytes()[0x100004:], "0123456789")
+	if err := f.Free(a, 10); err != nil {
+		t.Errorf("Free: unexpected error: %s", err)
+	}
+	if !bytes.Equal(buf.Bytes()[0x100004:0x10000E], bytes.Repeat([]byte{0xFF}, 10)) {
+		t.Errorf("Free: space was not cleared")
+	}
+	if addrs, _ := f.Regions(); len(addrs) != 2 {
+		t.Errorf("Free: unexpected region count %d", len(addrs))
+	}
+	if err := f.Free(b, 8); err != nil {
+		t.Errorf("Free: unexpected error: %s", err)
+	}
+	if addrs, sizes := f.Regions(); len(addrs) != 1 || sizes[0] != 0xFFC {
+		t.Errorf("Free: regions were not merged: %08X %d", addrs, sizes)
+	}
+
+	binary.LittleEndian.PutUint32(buf.Bytes()[0x400:], 0x08123456)
+	binary.LittleEndian.PutUint32(buf.Bytes()[0x808:], 0x08123456)
+	binary.LittleEndian.PutUint32(buf.Bytes()[0x902:], 0x08123456)
+	if n, err := f.Repoint(0x08123456, 0x08ABCDEF); err != nil || n != 2 {
+		t.Errorf("Repoint: unexpected result %d (%v)", n, err)
+	}
+	if v := binary.LittleEndian.Uint32(buf.Bytes()[0x808:]); v != 0x08ABCDEF {
+		t.Errorf("Repoint: unexpected pointer %08X", v)
+	}
+	if v := binary.LittleEndian.Uint32(buf.Bytes()[0x902:]); v != 0x08123456 {
+		t.Errorf("Repoint: unaligned value was modified")
+	}
+
+	if err := f.Expand(0x300000); err != nil {
+		t.Errorf("Expand: unexpected error: %s", err)
+	}
+	if buf.Len() != 0x300000 || f.Size() != 0xFFC+0x100000 {
+		t.Errorf("Expand: unexpected sizes %d, %d", buf.Len(), f.Size())
+	}
+	if err := f.Expand(0x02000001); err == nil {
+		t.Errorf("Expand: expected error")
+	}
+
+	f.AutoExpand = true
+	if a, err := f.Alloc(0x180000); err != nil || a != 0x08200000 {
+		t.Errorf("Alloc: unexpected auto-expanded result %08X (%v)", a, err)
+	}
+	if buf.Len() != 0x400000 {
+		t.Errorf("Alloc: unexpected expanded size %d", buf.Len())
+	}
+}
+
+func TestRelocation(t *testing.T) {
+	ver, buf := emptyROM(t, 0x1000000)
+	rom := buf.Bytes()
+	fill(rom[0xF00000:], 0xFF)
+
+	// Learned moves of species 1.
+	table := int(uint32(ver.AddrLevelMovePtr)-0x08000000) + 4
+	binary.LittleEndian.PutUint32(rom[table:], 0x08001000)
+	binary.LittleEndian.PutUint32(rom[0x2000:], 0x08001000)
+	copy(rom[0x1000:], []byte{33, 2, 45, 8, 0xFF, 0xFF})
+
+	species := ver.SpeciesByIndex(1).(gen3.Species)
+	if v := species.LearnedMoves(); len(v) != 2 {
+		t.Fatalf("LearnedMoves: unexpected result length %d", len(v))
+	}
+	moves := []pkm.LevelMove{
+		{Level: 1, Move: ver.MoveByIndex(33)},
+		{Level: 4, Move: ver.MoveByIndex(45)},
+		{Level: 100, Move: ver.MoveByIndex(300)},
+	}
+	if err := species.SetLearnedMoves(moves); err != nil {
+		t.Fatalf("SetLearnedMoves: unexpected error: %s", err)
+	}
+	p := binary.LittleEndian.Uint32(rom[table:])
+	if p == 0x08001000 {
+		t.Errorf("SetLearnedMoves: expected moves to be relocated")
+	}
+	// The old moves are shared with another reference, which is left as is.
+	if v := binary.LittleEndian.Uint32(rom[0x2000:]); v != 0x08001000 {
+		t.Errorf("SetLearnedMoves: other reference was changed: %08X", v)
+	}
+	if !bytes.Equal(rom[0x1000:0x1006], []byte{33, 2, 45, 8, 0xFF, 0xFF}) {
+		t.Errorf("SetLearnedMoves: shared moves were changed")
+	}
+	if v := species.LearnedMoves(); len(v) != len(moves) {
+		t.Errorf("LearnedMoves: unexpected result length %d", len(v))
+	} else {
+		for i, m := range v {
+			if m != moves[i] {
+				t.Errorf("LearnedMoves: %d: unexpected result %d %d", i, m.Level, m.Move.Index())
+			}
+		}
+	}
+	if err := species.SetLearnedMoves(moves[:1]); err != nil {
+		t.Fatalf("SetLearnedMoves: unexpected error: %s", err)
+	}
+	if v := binary.LittleEndian.Uint32(rom[table:]); v != p {
+		t.Errorf("SetLearnedMoves: expected shorter moves to be written in place")
+	}
+	if v := species.LearnedMoves(); len(v) != 1 {
+		t.Errorf("LearnedMoves: unexpected result length %d", len(v))
+	}
+	if err := species.SetLearnedMoves([]pkm.LevelMove{{Level: 128, Move: ver.MoveByIndex(1)}}); err == nil {
+		t.Errorf("SetLearnedMoves: expected error for level 128")
+	}
+
+	// Description of move 1.
+	table = int(uint32(ver.AddrMoveDescPtr) - 0x08000000)
+	binary.LittleEndian.PutUint32(rom[table:], 0x08003000)
+	copy(rom[0x3000:], []byte{0xBB, 0xBC, 0xFF})
+
+	move := ver.MoveByIndex(1).(gen3.Move)
+	if v := move.Description(); v != "AB" {
+		t.Fatalf("Description: unexpected result %q", v)
+	}
+	if err := move.SetDescription("A longer description."); err != nil {
+		t.Fatalf("SetDescription: unexpected error: %s", err)
+	}
+	if v := move.Description(); v != "A longer description." {
+		t.Errorf("Description: unexpected result %q", v)
+	}
+	if v := binary.LittleEndian.Uint32(rom[table:]); v == 0x08003000 {
+		t.Errorf("SetDescription: expected description to be relocated")
+	}
+}
+
+func TestWritePointedShared(t *testing.T) {
+	ver, buf := emptyROM(t, 0x1000000)
+	rom := buf.Bytes()
+	fill(rom[0xF00000:], 0xFF)
+
+	// Species 1 and 2 share learned moves.
+	table := int(uint32(ver.AddrLevelMovePtr) - 0x08000000)
+	binary.LittleEndian.PutUint32(rom[table+4:], 0x08001000)
+	binary.LittleEndian.PutUint32(rom[table+8:], 0x08001000)
+	copy(rom[0x1000:], []byte{33, 2, 45, 8, 0xFF, 0xFF})
+
+	// A shorter list would fit in place, but is written to new space.
+	species := ver.SpeciesByIndex(1).(gen3.Species)
+	if err := species.SetLearnedMoves([]pkm.LevelMove{{Level: 1, Move: ver.MoveByIndex(10)}}); err != nil {
+		t.Fatalf("SetLearnedMoves: unexpected error: %s", err)
+	}
+	if v := binary.LittleEndian.Uint32(rom[table+4:]); v == 0x08001000 {
+		t.Errorf("SetLearnedMoves: expected shared moves to be copied")
+	}
+	if v := binary.LittleEndian.Uint32(rom[table+8:]); v != 0x08001000 {
+		t.Errorf("SetLearnedMoves: other entry was changed: %08X", v)
+	}
+	if v := species.LearnedMoves(); len(v) != 1 || v[0].Move.Index() != 10 {
+		t.Errorf("LearnedMoves: unexpected result %v", v)
+	}
+	if v := ver.SpeciesByIndex(2).LearnedMoves(); len(v) != 2 || v[0].Move.Index() != 33 || v[1].Move.Index() != 45 {
+		t.Errorf("LearnedMoves: shared moves were changed")
+	}
+
+	// The remaining entry is the only reference, so the data is written in
+	// place.
+	if err := ver.SpeciesByIndex(2).(gen3.Species).SetLearnedMoves([]pkm.LevelMove{{Level: 1, Move: ver.MoveByIndex(20)}}); err != nil {
+		t.Fatalf("SetLearnedMoves: unexpected error: %s", err)
+	}
+	if v := binary.LittleEndian.Uint32(rom[table+8:]); v != 0x08001000 {
+		t.Errorf("SetLearnedMoves: expected moves to be written in place")
+	}
+}
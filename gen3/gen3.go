@@ -1,22 +1,126 @@
 package gen3
 
 import (
+	"errors"
+	"fmt"
 	"github.com/anaminus/pkm"
+	"github.com/anaminus/pkm/gba"
 	"io"
+	"os"
+	"sync"
 )
 
-// OpemROM creates a pkm.Version that reads a GameBoy Advance ROM file. If the
-// contents are identified as an unsupported version, then a nil value is
-// returned.
-func OpenROM(rom io.ReadSeeker) pkm.Version {
-	var gc pkm.GameCode
-	rom.Seek(addrGameCode.ROM(), 0)
-	rom.Read(gc[:])
-	if v, ok := versionLookup[gc]; ok {
-		v.ROM = rom
-		return &v
+// ErrUnsupported is returned by OpenROM when the contents of a ROM are not
+// identified as a supported version.
+var ErrUnsupported = errors.New("unsupported ROM")
+
+// OpemROM creates a pkm.Version that reads a GameBoy Advance ROM file. An
+// error is returned if the ROM does not have a valid GBA header, as checked by
+// gba.Header.Validate. If the contents are identified as an unsupported
+// version, then ErrUnsupported is returned. The rest of the ROM is not
+// checked; Validate can be used to find problems with the returned Version.
+//
+// If rom also implements io.ReaderAt, such as a Buffer, bytes.Reader or
+// os.File, then it is read through ReadAt. Otherwise, rom is adapted so that
+// each access seeks and reads while holding a lock.
+//
+// If rom also implements io.WriterAt or io.WriteSeeker, such as a Buffer or a
+// file opened for writing, then the returned Version is writable, and its
+// setters write changes through to rom.
+//
+// Options, such as Cached, configure how the ROM is read.
+func OpenROM(rom io.ReadSeeker, opts ...Option) (pkm.Version, error) {
+	if r, ok := rom.(io.ReaderAt); ok {
+		return OpenReaderAt(r, opts...)
+	}
+	a := &seekReaderAt{r: rom}
+	if w, ok := rom.(io.WriteSeeker); ok {
+		return OpenReaderAt(&seekReadWriterAt{seekReaderAt: a, w: w}, opts...)
+	}
+	return OpenReaderAt(a, opts...)
+}
+
+// OpenReaderAt is like OpenROM, but reads from an io.ReaderAt. If rom also
+// implements io.WriterAt, then the returned Version is writable.
+//
+// A Version is safe for concurrent reads as long as rom is. Modifying a
+// Version is not safe while the Version is otherwise in use.
+func OpenReaderAt(rom io.ReaderAt, opts ...Option) (pkm.Version, error) {
+	h, err := gba.ReadHeader(rom)
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	if err := h.Validate(); err != nil {
+		return nil, fmt.Errorf("not a GBA ROM: %w", err)
+	}
+	v, ok := versionLookup[h.GameCode]
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	v.ROM = rom
+	v.scan = new(sync.Mutex)
+	v.dexMaps = newDexMaps(len(v.pokedex))
+	v.typeChart = &typeChartCache{}
+	v.encounterOnce = new(sync.Once)
+	for _, opt := range opts {
+		opt(&v)
+	}
+	return &v, nil
+}
+
+// Adapts an io.ReadSeeker to an io.ReaderAt. Because the offset of the
+// underlying reader is shared, each access is serialized.
+type seekReaderAt struct {
+	mu sync.Mutex
+	r  io.ReadSeeker
+}
+
+func (a *seekReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.r.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(a.r, p)
+}
+
+// Size returns the size of the underlying reader.
+func (a *seekReaderAt) Size() (int64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.r.Seek(0, io.SeekEnd)
+}
+
+// Adapts an io.ReadWriteSeeker to an io.ReaderAt and io.WriterAt.
+type seekReadWriterAt struct {
+	*seekReaderAt
+	w io.WriteSeeker
+}
+
+func (a *seekReadWriterAt) WriteAt(p []byte, off int64) (n int, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.w.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return a.w.Write(p)
+}
+
+// Returns the size of a ROM.
+func romSize(r io.ReaderAt) (int64, error) {
+	switch r := r.(type) {
+	case interface{ Size() int64 }:
+		return r.Size(), nil
+	case interface{ Size() (int64, error) }:
+		return r.Size()
+	case interface{ Stat() (os.FileInfo, error) }:
+		fi, err := r.Stat()
+		if err != nil {
+			return 0, err
+		}
+		return fi.Size(), nil
 	}
-	return nil
+	return 0, errors.New("cannot determine size of ROM")
 }
 
 // Game codes that identify generation III versions.
@@ -31,10 +135,11 @@ var (
 
 var versionLookup = map[pkm.GameCode]Version{
 	CodeRubyEN: Version{
-		name: "Pokémon Ruby Version",
+		name:    "Pokémon Ruby Version",
+		dexData: structDexData,
 		pokedex: []pokedexData{
 			{Name: "National", Size: 386, Address: 0xFFFFFFFF},
-			{Name: "Standard", Size: 202, Address: 0xFFFFFFFF},
+			{Name: "Hoenn", Alias: "Standard", Size: 202, Address: 0xFFFFFFFF},
 		},
 		AddrAbilityName:    0xFFFFFFFF,
 		AddrAbilityDescPtr: 0xFFFFFFFF,
@@ -51,14 +156,17 @@ var versionLookup = map[pkm.GameCode]Version{
 		AddrSpeciesEvo:     0xFFFFFFFF,
 		AddrSpeciesName:    0xFFFFFFFF,
 		AddrSpeciesTM:      0xFFFFFFFF,
+		AddrStarters:       0xFFFFFFFF,
 		AddrTypeEffect:     0xFFFFFFFF,
 		AddrTMMove:         0xFFFFFFFF,
+		AddrTrainerData:    0xFFFFFFFF,
 	},
 	CodeSapphireEN: Version{
-		name: "Pokémon Sapphire Version",
+		name:    "Pokémon Sapphire Version",
+		dexData: structDexData,
 		pokedex: []pokedexData{
 			{Name: "National", Size: 386, Address: 0xFFFFFFFF},
-			{Name: "Standard", Size: 202, Address: 0xFFFFFFFF},
+			{Name: "Hoenn", Alias: "Standard", Size: 202, Address: 0xFFFFFFFF},
 		},
 		AddrAbilityName:    0xFFFFFFFF,
 		AddrAbilityDescPtr: 0xFFFFFFFF,
@@ -75,14 +183,17 @@ var versionLookup = map[pkm.GameCode]Version{
 		AddrSpeciesEvo:     0xFFFFFFFF,
 		AddrSpeciesName:    0xFFFFFFFF,
 		AddrSpeciesTM:      0xFFFFFFFF,
+		AddrStarters:       0xFFFFFFFF,
 		AddrTypeEffect:     0xFFFFFFFF,
 		AddrTMMove:         0xFFFFFFFF,
+		AddrTrainerData:    0xFFFFFFFF,
 	},
 	CodeEmeraldEN: Version{
-		name: "Pokémon Emerald Version",
+		name:    "Pokémon Emerald Version",
+		dexData: structDexDataEmerald,
 		pokedex: []pokedexData{
 			{Name: "National", Size: 386, Address: 0x0831DC82},
-			{Name: "Standard", Size: 202, Address: 0x0831D94C},
+			{Name: "Hoenn", Alias: "Standard", Size: 202, Address: 0x0831D94C},
 		},
 		AddrAbilityName:    0x0831B6DB,
 		AddrAbilityDescPtr: 0x0831BAD4,
@@ -99,14 +210,17 @@ var versionLookup = map[pkm.GameCode]Version{
 		AddrSpeciesEvo:     0x0832531C,
 		AddrSpeciesName:    0x083185C8,
 		AddrSpeciesTM:      0x0831E898,
+		AddrStarters:       0x085B1DF8,
 		AddrTypeEffect:     0x0831ACE8,
 		AddrTMMove:         0x08616040,
+		AddrTrainerData:    0x08310030,
 	},
 	CodeFireRedEN: Version{
-		name: "Pokémon Fire Red Version",
+		name:    "Pokémon Fire Red Version",
+		dexData: structDexData,
 		pokedex: []pokedexData{
 			{Name: "National", Size: 386, Address: 0xFFFFFFFF},
-			{Name: "Standard", Size: 151, Address: 0xFFFFFFFF},
+			{Name: "Kanto", Alias: "Standard", Size: 151, Address: 0xFFFFFFFF},
 		},
 		AddrAbilityName:    0xFFFFFFFF,
 		AddrAbilityDescPtr: 0xFFFFFFFF,
@@ -123,14 +237,17 @@ var versionLookup = map[pkm.GameCode]Version{
 		AddrSpeciesEvo:     0xFFFFFFFF,
 		AddrSpeciesName:    0xFFFFFFFF,
 		AddrSpeciesTM:      0xFFFFFFFF,
+		AddrStarters:       0xFFFFFFFF,
 		AddrTypeEffect:     0xFFFFFFFF,
 		AddrTMMove:         0xFFFFFFFF,
+		AddrTrainerData:    0xFFFFFFFF,
 	},
 	CodeLeafGreenEN: Version{
-		name: "Pokémon Leaf Green Version",
+		name:    "Pokémon Leaf Green Version",
+		dexData: structDexData,
 		pokedex: []pokedexData{
 			{Name: "National", Size: 386, Address: 0xFFFFFFFF},
-			{Name: "Standard", Size: 151, Address: 0xFFFFFFFF},
+			{Name: "Kanto", Alias: "Standard", Size: 151, Address: 0xFFFFFFFF},
 		},
 		AddrAbilityName:    0xFFFFFFFF,
 		AddrAbilityDescPtr: 0xFFFFFFFF,
@@ -147,7 +264,9 @@ var versionLookup = map[pkm.GameCode]Version{
 		AddrSpeciesEvo:     0xFFFFFFFF,
 		AddrSpeciesName:    0xFFFFFFFF,
 		AddrSpeciesTM:      0xFFFFFFFF,
+		AddrStarters:       0xFFFFFFFF,
 		AddrTypeEffect:     0xFFFFFFFF,
 		AddrTMMove:         0xFFFFFFFF,
+		AddrTrainerData:    0xFFFFFFFF,
 	},
 }
@@ -2,49 +2,60 @@ package gen3_test
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/anaminus/pkm"
+	"github.com/anaminus/pkm/gba"
 	"github.com/anaminus/pkm/gen3"
+	"github.com/anaminus/pkm/gen3/romtest"
 	"io"
-	"os"
+	"io/ioutil"
+	"sync"
 	"testing"
 )
 
+// Location of an optional ROM dump of Pokemon Emerald (BPEE). Tests that
+// check values against a retail ROM are skipped when the file is missing.
 const ROMLocation = "rom.gba"
 
-var rom io.ReadSeeker
+var (
+	romOnce sync.Once
+	rom     []byte
+	romErr  error
 
+	syntheticOnce sync.Once
+	synthetic     []byte
+)
+
+// Returns a reader of the ROM at ROMLocation, skipping the test if the file
+// cannot be read.
 func ROM(t *testing.T) io.ReadSeeker {
-	if rom != nil {
-		return rom
+	romOnce.Do(func() {
+		rom, romErr = ioutil.ReadFile(ROMLocation)
+	})
+	if romErr != nil {
+		t.Skipf("requires `%s` file in the current directory, whose contents are a ROM dump of Pokemon Emerald (BPEE): %s", ROMLocation, romErr)
 	}
-	failed := false
-	defer func() {
-		if failed {
-			t.Logf("Note: tests require `%s` file in the current directory, whose contents are a ROM dump of Pokemon Emerald (BPEE)", ROMLocation)
-		}
-	}()
-	f, err := os.Open(ROMLocation)
+	return bytes.NewReader(rom)
+}
+
+// Returns a reader of a synthetic ROM image of Pokemon Emerald (BPEE), filled
+// with the fixtures of the romtest package. The image is built once.
+func SyntheticROM() io.ReadSeeker {
+	syntheticOnce.Do(func() {
+		synthetic = romtest.Build()
+	})
+	return bytes.NewReader(synthetic)
+}
+
+// Opens a Version, failing the test if it cannot be opened.
+func openVersion(t *testing.T, r io.ReadSeeker) *gen3.Version {
+	ver, err := gen3.OpenROM(r)
 	if err != nil {
-		failed = true
 		t.Fatalf("failed to open ROM: %s", err)
 	}
-	defer f.Close()
-	s, err := f.Seek(0, 2)
-	if err != nil {
-		failed = true
-		t.Fatalf("failed to seek ROM: %s", err)
-	}
-	b := make([]byte, s)
-	_, err = f.Seek(0, 0)
-	if err != nil {
-		failed = true
-		t.Fatalf("failed to seek ROM: %s", err)
-	}
-	_, err = f.Read(b)
-	if err != nil {
-		failed = true
-		t.Fatalf("failed to read ROM: %s", err)
-	}
-	return bytes.NewReader(b)
+	return ver.(*gen3.Version)
 }
 
 func ExpectPanic(t *testing.T, s string, f func()) {
@@ -60,15 +71,166 @@ func ExpectPanic(t *testing.T, s string, f func()) {
 ////////////////////////////////////////////////////////////////
 
 func TestOpenROM(t *testing.T) {
-	ver := gen3.OpenROM(ROM(t))
-	if ver == nil {
-		t.Fatalf("OpenROM: failed to open ROM")
+	ver, err := gen3.OpenROM(ROM(t))
+	if err != nil {
+		t.Fatalf("OpenROM: failed to open ROM: %s", err)
 	}
 	if ver.GameCode() != gen3.CodeEmeraldEN {
 		t.Fatalf("expected version game code `%s`, got `%s`", gen3.CodeEmeraldEN, ver.GameCode())
 	}
+}
+
+func TestOpenROMSynthetic(t *testing.T) {
+	ver, err := gen3.OpenROM(SyntheticROM())
+	if err != nil {
+		t.Fatalf("OpenROM: failed to open ROM: %s", err)
+	}
+	if ver.GameCode() != gen3.CodeEmeraldEN {
+		t.Fatalf("expected version game code `%s`, got `%s`", gen3.CodeEmeraldEN, ver.GameCode())
+	}
+}
+
+func TestOpenROMHeader(t *testing.T) {
+	if _, err := gen3.OpenROM(bytes.NewReader([]byte{})); err == nil {
+		t.Fatalf("OpenROM: expected error for empty ROM")
+	}
+	if _, err := gen3.OpenROM(bytes.NewReader(make([]byte, 0x100))); !errors.Is(err, gba.ErrLogo) {
+		t.Fatalf("OpenROM: expected ErrLogo, got %v", err)
+	}
+	b := gba.NewHeader(pkm.GameCode{'A', 'A', 'A', 'E'}, "UNSUPPORTED").Bytes()
+	if _, err := gen3.OpenROM(bytes.NewReader(b)); err != gen3.ErrUnsupported {
+		t.Fatalf("OpenROM: expected ErrUnsupported, got %v", err)
+	}
+	b = gba.NewHeader(gen3.CodeEmeraldEN, "POKEMON EMER").Bytes()
+	b[0xA0] = 'X'
+	if _, err := gen3.OpenROM(bytes.NewReader(b)); !errors.Is(err, gba.ErrComplement) {
+		t.Fatalf("OpenROM: expected ErrComplement, got %v", err)
+	}
+}
+
+func TestConcurrentRead(t *testing.T) {
+	ver, buf := emptyROM(t, 0x1000000)
+	for i := 0; i < ver.SpeciesIndexSize(); i++ {
+		if err := ver.SpeciesByIndex(i).(gen3.Species).SetName(fmt.Sprintf("S%d", i)); err != nil {
+			t.Fatalf("SetName: unexpected error: %s", err)
+		}
+	}
 
-	if gen3.OpenROM(bytes.NewReader([]byte{})) != nil {
-		t.Fatalf("OpenROM: expected no Version")
+	readers := map[string]io.ReadSeeker{
+		"ReaderAt": bytes.NewReader(buf.Bytes()),
+		// Hides ReadAt, so that the ReadSeeker is adapted.
+		"ReadSeeker": struct{ io.ReadSeeker }{bytes.NewReader(buf.Bytes())},
+	}
+	for name, r := range readers {
+		ver, err := gen3.OpenROM(r)
+		if err != nil {
+			t.Fatalf("%s: failed to open ROM: %s", name, err)
+		}
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := g; i < ver.SpeciesIndexSize(); i += 8 {
+					if v, e := ver.SpeciesByIndex(i).Name(), fmt.Sprintf("S%d", i); v != e {
+						t.Errorf("%s: expected name %q, got %q", name, e, v)
+						return
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+	}
+}
+
+func TestValidate(t *testing.T) {
+	ver, _ := emptyROM(t, 0x1000000)
+	errs := ver.Validate()
+	messages := map[string]bool{}
+	for _, err := range errs {
+		messages[err.Error()] = true
+	}
+	for _, e := range []string{
+		"species 277 description pointer 0x00000000 invalid",
+		"move 1 description pointer 0x00000000 invalid",
+		"item 0 description pointer 0x00000000 invalid",
+	} {
+		if !messages[e] {
+			t.Errorf("Validate: expected error %q", e)
+		}
+	}
+	for _, err := range errs {
+		if _, ok := err.(gen3.TableError); ok {
+			t.Errorf("Validate: unexpected table error %q", err)
+		}
+	}
+
+	// Truncated ROM.
+	ver, _ = emptyROM(t, 0x318000)
+	found := false
+	for _, err := range ver.Validate() {
+		if err, ok := err.(gen3.TableError); ok && err.Table == "species name" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Validate: expected error for truncated species name table")
+	}
+}
+
+// Fails each read from a region of a ROM.
+type failReaderAt struct {
+	r          io.ReaderAt
+	start, end int64
+}
+
+var errRead = errors.New("read failed")
+
+func (f failReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < f.end && off+int64(len(p)) > f.start {
+		return 0, errRead
+	}
+	return f.r.ReadAt(p, off)
+}
+
+func TestValidateSynthetic(t *testing.T) {
+	b := romtest.Build()
+	ver := openVersion(t, bytes.NewReader(b))
+	for _, err := range ver.Validate() {
+		t.Errorf("Validate: unexpected error %q", err)
+	}
+
+	// Layout of map 0 of bank 0.
+	addr := func(p uint32) uint32 { return binary.LittleEndian.Uint32(b[p-0x08000000:]) }
+	header := addr(addr(addr(uint32(ver.AddrBanksPtr))))
+	binary.LittleEndian.PutUint32(b[header-0x08000000:], 0x09000000)
+	ver = openVersion(t, bytes.NewReader(b))
+	found := false
+	for _, err := range ver.Validate() {
+		if err.Error() == "bank 0 map 0 layout pointer 0x09000000 invalid" {
+			found = true
+		} else {
+			t.Errorf("Validate: unexpected error %q", err)
+		}
+	}
+	if !found {
+		t.Errorf("Validate: expected error for map layout")
+	}
+
+	// Errors from reading the ROM are returned.
+	b = romtest.Build()
+	off := int64(ver.AddrMapLabel - 0x08000000)
+	v, err := gen3.OpenReaderAt(failReaderAt{r: bytes.NewReader(b), start: off, end: off + 8})
+	if err != nil {
+		t.Fatalf("OpenReaderAt: unexpected error: %s", err)
+	}
+	found = false
+	for _, err := range v.(*gen3.Version).Validate() {
+		if errors.Is(err, errRead) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Validate: expected read error")
 	}
 }
@@ -24,14 +24,30 @@ type Item struct {
 }
 
 func (i Item) Name() string {
-	b := readStruct(
-		i.v.ROM,
-		i.v.AddrItemData,
-		i.i,
-		structItemData,
-		0,
-	)
-	return decodeTextString(b)
+	return i.v.text(textItemName, i.i, indexSizeItem, func(j int) string {
+		b := readStruct(
+			i.v.table(tableItemData),
+			i.v.AddrItemData,
+			j,
+			structItemData,
+			0,
+		)
+		return decodeTextString(b)
+	})
+}
+
+// SetName sets the name of the item. Returns a *NameError if the name does not
+// fit.
+func (i Item) SetName(name string) error {
+	b, err := encodeName(name, structItemData.FieldSize(0))
+	if err != nil {
+		return err
+	}
+	w, err := i.v.writer()
+	if err != nil {
+		return err
+	}
+	return writeStruct(w, i.v.AddrItemData, i.i, structItemData, b, 0)
 }
 
 func (i Item) Index() int {
@@ -39,20 +55,34 @@ func (i Item) Index() int {
 }
 
 func (i Item) Description() string {
+	return i.v.text(textItemDescription, i.i, indexSizeItem, func(j int) string {
+		b := readStruct(
+			i.v.table(tableItemData),
+			i.v.AddrItemData,
+			j,
+			structItemData,
+			5,
+		)
+		return readTextString(i.v.reader(decPtr(b).ROM()))
+	})
+}
+
+// Pocket returns the bag pocket in which the item is stored: 1 for items, 2
+// for balls, 3 for TMs and HMs, 4 for berries, and 5 for key items.
+func (i Item) Pocket() byte {
 	b := readStruct(
-		i.v.ROM,
+		i.v.table(tableItemData),
 		i.v.AddrItemData,
 		i.i,
 		structItemData,
-		5,
+		7,
 	)
-	i.v.ROM.Seek(decPtr(b).ROM(), 0)
-	return readTextString(i.v.ROM)
+	return b[0]
 }
 
 func (i Item) Price() int {
 	b := readStruct(
-		i.v.ROM,
+		i.v.table(tableItemData),
 		i.v.AddrItemData,
 		i.i,
 		structItemData,
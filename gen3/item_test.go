@@ -2,13 +2,14 @@ package gen3_test
 
 import (
 	"github.com/anaminus/pkm/gen3"
+	"github.com/anaminus/pkm/gen3/romtest"
 	"testing"
 )
 
 func TestItem(t *testing.T) {
-	ver := gen3.OpenROM(ROM(t))
-	if ver == nil {
-		t.Fatalf("failed to open ROM")
+	ver, err := gen3.OpenROM(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
 	}
 
 	item := ver.ItemByIndex(1)
@@ -39,3 +40,38 @@ func TestItem(t *testing.T) {
 		t.Errorf("Price: unexpected result %d", v)
 	}
 }
+
+func TestItemSynthetic(t *testing.T) {
+	ver, err := gen3.OpenROM(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	item := ver.ItemByIndex(1)
+	if v := item.Index(); v != 1 {
+		t.Errorf("Index: unexpected result %d", v)
+	}
+	if v := item.Name(); v != romtest.ItemName(1) {
+		t.Errorf("Name: unexpected result \"%s\"", v)
+	}
+	if v := item.Description(); v != romtest.ItemDescription(1) {
+		t.Errorf("Description: unexpected result \"%s\"", v)
+	}
+	if v := item.Price(); v != romtest.ItemPrice(1) {
+		t.Errorf("Price: unexpected result %d", v)
+	}
+
+	item = ver.ItemByIndex(13)
+	if v := item.Index(); v != 13 {
+		t.Errorf("Index: unexpected result %d", v)
+	}
+	if v := item.Name(); v != romtest.ItemName(13) {
+		t.Errorf("Name: unexpected result \"%s\"", v)
+	}
+	if v := item.Description(); v != romtest.ItemDescription(13) {
+		t.Errorf("Description: unexpected result \"%s\"", v)
+	}
+	if v := item.Price(); v != romtest.ItemPrice(13) {
+		t.Errorf("Price: unexpected result %d", v)
+	}
+}
@@ -2,9 +2,14 @@ package gen3
 
 import (
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"github.com/anaminus/pkm"
+	"github.com/anaminus/pkm/gba/compress"
 	"image"
 	"image/color"
+	"io"
+	"math"
 	"strings"
 )
 
@@ -136,10 +141,16 @@ func (m Map) Index() int {
 
 // Get pointer to map header.
 func (m Map) headerPtr() ptr {
-	m.v.ROM.Seek(m.v.AddrBanksPtr.ROM(), 0)
+	if m.v.cache == nil {
+		return m.readHeaderPtr()
+	}
+	return m.v.cache.get().headerPtrs(m.v)[m.b][m.i]
+}
+
+func (m Map) readHeaderPtr() ptr {
 	b := readStruct(
 		m.v.ROM,
-		readPtr(m.v.ROM),
+		readPtr(m.v.reader(m.v.AddrBanksPtr.ROM())),
 		m.b,
 		structPtr,
 	)
@@ -175,8 +186,7 @@ func (m Map) Layout() pkm.Layout {
 		height: height,
 		cells:  make([]byte, width*height*2),
 	}
-	m.v.ROM.Seek(decPtr(b[8:12]).ROM(), 0)
-	m.v.ROM.Read(l.cells)
+	m.v.ROM.ReadAt(l.cells, decPtr(b[8:12]).ROM())
 	return l
 }
 
@@ -207,8 +217,7 @@ func (m Map) Border() pkm.Layout {
 		height: height,
 		cells:  make([]byte, width*height*2),
 	}
-	m.v.ROM.Seek(decPtr(b[0:4]).ROM(), 0)
-	m.v.ROM.Read(l.cells)
+	m.v.ROM.ReadAt(l.cells, decPtr(b[0:4]).ROM())
 	return l
 }
 
@@ -320,14 +329,14 @@ func (m Map) readTileset(ts *_tileset, p ptr, off int) {
 	// half.
 	{
 		const size = len(ts.image) / 2
-		m.v.ROM.Seek(decPtr(header[4:8]).ROM(), 0)
+		r := m.v.reader(decPtr(header[4:8]).ROM())
 		if header[0] == 1 {
-			b, ok := readLZ77(m.v.ROM)
-			if ok {
+			b, err := compress.Decompress(r)
+			if err == nil {
 				copy(ts.image[off*size:], b)
 			}
 		} else {
-			m.v.ROM.Read(ts.image[off*size : off*size+size])
+			r.Read(ts.image[off*size : off*size+size])
 		}
 	}
 	// Palette
@@ -353,14 +362,13 @@ func (m Map) readTileset(ts *_tileset, p ptr, off int) {
 	// is drawn when no opaque colors have been drawn to a pixel.
 	{
 		const size = 32 * 6
-		m.v.ROM.Seek(decPtr(header[8:12]).ROM()+32*6*int64(header[1]), 0)
-		m.v.ROM.Read(ts.pal[off*size : off*size+size])
+		addr := decPtr(header[8:12]).ROM() + 32*6*int64(header[1])
+		m.v.ROM.ReadAt(ts.pal[off*size:off*size+size], addr)
 	}
 	// Blocks
 	{
 		const size = len(ts.blocks) / 2
-		m.v.ROM.Seek(decPtr(header[12:16]).ROM(), 0)
-		m.v.ROM.Read(ts.blocks[off*size : off*size+size])
+		m.v.ROM.ReadAt(ts.blocks[off*size:off*size+size], decPtr(header[12:16]).ROM())
 	}
 }
 
@@ -490,26 +498,32 @@ func (m Map) Connections() []pkm.Connection {
 		structMapHeader,
 		3,
 	)
+	p := decPtr(b)
+	if !p.ValidROM() {
+		return nil
+	}
 	b = readStruct(
 		m.v.ROM,
-		decPtr(b),
+		p,
 		0,
 		structConnHeader,
 	)
 	n := int(decUint32(b[0:4]))
+	p = decPtr(b[4:8])
+	if !p.ValidROM() {
+		return nil
+	}
 	conns := make([]pkm.Connection, n)
-
-	m.v.ROM.Seek(decPtr(b[4:8]).ROM(), 0)
 	for i := range conns {
 		b = readStruct(
 			m.v.ROM,
-			m.headerPtr(),
+			p,
 			i,
 			structConnData,
 		)
 		conns[i] = pkm.Connection{
 			Direction: pkm.Direction(decUint32(b[0:4])),
-			Offset:    int(decUint32(b[4:8])),
+			Offset:    int(int32(decUint32(b[4:8]))),
 			Bank:      int(b[8]),
 			Map:       int(b[9]),
 		}
@@ -519,13 +533,112 @@ func (m Map) Connections() []pkm.Connection {
 
 ////////////////////////////////////////////////////////////////
 
+// Returns the address of the encounter list. The game refers to the list
+// through a pointer within its code, which is located the first time the list
+// is needed. The pointer is rewritten when the list is moved, so the list is
+// found at its new address when the ROM is opened again. If no such pointer is
+// found, then AddrEncounterList is returned.
+func (v *Version) encounterList() ptr {
+	v.encounterOnce.Do(func() {
+		v.encounterPtr = v.findEncounterPtr()
+	})
+	if v.encounterPtr == 0 {
+		return v.AddrEncounterList
+	}
+	return readPtr(v.reader(v.encounterPtr.ROM()))
+}
+
+// Searches the code of the game, which precedes the original encounter list,
+// for a pointer to the list. A pointer to AddrEncounterList is preferred.
+// Otherwise, as when the list has been moved, the first pointer to a valid
+// encounter list is used. Returns 0 if no pointer is found.
+func (v *Version) findEncounterPtr() ptr {
+	if !v.AddrEncounterList.ValidROM() {
+		return 0
+	}
+	size, err := v.romSize()
+	if err != nil {
+		return 0
+	}
+	if end := v.AddrEncounterList.ROM(); end < size {
+		size = end
+	}
+	// Returns the location of the first pointer within the code that matches.
+	scan := func(match func(p ptr) bool) ptr {
+		ps := int64(structPtr.Size())
+		buf := make([]byte, 1<<16)
+		for off := int64(0); off < size; off += int64(len(buf)) {
+			m, err := v.ROM.ReadAt(buf, off)
+			if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+				return 0
+			}
+			for i := int64(0); i+ps <= int64(m) && off+i+ps <= size; i += ps {
+				if match(decPtr(buf[i : i+ps])) {
+					return ptr(addrROM + off + i)
+				}
+			}
+		}
+		return 0
+	}
+	if p := scan(func(p ptr) bool { return p == v.AddrEncounterList }); p != 0 {
+		return p
+	}
+	valid := map[ptr]bool{}
+	return scan(func(p ptr) bool {
+		if !p.ValidROM() || p%4 != 0 {
+			return false
+		}
+		ok, checked := valid[p]
+		if !checked {
+			ok = v.validEncounterList(p)
+			valid[p] = ok
+		}
+		return ok
+	})
+}
+
+// Reports whether the data at an address has the structure of an encounter
+// list. Each entry must have at least one valid table pointer, and the list
+// must be terminated.
+func (v *Version) validEncounterList(addr ptr) bool {
+	size, err := v.romSize()
+	if err != nil {
+		return false
+	}
+	n := structEncounterPtrs.Size()
+	for i := 0; addr.ROM()+int64((i+1)*n) <= size; i++ {
+		b := readStruct(v.ROM, addr, i, structEncounterPtrs)
+		if b[0] == 0xFF && b[1] == 0xFF {
+			return i > 0
+		}
+		if b[2] != 0 || b[3] != 0 {
+			return false
+		}
+		populated := false
+		for a := 4; a < n; a += structPtr.Size() {
+			switch p := decPtr(b[a : a+structPtr.Size()]); {
+			case p == 0:
+			case p.ValidROM() && p%4 == 0:
+				populated = true
+			default:
+				return false
+			}
+		}
+		if !populated {
+			return false
+		}
+	}
+	return false
+}
+
 func (m Map) Encounters() []pkm.EncounterList {
+	list := m.v.encounterList()
 	ptrs := [4]ptr{}
 	for p := 0; p < len(ptrs); p++ {
 		for i := 0; ; i++ {
 			b := readStruct(
 				m.v.ROM,
-				m.v.AddrEncounterList,
+				list,
 				i,
 				structEncounterPtrs,
 				0, 1,
@@ -535,7 +648,7 @@ func (m Map) Encounters() []pkm.EncounterList {
 			} else if int(b[0]) == m.BankIndex() && int(b[1]) == m.Index() {
 				b := readStruct(
 					m.v.ROM,
-					m.v.AddrEncounterList,
+					list,
 					i,
 					structEncounterPtrs,
 					p+3,
@@ -553,6 +666,139 @@ func (m Map) Encounters() []pkm.EncounterList {
 	}
 }
 
+// Returns the index of the map within the encounter list, and the number of
+// entries in the list, excluding the terminator. The index is -1 if the map
+// has no entry.
+func (m Map) encounterEntry() (index, n int) {
+	list := m.v.encounterList()
+	index = -1
+	for i := 0; ; i++ {
+		b := readStruct(
+			m.v.ROM,
+			list,
+			i,
+			structEncounterPtrs,
+			0, 1,
+		)
+		if b[0] == 0xFF && b[1] == 0xFF {
+			return index, i
+		} else if index < 0 && int(b[0]) == m.BankIndex() && int(b[1]) == m.Index() {
+			index = i
+		}
+	}
+}
+
+// Adds an entry for the map to the encounter list, returning the index of the
+// entry. The list is grown in place if the space following it is unused.
+// Otherwise, the list is moved to free space, and the pointers to it within
+// the code of the game are rewritten.
+func (m Map) addEncounterEntry(n int) (int, error) {
+	w, err := m.v.writer()
+	if err != nil {
+		return 0, err
+	}
+	f, err := m.v.FreeSpace()
+	if err != nil {
+		return 0, err
+	}
+	list := m.v.encounterList()
+	size := structEncounterPtrs.Size()
+	entry := make([]byte, size)
+	entry[0] = byte(m.BankIndex())
+	entry[1] = byte(m.Index())
+	term, err := readStructErr(m.v.ROM, list, n, structEncounterPtrs)
+	if err != nil {
+		return 0, err
+	}
+
+	end := list + ptr((n+1)*size)
+	if f.take(end, size) {
+		if err := writeStruct(w, list, n+1, structEncounterPtrs, term); err != nil {
+			return 0, err
+		}
+		return n, writeStruct(w, list, n, structEncounterPtrs, entry)
+	}
+
+	b := make([]byte, 0, (n+2)*size)
+	for i := 0; i < n; i++ {
+		e, err := readStructErr(m.v.ROM, list, i, structEncounterPtrs)
+		if err != nil {
+			return 0, err
+		}
+		b = append(b, e...)
+	}
+	b = append(b, entry...)
+	b = append(b, term...)
+	addr, err := f.alloc(len(b))
+	if err != nil {
+		return 0, err
+	}
+	if err := writeBytes(w, addr, b); err != nil {
+		return 0, err
+	}
+	if _, err := f.repoint(list, addr); err != nil {
+		return 0, err
+	}
+	if err := f.free(list, (n+1)*size); err != nil {
+		return 0, err
+	}
+	if m.v.encounterPtr == 0 {
+		// Without a pointer in the code, the new location is known only to
+		// this Version.
+		m.v.AddrEncounterList = addr
+	}
+	return n, nil
+}
+
+// AddEncounterList adds an encounter table to the area of the map at the given
+// index of Encounters, returning the new list. If the area is already
+// populated, then the existing list is returned. The slots of a new table are
+// empty, and its encounter rate is 0.
+//
+// If the map has no entry in the encounter list, then an entry is added. If
+// the list cannot grow in place, then it is moved to free space, and the
+// pointers to it within the code of the game are rewritten, so that the moved
+// list is found when the ROM is opened again.
+func (m Map) AddEncounterList(index int) (pkm.EncounterList, error) {
+	lists := m.Encounters()
+	if index < 0 || index >= len(lists) {
+		panic("encounter list index out of bounds")
+	}
+	if lists[index].Populated() {
+		return lists[index], nil
+	}
+	w, err := m.v.writer()
+	if err != nil {
+		return nil, err
+	}
+	f, err := m.v.FreeSpace()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, n := m.encounterEntry()
+	if entry < 0 {
+		if entry, err = m.addEncounterEntry(n); err != nil {
+			return nil, err
+		}
+	}
+
+	hsize := structEncounterHeader.Size()
+	b := make([]byte, hsize+lists[index].EncounterIndexSize()*structEncounter.Size())
+	addr, err := f.alloc(len(b))
+	if err != nil {
+		return nil, err
+	}
+	copy(b[hsize-structPtr.Size():], encUint32(uint32(addr+ptr(hsize))))
+	if err := writeBytes(w, addr, b); err != nil {
+		return nil, err
+	}
+	if err := writeStruct(w, m.v.encounterList(), entry, structEncounterPtrs, encUint32(uint32(addr)), index+3); err != nil {
+		return nil, err
+	}
+	return m.Encounters()[index], nil
+}
+
 func (m Map) Name() string {
 	b := readStruct(
 		m.v.ROM,
@@ -567,8 +813,7 @@ func (m Map) Name() string {
 		int(b[0]),
 		structMapLabel,
 	)
-	m.v.ROM.Seek(decPtr(b[4:8]).ROM(), 0)
-	return readTextString(m.v.ROM)
+	return readTextString(m.v.reader(decPtr(b[4:8]).ROM()))
 }
 
 ////////////////////////////////////////////////////////////////
@@ -612,8 +857,74 @@ func (e Encounter) Species() pkm.Species {
 	return Species{v: e.v, i: int(decUint16(b))}
 }
 
+// Writes a field of the encounter.
+func (e Encounter) setData(b []byte, field int) error {
+	w, err := e.v.writer()
+	if err != nil {
+		return err
+	}
+	return writeStruct(
+		w,
+		e.p,
+		e.i,
+		structEncounter,
+		b,
+		field,
+	)
+}
+
+func checkLevel(level int) error {
+	if level < 1 || level > 100 {
+		return fmt.Errorf("level %d out of range 1-100", level)
+	}
+	return nil
+}
+
+func (e Encounter) SetMinLevel(level int) error {
+	if err := checkLevel(level); err != nil {
+		return err
+	}
+	return e.setData([]byte{byte(level)}, 0)
+}
+
+func (e Encounter) SetMaxLevel(level int) error {
+	if err := checkLevel(level); err != nil {
+		return err
+	}
+	return e.setData([]byte{byte(level)}, 1)
+}
+
+func (e Encounter) SetSpecies(species pkm.Species) error {
+	return e.setData(encUint16(uint16(species.Index())), 2)
+}
+
 ////////////////////////////////////////////////////////////////
 
+// ErrUnpopulated is returned when modifying an encounter list that has no
+// encounter table.
+var ErrUnpopulated = errors.New("encounter list is not populated")
+
+func setEncounterRate(v *Version, p ptr, rate float64) error {
+	if rate < 0 || rate > 1 {
+		return fmt.Errorf("encounter rate %g out of range 0-1", rate)
+	}
+	w, err := v.writer()
+	if err != nil {
+		return err
+	}
+	if !p.ValidROM() {
+		return ErrUnpopulated
+	}
+	return writeStruct(
+		w,
+		p,
+		0,
+		structEncounterHeader,
+		[]byte{byte(math.Round(rate * 255))},
+		0,
+	)
+}
+
 func encounterRate(v *Version, p ptr) float64 {
 	if !p.ValidROM() {
 		return 0
@@ -687,6 +998,12 @@ func (e EncounterGrass) EncounterRate() float64 {
 	return encounterRate(e.v, e.p)
 }
 
+// SetEncounterRate sets the encounter rate of the list. Returns
+// ErrUnpopulated if the list has no encounter table.
+func (e EncounterGrass) SetEncounterRate(rate float64) error {
+	return setEncounterRate(e.v, e.p, rate)
+}
+
 func (e EncounterGrass) Encounters() []pkm.Encounter {
 	return encounters(e.v, e.p, e.EncounterIndexSize())
 }
@@ -737,6 +1054,12 @@ func (e EncounterWater) EncounterRate() float64 {
 	return encounterRate(e.v, e.p)
 }
 
+// SetEncounterRate sets the encounter rate of the list. Returns
+// ErrUnpopulated if the list has no encounter table.
+func (e EncounterWater) SetEncounterRate(rate float64) error {
+	return setEncounterRate(e.v, e.p, rate)
+}
+
 func (e EncounterWater) Encounters() []pkm.Encounter {
 	return encounters(e.v, e.p, e.EncounterIndexSize())
 }
@@ -787,6 +1110,12 @@ func (e EncounterRock) EncounterRate() float64 {
 	return encounterRate(e.v, e.p)
 }
 
+// SetEncounterRate sets the encounter rate of the list. Returns
+// ErrUnpopulated if the list has no encounter table.
+func (e EncounterRock) SetEncounterRate(rate float64) error {
+	return setEncounterRate(e.v, e.p, rate)
+}
+
 func (e EncounterRock) Encounters() []pkm.Encounter {
 	return encounters(e.v, e.p, e.EncounterIndexSize())
 }
@@ -837,6 +1166,12 @@ func (e EncounterRod) EncounterRate() float64 {
 	return encounterRate(e.v, e.p)
 }
 
+// SetEncounterRate sets the encounter rate of the list. Returns
+// ErrUnpopulated if the list has no encounter table.
+func (e EncounterRod) SetEncounterRate(rate float64) error {
+	return setEncounterRate(e.v, e.p, rate)
+}
+
 func (e EncounterRod) Encounters() []pkm.Encounter {
 	return encounters(e.v, e.p, e.EncounterIndexSize())
 }
@@ -1,14 +1,32 @@
 package gen3_test
 
 import (
+	"bytes"
 	"github.com/anaminus/pkm/gen3"
+	"github.com/anaminus/pkm/gen3/romtest"
+	"strings"
 	"testing"
 )
 
+// Returns a synthetic ROM image in which the space following the terminator of
+// the encounter list is unused, so that the list can grow in place.
+func growableROM(t *testing.T) *gen3.Buffer {
+	b := romtest.Build()
+	ver := openVersion(t, bytes.NewReader(b))
+	off := int(ver.AddrEncounterList) - 0x08000000
+	for b[off] != 0xFF || b[off+1] != 0xFF {
+		off += 20
+	}
+	for i := off + 20; i < off+0x100; i++ {
+		b[i] = 0xFF
+	}
+	return gen3.NewBuffer(b)
+}
+
 func TestBank(t *testing.T) {
-	ver := gen3.OpenROM(ROM(t))
-	if ver == nil {
-		t.Fatalf("failed to open ROM")
+	ver, err := gen3.OpenROM(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
 	}
 
 	ver.ScanBanks()
@@ -42,10 +60,47 @@ func TestBank(t *testing.T) {
 	}
 }
 
+func TestBankSynthetic(t *testing.T) {
+	ver, err := gen3.OpenROM(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	ver.ScanBanks()
+	b := ver.BankByIndex(0)
+	if v := b.Index(); v != 0 {
+		t.Errorf("Index: unexpected result %d", v)
+	}
+	if v := b.MapIndexSize(); v != 3 {
+		t.Errorf("MapIndexSize: unexpected result %d", v)
+	}
+	if v := b.Maps(); len(v) != 3 {
+		t.Errorf("Maps: unexpected result length %d", len(v))
+	}
+	if v := b.MapByIndex(2); v == nil {
+		t.Errorf("MapByIndex: unexpected result <nil>")
+	} else if v.Index() != 2 {
+		t.Errorf("MapByIndex: unexpected result %d (%s)", v.Index(), v.Name())
+	}
+	ExpectPanic(t, "MapByIndex", func() {
+		b.MapByIndex(3)
+	})
+	if v := b.MapByName(romtest.MapLabel(3)); v == nil {
+		t.Errorf("MapByIndex: unexpected result <nil>")
+	} else if v.Index() != 2 {
+		t.Errorf("MapByName: unexpected result %d (%s)", v.Index(), v.Name())
+	} else if v := b.MapByName(strings.ToLower(romtest.MapLabel(3))); v == nil {
+		t.Errorf("MapByName: not case-insensitive")
+	}
+	if v := b.MapByName("unknown"); v != nil {
+		t.Errorf("MapByName: expected nil result")
+	}
+}
+
 func TestMap(t *testing.T) {
-	ver := gen3.OpenROM(ROM(t))
-	if ver == nil {
-		t.Fatalf("failed to open ROM")
+	ver, err := gen3.OpenROM(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
 	}
 
 	ver.ScanBanks()
@@ -61,10 +116,29 @@ func TestMap(t *testing.T) {
 	}
 }
 
+func TestMapSynthetic(t *testing.T) {
+	ver, err := gen3.OpenROM(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	ver.ScanBanks()
+	m := ver.BankByIndex(0).MapByIndex(2)
+	if v := m.BankIndex(); v != 0 {
+		t.Errorf("BankIndex: unexpected result %d", v)
+	}
+	if v := m.Index(); v != 2 {
+		t.Errorf("Index: unexpected result %d", v)
+	}
+	if v := m.Name(); v != romtest.MapLabel(3) {
+		t.Errorf("Name: unexpected result \"%s\"", v)
+	}
+}
+
 func TestEncounters(t *testing.T) {
-	ver := gen3.OpenROM(ROM(t))
-	if ver == nil {
-		t.Fatalf("failed to open ROM")
+	ver, err := gen3.OpenROM(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
 	}
 
 	ver.ScanBanks()
@@ -88,10 +162,10 @@ func TestEncounters(t *testing.T) {
 			t.Errorf("EncounterList.EncounterIndexSize: unexpected result %d", v)
 		}
 		if v := e.Encounters(); v != nil {
-			t.Errorf("EncounterList.Encounters: unexpected non-nil result", v)
+			t.Errorf("EncounterList.Encounters: unexpected non-nil result")
 		}
 		if v := e.Encounter(0); v != nil {
-			t.Errorf("EncounterList.Encounter: unexpected non-nil result", v)
+			t.Errorf("EncounterList.Encounter: unexpected non-nil result")
 		}
 		ExpectPanic(t, "EncounterList.Encounter", func() {
 			e.Encounter(e.EncounterIndexSize())
@@ -266,6 +340,212 @@ func TestEncounters(t *testing.T) {
 	}
 }
 
+func TestEncountersSynthetic(t *testing.T) {
+	ver, err := gen3.OpenROM(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	ver.ScanBanks()
+	for i, e := range ver.BankByIndex(1).MapByIndex(0).Encounters() {
+		if e.Populated() {
+			t.Errorf("Encounters: unexpected populated EncounterList #%d (%s) of map 1.0", i, e.Name())
+		}
+	}
+	{
+		e := ver.BankByIndex(0).MapByIndex(1).Encounters()[0]
+		if v := e.Name(); v != "Grass" {
+			t.Errorf("EncounterList.Name: unexpected result \"%s\"", v)
+		}
+		if v := e.Populated(); v {
+			t.Errorf("EncounterList.Populated: unexpected result %t", v)
+		}
+		if v := e.EncounterRate(); v != 0 {
+			t.Errorf("EncounterList.EncounterRate: unexpected result %f", v)
+		}
+		if v := e.EncounterIndexSize(); v != 12 {
+			t.Errorf("EncounterList.EncounterIndexSize: unexpected result %d", v)
+		}
+		if v := e.Encounters(); v != nil {
+			t.Errorf("EncounterList.Encounters: unexpected non-nil result")
+		}
+		if v := e.Encounter(0); v != nil {
+			t.Errorf("EncounterList.Encounter: unexpected non-nil result")
+		}
+		ExpectPanic(t, "EncounterList.Encounter", func() {
+			e.Encounter(e.EncounterIndexSize())
+		})
+		if v := e.SpeciesRate(0); v != 0.2 {
+			t.Errorf("EncounterList.SpeciesRate: unexpected result %g", v)
+		}
+	}
+
+	m := ver.BankByIndex(0).MapByIndex(2)
+	e := m.Encounters()
+	if len(e) != 4 {
+		t.Errorf("Encounters: unexpected result length %d", len(e))
+	}
+	if e, ok := e[0].(gen3.EncounterGrass); !ok {
+		t.Errorf("type assertion of EncounterList #0 failed: expected gen3.EncounterGrass")
+	} else {
+		ExpectPanic(t, "EncounterGrass.SpeciesRate", func() {
+			e.SpeciesRate(e.EncounterIndexSize())
+		})
+		if v := e.SpeciesRate(0); v != 0.2 {
+			t.Errorf("EncounterGrass.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(2); v != 0.1 {
+			t.Errorf("EncounterGrass.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(6); v != 0.05 {
+			t.Errorf("EncounterGrass.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(8); v != 0.04 {
+			t.Errorf("EncounterGrass.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(10); v != 0.01 {
+			t.Errorf("EncounterGrass.SpeciesRate: unexpected result %g", v)
+		}
+	}
+	if e, ok := e[1].(gen3.EncounterWater); !ok {
+		t.Errorf("type assertion of EncounterList #1 failed: expected gen3.EncounterWater")
+	} else {
+		ExpectPanic(t, "EncounterWater.SpeciesRate", func() {
+			e.SpeciesRate(e.EncounterIndexSize())
+		})
+		if v := e.SpeciesRate(0); v != 0.6 {
+			t.Errorf("EncounterWater.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(1); v != 0.3 {
+			t.Errorf("EncounterWater.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(2); v != 0.05 {
+			t.Errorf("EncounterWater.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(3); v != 0.04 {
+			t.Errorf("EncounterWater.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(4); v != 0.01 {
+			t.Errorf("EncounterWater.SpeciesRate: unexpected result %g", v)
+		}
+	}
+	if e, ok := e[2].(gen3.EncounterRock); !ok {
+		t.Errorf("type assertion of EncounterList #2 failed: expected gen3.EncounterRock")
+	} else {
+		ExpectPanic(t, "EncounterRock.SpeciesRate", func() {
+			e.SpeciesRate(e.EncounterIndexSize())
+		})
+		if v := e.SpeciesRate(0); v != 0.6 {
+			t.Errorf("EncounterRock.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(1); v != 0.3 {
+			t.Errorf("EncounterRock.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(2); v != 0.05 {
+			t.Errorf("EncounterRock.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(3); v != 0.04 {
+			t.Errorf("EncounterRock.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(4); v != 0.01 {
+			t.Errorf("EncounterRock.SpeciesRate: unexpected result %g", v)
+		}
+	}
+	if e, ok := e[3].(gen3.EncounterRod); !ok {
+		t.Errorf("type assertion of EncounterList #3 failed: expected gen3.EncounterRod")
+	} else {
+		ExpectPanic(t, "EncounterRod.SpeciesRate", func() {
+			e.SpeciesRate(e.EncounterIndexSize())
+		})
+		if v := e.SpeciesRate(0); v != 0.7 {
+			t.Errorf("EncounterRod.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(1); v != 0.3 {
+			t.Errorf("EncounterRod.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(2); v != 0.6 {
+			t.Errorf("EncounterRod.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(3); v != 0.2 {
+			t.Errorf("EncounterRod.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(4); v != 0.2 {
+			t.Errorf("EncounterRod.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(5); v != 0.4 {
+			t.Errorf("EncounterRod.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(6); v != 0.4 {
+			t.Errorf("EncounterRod.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(7); v != 0.15 {
+			t.Errorf("EncounterRod.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(8); v != 0.04 {
+			t.Errorf("EncounterRod.SpeciesRate: unexpected result %g", v)
+		}
+		if v := e.SpeciesRate(9); v != 0.01 {
+			t.Errorf("EncounterRod.SpeciesRate: unexpected result %g", v)
+		}
+
+		ExpectPanic(t, "EncounterRod.RodType", func() {
+			e.RodType(e.EncounterIndexSize())
+		})
+		if v := e.RodType(0); v != gen3.OldRod {
+			t.Errorf("EncounterRod.RodType: unexpected result %s", v)
+		}
+		if v := e.RodType(2); v != gen3.GoodRod {
+			t.Errorf("EncounterRod.RodType: unexpected result %s", v)
+		}
+		if v := e.RodType(5); v != gen3.SuperRod {
+			t.Errorf("EncounterRod.RodType: unexpected result %s", v)
+		}
+	}
+	name := [4]string{"Grass", "Water", "Rock", "Rod"}
+	size := [4]int{12, 5, 5, 10}
+	erate := [4]byte{10, 4, 20, 30}
+	srate := [4]float64{0.2, 0.6, 0.6, 0.7}
+	for i, e := range e {
+		if v := e.Name(); v != name[i] {
+			t.Errorf("EncounterList.Name: %d: unexpected result \"%s\"", i, v)
+		}
+		if v := e.Populated(); !v {
+			t.Errorf("EncounterList.Populated: %d: unexpected result %t", i, v)
+		}
+		if v := e.EncounterRate(); byte(v*255) != erate[i] {
+			t.Errorf("EncounterList.EncounterRate: %d: unexpected result %f", i, v)
+		}
+		if v := e.EncounterIndexSize(); v != size[i] {
+			t.Errorf("EncounterList.EncounterIndexSize: %d: unexpected result %d", i, v)
+		}
+		if v := e.Encounters(); len(v) != size[i] {
+			t.Errorf("EncounterList.Encounters: %d: unexpected result length %d", i, len(v))
+		}
+		if v := e.Encounter(0); v == nil {
+			t.Errorf("EncounterList.Encounter: %d: unexpected result <nil>", i)
+		}
+		ExpectPanic(t, "EncounterList.Encounter", func() {
+			e.Encounter(e.EncounterIndexSize())
+		})
+		if v := e.SpeciesRate(0); v != srate[i] {
+			t.Errorf("EncounterList.SpeciesRate: %d: unexpected result %g", i, v)
+		}
+	}
+	{
+		fixture := romtest.Maps[2].Encounters[0][0]
+		e := e[0].Encounter(0)
+		if v := e.MinLevel(); v != fixture.MinLevel {
+			t.Errorf("Encounter.MinLevel: unexpected result %d", v)
+		}
+		if v := e.MaxLevel(); v != fixture.MaxLevel {
+			t.Errorf("Encounter.MaxLevel: unexpected result %d", v)
+		}
+		if v := e.Species(); v.Index() != fixture.Species {
+			t.Errorf("Encounter.Species: unexpected result %d (%s)", v.Index(), v.Name())
+		}
+	}
+}
+
 func TestRod(t *testing.T) {
 	if v := gen3.OldRod.String(); v != "Old" {
 		t.Errorf("OldRod.String: unexpected result \"%s\"", v)
@@ -277,3 +557,282 @@ func TestRod(t *testing.T) {
 		t.Errorf("SuperRod.String: unexpected result \"%s\"", v)
 	}
 }
+
+func TestSummarizeEncounters(t *testing.T) {
+	ver, err := gen3.OpenROM(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	ver.ScanBanks()
+	if v := gen3.SummarizeEncounters(ver.BankByIndex(1).MapByIndex(0)); len(v) != 0 {
+		t.Errorf("SummarizeEncounters: unexpected result length %d", len(v))
+	}
+
+	summaries := gen3.SummarizeEncounters(ver.BankByIndex(0).MapByIndex(26))
+	total := map[string]float64{}
+	for _, s := range summaries {
+		total[s.Area] += s.Rate
+		if s.MinLevel > s.MaxLevel {
+			t.Errorf("SummarizeEncounters: %s: %s: unexpected level range %d-%d", s.Area, s.Species.Name(), s.MinLevel, s.MaxLevel)
+		}
+	}
+	for _, area := range []string{"Grass", "Water", "Rock", "Old Rod", "Good Rod", "Super Rod"} {
+		if v, ok := total[area]; !ok {
+			t.Errorf("SummarizeEncounters: missing area %s", area)
+		} else if v < 0.999 || v > 1.001 {
+			t.Errorf("SummarizeEncounters: %s: rates sum to %g", area, v)
+		}
+	}
+	if len(total) != 6 {
+		t.Errorf("SummarizeEncounters: unexpected number of areas %d", len(total))
+	}
+
+	s := summaries[0]
+	if s.Area != "Grass" || s.Species.Index() != 27 {
+		t.Errorf("SummarizeEncounters: unexpected first summary %s %d", s.Area, s.Species.Index())
+	}
+}
+
+func TestSummarizeEncountersSynthetic(t *testing.T) {
+	ver, err := gen3.OpenROM(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	ver.ScanBanks()
+	if v := gen3.SummarizeEncounters(ver.BankByIndex(1).MapByIndex(0)); len(v) != 0 {
+		t.Errorf("SummarizeEncounters: unexpected result length %d", len(v))
+	}
+
+	summaries := gen3.SummarizeEncounters(ver.BankByIndex(0).MapByIndex(2))
+	total := map[string]float64{}
+	for _, s := range summaries {
+		total[s.Area] += s.Rate
+		if s.MinLevel > s.MaxLevel {
+			t.Errorf("SummarizeEncounters: %s: %s: unexpected level range %d-%d", s.Area, s.Species.Name(), s.MinLevel, s.MaxLevel)
+		}
+	}
+	for _, area := range []string{"Grass", "Water", "Rock", "Old Rod", "Good Rod", "Super Rod"} {
+		if v, ok := total[area]; !ok {
+			t.Errorf("SummarizeEncounters: missing area %s", area)
+		} else if v < 0.999 || v > 1.001 {
+			t.Errorf("SummarizeEncounters: %s: rates sum to %g", area, v)
+		}
+	}
+	if len(total) != 6 {
+		t.Errorf("SummarizeEncounters: unexpected number of areas %d", len(total))
+	}
+
+	s := summaries[0]
+	if s.Area != "Grass" || s.Species.Index() != romtest.Maps[2].Encounters[0][0].Species {
+		t.Errorf("SummarizeEncounters: unexpected first summary %s %d", s.Area, s.Species.Index())
+	}
+}
+
+func TestEncountersWrite(t *testing.T) {
+	buf, err := gen3.ReadBuffer(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to read ROM: %s", err)
+	}
+	ver := openVersion(t, buf)
+	ver.ScanBanks()
+
+	list := ver.BankByIndex(0).MapByIndex(26).Encounters()[3].(gen3.EncounterRod)
+	e := list.Encounter(7).(gen3.Encounter)
+	if err := e.SetSpecies(ver.SpeciesByIndex(129)); err != nil {
+		t.Errorf("SetSpecies: unexpected error: %s", err)
+	}
+	if err := e.SetMinLevel(5); err != nil {
+		t.Errorf("SetMinLevel: unexpected error: %s", err)
+	}
+	if err := e.SetMaxLevel(50); err != nil {
+		t.Errorf("SetMaxLevel: unexpected error: %s", err)
+	}
+	if err := e.SetMaxLevel(101); err == nil {
+		t.Errorf("SetMaxLevel: expected error")
+	}
+	if err := list.SetEncounterRate(0.5); err != nil {
+		t.Errorf("SetEncounterRate: unexpected error: %s", err)
+	}
+	list = ver.BankByIndex(0).MapByIndex(26).Encounters()[3].(gen3.EncounterRod)
+	if e := list.Encounter(7); e.Species().Index() != 129 || e.MinLevel() != 5 || e.MaxLevel() != 50 {
+		t.Errorf("Encounter: unexpected result %d %d-%d", e.Species().Index(), e.MinLevel(), e.MaxLevel())
+	}
+	if v := list.EncounterRate(); v != 128.0/255 {
+		t.Errorf("EncounterRate: unexpected result %g", v)
+	}
+
+	m := ver.BankByIndex(1).MapByIndex(0).(gen3.Map)
+	if err := m.Encounters()[0].(gen3.EncounterGrass).SetEncounterRate(0.1); err != gen3.ErrUnpopulated {
+		t.Errorf("SetEncounterRate: expected ErrUnpopulated, got %v", err)
+	}
+	added, err := m.AddEncounterList(0)
+	if err != nil {
+		t.Fatalf("AddEncounterList: unexpected error: %s", err)
+	}
+	if !added.Populated() {
+		t.Fatalf("AddEncounterList: list is not populated")
+	}
+	if err := added.(gen3.EncounterGrass).SetEncounterRate(0.1); err != nil {
+		t.Errorf("SetEncounterRate: unexpected error: %s", err)
+	}
+	for _, e := range added.Encounters() {
+		if err := e.(gen3.Encounter).SetSpecies(ver.SpeciesByIndex(25)); err != nil {
+			t.Errorf("SetSpecies: unexpected error: %s", err)
+		}
+	}
+	if _, err := m.AddEncounterList(3); err != nil {
+		t.Fatalf("AddEncounterList: unexpected error: %s", err)
+	}
+
+	reopened := openVersion(t, buf)
+	reopened.ScanBanks()
+	lists := reopened.BankByIndex(1).MapByIndex(0).Encounters()
+	if !lists[0].Populated() || !lists[3].Populated() || lists[1].Populated() {
+		t.Fatalf("AddEncounterList: unexpected populated lists")
+	}
+	if v := lists[0].Encounter(11).Species().Index(); v != 25 {
+		t.Errorf("AddEncounterList: unexpected species %d", v)
+	}
+	if v := gen3.SummarizeEncounters(reopened.BankByIndex(0).MapByIndex(26)); len(v) == 0 {
+		t.Errorf("AddEncounterList: existing encounters were lost")
+	}
+}
+
+func TestEncountersWriteSynthetic(t *testing.T) {
+	buf := growableROM(t)
+	ver := openVersion(t, buf)
+	ver.ScanBanks()
+
+	list := ver.BankByIndex(0).MapByIndex(2).Encounters()[3].(gen3.EncounterRod)
+	e := list.Encounter(7).(gen3.Encounter)
+	if err := e.SetSpecies(ver.SpeciesByIndex(129)); err != nil {
+		t.Errorf("SetSpecies: unexpected error: %s", err)
+	}
+	if err := e.SetMinLevel(5); err != nil {
+		t.Errorf("SetMinLevel: unexpected error: %s", err)
+	}
+	if err := e.SetMaxLevel(50); err != nil {
+		t.Errorf("SetMaxLevel: unexpected error: %s", err)
+	}
+	if err := e.SetMaxLevel(101); err == nil {
+		t.Errorf("SetMaxLevel: expected error")
+	}
+	if err := list.SetEncounterRate(0.5); err != nil {
+		t.Errorf("SetEncounterRate: unexpected error: %s", err)
+	}
+	list = ver.BankByIndex(0).MapByIndex(2).Encounters()[3].(gen3.EncounterRod)
+	if e := list.Encounter(7); e.Species().Index() != 129 || e.MinLevel() != 5 || e.MaxLevel() != 50 {
+		t.Errorf("Encounter: unexpected result %d %d-%d", e.Species().Index(), e.MinLevel(), e.MaxLevel())
+	}
+	if v := list.EncounterRate(); v != 128.0/255 {
+		t.Errorf("EncounterRate: unexpected result %g", v)
+	}
+
+	m := ver.BankByIndex(1).MapByIndex(0).(gen3.Map)
+	if err := m.Encounters()[0].(gen3.EncounterGrass).SetEncounterRate(0.1); err != gen3.ErrUnpopulated {
+		t.Errorf("SetEncounterRate: expected ErrUnpopulated, got %v", err)
+	}
+	added, err := m.AddEncounterList(0)
+	if err != nil {
+		t.Fatalf("AddEncounterList: unexpected error: %s", err)
+	}
+	if !added.Populated() {
+		t.Fatalf("AddEncounterList: list is not populated")
+	}
+	if err := added.(gen3.EncounterGrass).SetEncounterRate(0.1); err != nil {
+		t.Errorf("SetEncounterRate: unexpected error: %s", err)
+	}
+	for _, e := range added.Encounters() {
+		if err := e.(gen3.Encounter).SetSpecies(ver.SpeciesByIndex(25)); err != nil {
+			t.Errorf("SetSpecies: unexpected error: %s", err)
+		}
+	}
+	if _, err := m.AddEncounterList(3); err != nil {
+		t.Fatalf("AddEncounterList: unexpected error: %s", err)
+	}
+
+	reopened := openVersion(t, buf)
+	reopened.ScanBanks()
+	lists := reopened.BankByIndex(1).MapByIndex(0).Encounters()
+	if !lists[0].Populated() || !lists[3].Populated() || lists[1].Populated() {
+		t.Fatalf("AddEncounterList: unexpected populated lists")
+	}
+	if v := lists[0].Encounter(11).Species().Index(); v != 25 {
+		t.Errorf("AddEncounterList: unexpected species %d", v)
+	}
+	if v := gen3.SummarizeEncounters(reopened.BankByIndex(0).MapByIndex(2)); len(v) == 0 {
+		t.Errorf("AddEncounterList: existing encounters were lost")
+	}
+}
+
+func TestAddEncounterList(t *testing.T) {
+	// Data follows the encounter list, so the list is moved.
+	ver := romtest.Version()
+	ver.ScanBanks()
+	addr := gen3.EncounterList(ver)
+	m := ver.BankByIndex(1).MapByIndex(0).(gen3.Map)
+	list, err := m.AddEncounterList(1)
+	if err != nil {
+		t.Fatalf("AddEncounterList: unexpected error: %s", err)
+	}
+	if err := list.Encounter(0).(gen3.Encounter).SetSpecies(ver.SpeciesByIndex(26)); err != nil {
+		t.Errorf("SetSpecies: unexpected error: %s", err)
+	}
+	if gen3.EncounterList(ver) == addr {
+		t.Errorf("AddEncounterList: list was not moved")
+	}
+
+	// The moved list is found when the ROM is opened again.
+	var b bytes.Buffer
+	if _, err := ver.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo: unexpected error: %s", err)
+	}
+	reopened := openVersion(t, bytes.NewReader(b.Bytes()))
+	reopened.ScanBanks()
+	if gen3.EncounterList(reopened) != gen3.EncounterList(ver) {
+		t.Errorf("AddEncounterList: moved list not found after reopening")
+	}
+	lists := reopened.BankByIndex(1).MapByIndex(0).Encounters()
+	if lists[0].Populated() || !lists[1].Populated() {
+		t.Fatalf("AddEncounterList: unexpected populated lists")
+	}
+	if v := lists[1].Encounter(0).Species().Index(); v != 26 {
+		t.Errorf("AddEncounterList: unexpected species %d", v)
+	}
+	if v := reopened.BankByIndex(0).MapByIndex(1).Encounters()[3]; !v.Populated() {
+		t.Errorf("AddEncounterList: existing encounters were lost")
+	}
+
+	// The space following the list is unused, so the list grows in place.
+	buf := growableROM(t)
+	ver = openVersion(t, buf)
+	ver.ScanBanks()
+	addr = gen3.EncounterList(ver)
+	m = ver.BankByIndex(1).MapByIndex(0).(gen3.Map)
+	list, err = m.AddEncounterList(0)
+	if err != nil {
+		t.Fatalf("AddEncounterList: unexpected error: %s", err)
+	}
+	if err := list.Encounter(0).(gen3.Encounter).SetSpecies(ver.SpeciesByIndex(25)); err != nil {
+		t.Errorf("SetSpecies: unexpected error: %s", err)
+	}
+	if gen3.EncounterList(ver) != addr {
+		t.Errorf("AddEncounterList: list was moved")
+	}
+
+	// The change is seen when the ROM is opened again.
+	reopened = openVersion(t, buf)
+	reopened.ScanBanks()
+	lists = reopened.BankByIndex(1).MapByIndex(0).Encounters()
+	if !lists[0].Populated() || lists[1].Populated() {
+		t.Fatalf("AddEncounterList: unexpected populated lists")
+	}
+	if v := lists[0].Encounter(0).Species().Index(); v != 25 {
+		t.Errorf("AddEncounterList: unexpected species %d", v)
+	}
+	if v := reopened.BankByIndex(0).MapByIndex(1).Encounters()[3]; !v.Populated() {
+		t.Errorf("AddEncounterList: existing encounters were lost")
+	}
+}
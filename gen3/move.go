@@ -1,6 +1,7 @@
 package gen3
 
 import (
+	"errors"
 	"github.com/anaminus/pkm"
 	"strconv"
 )
@@ -32,13 +33,29 @@ type Move struct {
 }
 
 func (m Move) Name() string {
-	b := readStruct(
-		m.v.ROM,
-		m.v.AddrMoveName,
-		m.i,
-		structMoveName,
-	)
-	return decodeTextString(b)
+	return m.v.text(textMoveName, m.i, indexSizeMove, func(j int) string {
+		b := readStruct(
+			m.v.table(tableMoveName),
+			m.v.AddrMoveName,
+			j,
+			structMoveName,
+		)
+		return decodeTextString(b)
+	})
+}
+
+// SetName sets the name of the move. Returns a *NameError if the name does not
+// fit.
+func (m Move) SetName(name string) error {
+	b, err := encodeName(name, structMoveName.Size())
+	if err != nil {
+		return err
+	}
+	w, err := m.v.writer()
+	if err != nil {
+		return err
+	}
+	return writeStruct(w, m.v.AddrMoveName, m.i, structMoveName, b)
 }
 
 func (m Move) Index() int {
@@ -46,19 +63,52 @@ func (m Move) Index() int {
 }
 
 func (m Move) Description() string {
+	return m.v.text(textMoveDescription, m.i, indexSizeMove, func(j int) string {
+		b := readStruct(
+			m.v.table(tableMoveDescPtr),
+			m.v.AddrMoveDescPtr,
+			j-1,
+			structPtr,
+		)
+		return readTextString(m.v.reader(decPtr(b).ROM()))
+	})
+}
+
+// Returns the size of the encoded description of the move, including the
+// string terminator.
+func (m Move) descriptionSize() int {
 	b := readStruct(
-		m.v.ROM,
+		m.v.table(tableMoveDescPtr),
 		m.v.AddrMoveDescPtr,
 		m.i-1,
 		structPtr,
 	)
-	m.v.ROM.Seek(decPtr(b).ROM(), 0)
-	return readTextString(m.v.ROM)
+	p := decPtr(b)
+	if !p.ValidROM() {
+		return 0
+	}
+	return textSize(m.v.reader(p.ROM()))
+}
+
+// SetDescription sets the description of the move. If the description is
+// longer than the current description, or the current description is shared
+// with other moves, then the description is written to free space.
+// Returns an error for move 0, which has no description.
+func (m Move) SetDescription(desc string) error {
+	if m.i == 0 {
+		return errors.New("move 0 has no description")
+	}
+	return m.v.writePointed(
+		m.v.AddrMoveDescPtr,
+		m.i-1,
+		append(encodeText(desc), strTerm),
+		m.descriptionSize(),
+	)
 }
 
 func (m Move) Type() pkm.Type {
 	b := readStruct(
-		m.v.ROM,
+		m.v.table(tableMoveData),
 		m.v.AddrMoveData,
 		m.i,
 		structMoveData,
@@ -69,7 +119,7 @@ func (m Move) Type() pkm.Type {
 
 func (m Move) BasePower() byte {
 	b := readStruct(
-		m.v.ROM,
+		m.v.table(tableMoveData),
 		m.v.AddrMoveData,
 		m.i,
 		structMoveData,
@@ -80,7 +130,7 @@ func (m Move) BasePower() byte {
 
 func (m Move) Accuracy() byte {
 	b := readStruct(
-		m.v.ROM,
+		m.v.table(tableMoveData),
 		m.v.AddrMoveData,
 		m.i,
 		structMoveData,
@@ -91,7 +141,7 @@ func (m Move) Accuracy() byte {
 
 func (m Move) PowerPoints() byte {
 	b := readStruct(
-		m.v.ROM,
+		m.v.table(tableMoveData),
 		m.v.AddrMoveData,
 		m.i,
 		structMoveData,
@@ -102,7 +152,7 @@ func (m Move) PowerPoints() byte {
 
 func (m Move) Effect() pkm.Effect {
 	b := readStruct(
-		m.v.ROM,
+		m.v.table(tableMoveData),
 		m.v.AddrMoveData,
 		m.i,
 		structMoveData,
@@ -113,7 +163,7 @@ func (m Move) Effect() pkm.Effect {
 
 func (m Move) EffectAccuracy() byte {
 	b := readStruct(
-		m.v.ROM,
+		m.v.table(tableMoveData),
 		m.v.AddrMoveData,
 		m.i,
 		structMoveData,
@@ -124,7 +174,7 @@ func (m Move) EffectAccuracy() byte {
 
 func (m Move) Affectee() pkm.Affectee {
 	b := readStruct(
-		m.v.ROM,
+		m.v.table(tableMoveData),
 		m.v.AddrMoveData,
 		m.i,
 		structMoveData,
@@ -135,7 +185,7 @@ func (m Move) Affectee() pkm.Affectee {
 
 func (m Move) Priority() int8 {
 	b := readStruct(
-		m.v.ROM,
+		m.v.table(tableMoveData),
 		m.v.AddrMoveData,
 		m.i,
 		structMoveData,
@@ -146,7 +196,7 @@ func (m Move) Priority() int8 {
 
 func (m Move) Flags() pkm.MoveFlags {
 	b := readStruct(
-		m.v.ROM,
+		m.v.table(tableMoveData),
 		m.v.AddrMoveData,
 		m.i,
 		structMoveData,
@@ -155,6 +205,58 @@ func (m Move) Flags() pkm.MoveFlags {
 	return pkm.MoveFlags(b[0])
 }
 
+// Writes a field of the move data.
+func (m Move) setData(b []byte, field int) error {
+	w, err := m.v.writer()
+	if err != nil {
+		return err
+	}
+	return writeStruct(
+		w,
+		m.v.AddrMoveData,
+		m.i,
+		structMoveData,
+		b,
+		field,
+	)
+}
+
+func (m Move) SetType(t pkm.Type) error {
+	return m.setData([]byte{byte(t)}, 2)
+}
+
+func (m Move) SetBasePower(power byte) error {
+	return m.setData([]byte{power}, 1)
+}
+
+func (m Move) SetAccuracy(accuracy byte) error {
+	return m.setData([]byte{accuracy}, 3)
+}
+
+func (m Move) SetPowerPoints(pp byte) error {
+	return m.setData([]byte{pp}, 4)
+}
+
+func (m Move) SetEffect(effect pkm.Effect) error {
+	return m.setData([]byte{byte(effect)}, 0)
+}
+
+func (m Move) SetEffectAccuracy(accuracy byte) error {
+	return m.setData([]byte{accuracy}, 5)
+}
+
+func (m Move) SetAffectee(affectee pkm.Affectee) error {
+	return m.setData([]byte{byte(affectee)}, 6)
+}
+
+func (m Move) SetPriority(priority int8) error {
+	return m.setData([]byte{byte(priority)}, 7)
+}
+
+func (m Move) SetFlags(flags pkm.MoveFlags) error {
+	return m.setData([]byte{byte(flags)}, 8)
+}
+
 type TM struct {
 	v *Version
 	i int
@@ -190,3 +292,18 @@ func (tm TM) Move() pkm.Move {
 	)
 	return Move{v: tm.v, i: int(decUint16(b))}
 }
+
+// SetMove sets the move taught by the TM.
+func (tm TM) SetMove(move pkm.Move) error {
+	w, err := tm.v.writer()
+	if err != nil {
+		return err
+	}
+	return writeStruct(
+		w,
+		tm.v.AddrTMMove,
+		tm.i,
+		structTMMove,
+		encUint16(uint16(move.Index())),
+	)
+}
@@ -1,20 +1,22 @@
 package gen3_test
 
 import (
+	"encoding/binary"
 	"github.com/anaminus/pkm"
 	"github.com/anaminus/pkm/gen3"
+	"github.com/anaminus/pkm/gen3/romtest"
 	"testing"
 )
 
 func TestMove(t *testing.T) {
-	ver := gen3.OpenROM(ROM(t))
-	if ver == nil {
-		t.Fatalf("failed to open ROM")
+	ver, err := gen3.OpenROM(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
 	}
 
 	move := ver.MoveByIndex(1)
 	if v := move.Index(); v != 1 {
-		t.Errorf("Index: unexpected result \"%s\"", v)
+		t.Errorf("Index: unexpected result %d", v)
 	}
 	if v := move.Name(); v != "POUND" {
 		t.Errorf("Name: unexpected result \"%s\"", v)
@@ -44,14 +46,56 @@ func TestMove(t *testing.T) {
 		t.Errorf("Priority: unexpected result %d", v)
 	}
 	if v := move.Flags(); v != pkm.Contact|pkm.Protect|pkm.MirrorMove|pkm.KingsRock {
-		t.Errorf("Flags: unexpected result %s", v)
+		t.Errorf("Flags: unexpected result %d", v)
+	}
+}
+
+func TestMoveSynthetic(t *testing.T) {
+	ver, err := gen3.OpenROM(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	move := ver.MoveByIndex(1)
+	if v := move.Index(); v != 1 {
+		t.Errorf("Index: unexpected result %d", v)
+	}
+	if v := move.Name(); v != romtest.MoveName(1) {
+		t.Errorf("Name: unexpected result \"%s\"", v)
+	}
+	if v := move.Description(); v != romtest.MoveDescription(1) {
+		t.Errorf("Description: unexpected result \"%s\"", v)
+	}
+	if v := move.Type(); v != romtest.MoveType(1) {
+		t.Errorf("Type: unexpected result \"%s\"", v)
+	}
+	if v := move.BasePower(); int(v) != romtest.MovePower(1) {
+		t.Errorf("BasePower: unexpected result %d", v)
+	}
+	if v := move.Accuracy(); v != 100 {
+		t.Errorf("Accuracy: unexpected result %d", v)
+	}
+	if v := move.Effect(); v != 0 {
+		t.Errorf("Effect: unexpected result %d", v)
+	}
+	if v := move.EffectAccuracy(); v != 0 {
+		t.Errorf("EffectAccuracy: unexpected result %d", v)
+	}
+	if v := move.Affectee(); v != 0 {
+		t.Errorf("Affectee: unexpected result %d", v)
+	}
+	if v := move.Priority(); v != 0 {
+		t.Errorf("Priority: unexpected result %d", v)
+	}
+	if v := move.Flags(); v != 0 {
+		t.Errorf("Flags: unexpected result %d", v)
 	}
 }
 
 func TestTM(t *testing.T) {
-	ver := gen3.OpenROM(ROM(t))
-	if ver == nil {
-		t.Fatalf("failed to open ROM")
+	ver, err := gen3.OpenROM(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
 	}
 
 	tm := ver.TMByIndex(0)
@@ -87,3 +131,243 @@ func TestTM(t *testing.T) {
 		t.Errorf("Move: unexpected result %d", v.Index())
 	}
 }
+
+func TestTMSynthetic(t *testing.T) {
+	ver, err := gen3.OpenROM(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	tm := ver.TMByIndex(0)
+	if v := tm.Index(); v != 0 {
+		t.Errorf("Index: unexpected result %d", v)
+	}
+	if v := tm.Name(); v != "TM01" {
+		t.Errorf("Name: unexpected result \"%s\"", v)
+	}
+	if v := tm.Move(); v.Index() != romtest.TMMove(0) {
+		t.Errorf("Move: unexpected result %d", v.Index())
+	}
+
+	tm = ver.TMByIndex(35)
+	if v := tm.Index(); v != 35 {
+		t.Errorf("Index: unexpected result %d", v)
+	}
+	if v := tm.Name(); v != "TM36" {
+		t.Errorf("Name: unexpected result \"%s\"", v)
+	}
+	if v := tm.Move(); v.Index() != romtest.TMMove(35) {
+		t.Errorf("Move: unexpected result %d", v.Index())
+	}
+
+	tm = ver.TMByIndex(50)
+	if v := tm.Index(); v != 50 {
+		t.Errorf("Index: unexpected result %d", v)
+	}
+	if v := tm.Name(); v != "HM01" {
+		t.Errorf("Name: unexpected result \"%s\"", v)
+	}
+	if v := tm.Move(); v.Index() != romtest.TMMove(50) {
+		t.Errorf("Move: unexpected result %d", v.Index())
+	}
+}
+
+func TestMoveWrite(t *testing.T) {
+	buf, err := gen3.ReadBuffer(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to read ROM: %s", err)
+	}
+	ver, err := gen3.OpenROM(buf)
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	move := ver.MoveByIndex(1).(gen3.Move)
+	check := func(name string, err error) {
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+		}
+	}
+	check("SetType", move.SetType(pkm.TypeFire))
+	check("SetBasePower", move.SetBasePower(90))
+	check("SetAccuracy", move.SetAccuracy(85))
+	check("SetPowerPoints", move.SetPowerPoints(15))
+	check("SetEffect", move.SetEffect(4))
+	check("SetEffectAccuracy", move.SetEffectAccuracy(10))
+	check("SetAffectee", move.SetAffectee(0x08))
+	check("SetPriority", move.SetPriority(-1))
+	check("SetFlags", move.SetFlags(pkm.Protect|pkm.KingsRock))
+
+	if v := move.Type(); v != pkm.TypeFire {
+		t.Errorf("Type: unexpected result %s", v)
+	}
+	if v := move.BasePower(); v != 90 {
+		t.Errorf("BasePower: unexpected result %d", v)
+	}
+	if v := move.Accuracy(); v != 85 {
+		t.Errorf("Accuracy: unexpected result %d", v)
+	}
+	if v := move.PowerPoints(); v != 15 {
+		t.Errorf("PowerPoints: unexpected result %d", v)
+	}
+	if v := move.Effect(); v != 4 {
+		t.Errorf("Effect: unexpected result %d", v)
+	}
+	if v := move.EffectAccuracy(); v != 10 {
+		t.Errorf("EffectAccuracy: unexpected result %d", v)
+	}
+	if v := move.Affectee(); v != 0x08 {
+		t.Errorf("Affectee: unexpected result %d", v)
+	}
+	if v := move.Priority(); v != -1 {
+		t.Errorf("Priority: unexpected result %d", v)
+	}
+	if v := move.Flags(); v != pkm.Protect|pkm.KingsRock {
+		t.Errorf("Flags: unexpected result %d", v)
+	}
+	if v := ver.MoveByIndex(2).BasePower(); v == 90 {
+		t.Errorf("BasePower: neighboring move was modified")
+	}
+
+	tm := ver.TMByIndex(0).(gen3.TM)
+	check("SetMove", tm.SetMove(move))
+	if v := tm.Move(); v != pkm.Move(move) {
+		t.Errorf("TM.Move: unexpected result %d", v.Index())
+	}
+	if v := ver.TMByIndex(1).Move(); v == pkm.Move(move) {
+		t.Errorf("TM.Move: neighboring TM was modified")
+	}
+
+	species := ver.SpeciesByIndex(1).(gen3.Species)
+	check("SetCanLearnTM", species.SetCanLearnTM(tm, true))
+	check("SetCanLearnTM", species.SetCanLearnTM(ver.TMByIndex(5), false))
+	if !species.CanLearnTM(tm) {
+		t.Errorf("CanLearnTM: expected learnable TM01")
+	}
+	if species.CanLearnTM(ver.TMByIndex(5)) {
+		t.Errorf("CanLearnTM: expected unlearnable TM06")
+	}
+	if !species.CanLearnTM(ver.TMByIndex(8)) {
+		t.Errorf("CanLearnTM: expected TM09 to be unchanged")
+	}
+
+	tms := []pkm.TM{ver.TMByIndex(2), ver.TMByIndex(57)}
+	check("SetLearnableTMs", species.SetLearnableTMs(tms))
+	if v := species.LearnableTMs(); len(v) != 2 || v[0] != tms[0] || v[1] != tms[1] {
+		t.Errorf("LearnableTMs: unexpected result %v", v)
+	}
+}
+
+func TestMoveWriteSynthetic(t *testing.T) {
+	buf, err := gen3.ReadBuffer(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to read ROM: %s", err)
+	}
+	ver, err := gen3.OpenROM(buf)
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	move := ver.MoveByIndex(1).(gen3.Move)
+	check := func(name string, err error) {
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+		}
+	}
+	check("SetType", move.SetType(pkm.TypeFire))
+	check("SetBasePower", move.SetBasePower(90))
+	check("SetAccuracy", move.SetAccuracy(85))
+	check("SetPowerPoints", move.SetPowerPoints(15))
+	check("SetEffect", move.SetEffect(4))
+	check("SetEffectAccuracy", move.SetEffectAccuracy(10))
+	check("SetAffectee", move.SetAffectee(0x08))
+	check("SetPriority", move.SetPriority(-1))
+	check("SetFlags", move.SetFlags(pkm.Protect|pkm.KingsRock))
+
+	if v := move.Type(); v != pkm.TypeFire {
+		t.Errorf("Type: unexpected result %s", v)
+	}
+	if v := move.BasePower(); v != 90 {
+		t.Errorf("BasePower: unexpected result %d", v)
+	}
+	if v := move.Accuracy(); v != 85 {
+		t.Errorf("Accuracy: unexpected result %d", v)
+	}
+	if v := move.PowerPoints(); v != 15 {
+		t.Errorf("PowerPoints: unexpected result %d", v)
+	}
+	if v := move.Effect(); v != 4 {
+		t.Errorf("Effect: unexpected result %d", v)
+	}
+	if v := move.EffectAccuracy(); v != 10 {
+		t.Errorf("EffectAccuracy: unexpected result %d", v)
+	}
+	if v := move.Affectee(); v != 0x08 {
+		t.Errorf("Affectee: unexpected result %d", v)
+	}
+	if v := move.Priority(); v != -1 {
+		t.Errorf("Priority: unexpected result %d", v)
+	}
+	if v := move.Flags(); v != pkm.Protect|pkm.KingsRock {
+		t.Errorf("Flags: unexpected result %d", v)
+	}
+	if v := ver.MoveByIndex(2).BasePower(); v == 90 {
+		t.Errorf("BasePower: neighboring move was modified")
+	}
+
+	tm := ver.TMByIndex(0).(gen3.TM)
+	check("SetMove", tm.SetMove(move))
+	if v := tm.Move(); v != pkm.Move(move) {
+		t.Errorf("TM.Move: unexpected result %d", v.Index())
+	}
+	if v := ver.TMByIndex(1).Move(); v == pkm.Move(move) {
+		t.Errorf("TM.Move: neighboring TM was modified")
+	}
+
+	species := ver.SpeciesByIndex(1).(gen3.Species)
+	check("SetCanLearnTM", species.SetCanLearnTM(tm, true))
+	check("SetCanLearnTM", species.SetCanLearnTM(ver.TMByIndex(romtest.SpeciesTM(1)), false))
+	if !species.CanLearnTM(tm) {
+		t.Errorf("CanLearnTM: expected learnable TM01")
+	}
+	if species.CanLearnTM(ver.TMByIndex(romtest.SpeciesTM(1))) {
+		t.Errorf("CanLearnTM: expected unlearnable TM02")
+	}
+	if !ver.SpeciesByIndex(2).CanLearnTM(ver.TMByIndex(romtest.SpeciesTM(2))) {
+		t.Errorf("CanLearnTM: neighboring species was modified")
+	}
+
+	tms := []pkm.TM{ver.TMByIndex(2), ver.TMByIndex(57)}
+	check("SetLearnableTMs", species.SetLearnableTMs(tms))
+	if v := species.LearnableTMs(); len(v) != 2 || v[0] != tms[0] || v[1] != tms[1] {
+		t.Errorf("LearnableTMs: unexpected result %v", v)
+	}
+}
+
+func TestSetDescriptionSize(t *testing.T) {
+	b := romtest.Build()
+	ver := openVersion(t, gen3.NewBuffer(b))
+	p := int(ver.AddrMoveDescPtr) - 0x08000000
+	off := int(binary.LittleEndian.Uint32(b[p:])) - 0x08000000
+
+	// The old description, including control codes, is measured by its
+	// encoded bytes.
+	raw := []byte{0xFC, 0x01, 0x02, 0xFC, 0x0C, 0x05, 0xFA, 0xFB, 0xF9, 0x01, 0xF7, 0xFF}
+	copy(b[off:], raw)
+	if err := ver.MoveByIndex(1).(gen3.Move).SetDescription("A"); err != nil {
+		t.Fatalf("SetDescription: unexpected error: %s", err)
+	}
+	if v := ver.MoveByIndex(1).Description(); v != "A" {
+		t.Errorf("SetDescription: unexpected result %q", v)
+	}
+	// The remains of the old description are freed.
+	for i := off + 2; i < off+len(raw); i++ {
+		if b[i] != 0xFF {
+			t.Errorf("SetDescription: byte %d of old description was not freed", i-off)
+		}
+	}
+
+	if err := ver.MoveByIndex(0).(gen3.Move).SetDescription("A"); err == nil {
+		t.Errorf("SetDescription: expected error for move 0")
+	}
+}
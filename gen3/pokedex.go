@@ -2,6 +2,7 @@ package gen3
 
 import (
 	"github.com/anaminus/pkm"
+	"sync"
 )
 
 var (
@@ -10,17 +11,66 @@ var (
 	)
 )
 
+// Describes a pokedex of a version.
 type pokedexData struct {
-	Name    string
-	Size    int
+	Name string
+	// Alternative name accepted by PokedexByName. The regional pokedex of
+	// each version was previously named "Standard".
+	Alias string
+	// Number of species in the pokedex. Species with a number greater than
+	// Size are not in the pokedex.
+	Size int
+	// Table of pokedex numbers, indexed by species, starting at species 1.
 	Address ptr
 }
 
+// Maps species to pokedex numbers and back. Built from the table of a
+// pokedex the first time the pokedex is used.
+type dexMap struct {
+	once sync.Once
+	// Pokedex number of each species index, or 0.
+	numbers []int
+	// Species index of each pokedex number, or 0.
+	species []int
+}
+
+// Returns the dexMaps of a version, one for each pokedex.
+func newDexMaps(n int) []*dexMap {
+	a := make([]*dexMap, n)
+	for i := range a {
+		a[i] = &dexMap{}
+	}
+	return a
+}
+
 type Pokedex struct {
 	v *Version
 	i int
 }
 
+// Returns the map of the pokedex, building it if necessary.
+func (p Pokedex) dexMap() *dexMap {
+	m := p.v.dexMaps[p.i]
+	m.once.Do(func() {
+		d := p.v.pokedex[p.i]
+		b := make([]byte, structDex.Size()*(indexSizeSpecies-1))
+		p.v.ROM.ReadAt(b, d.Address.ROM())
+		m.numbers = make([]int, indexSizeSpecies)
+		m.species = make([]int, d.Size+1)
+		for i := 1; i < indexSizeSpecies; i++ {
+			n := int(decUint16(b[(i-1)*structDex.Size():]))
+			if n <= 0 || n > d.Size {
+				continue
+			}
+			m.numbers[i] = n
+			if m.species[n] == 0 {
+				m.species[n] = i
+			}
+		}
+	})
+	return m
+}
+
 func (p Pokedex) Name() string {
 	return p.v.pokedex[p.i].Name
 }
@@ -33,36 +83,39 @@ func (p Pokedex) Species(number int) pkm.Species {
 	if number <= 0 || number > p.Size() {
 		panic("species number out of bounds")
 	}
-	p.v.ROM.Seek(p.v.pokedex[p.i].Address.ROM(), 0)
-	var species pkm.Species
-	for i, q := 1, make([]byte, 2); i <= indexSizeSpecies; i++ {
-		p.v.ROM.Read(q)
-		if int(decUint16(q)) == number {
-			species = Species{v: p.v, i: i}
-			break
-		}
+	if i := p.dexMap().species[number]; i != 0 {
+		return Species{v: p.v, i: i}
 	}
-	return species
+	return nil
 }
 
 func (p Pokedex) AllSpecies() []pkm.Species {
-	a := make([]pkm.Species, p.Size())
-	p.v.ROM.Seek(p.v.pokedex[p.i].Address.ROM(), 0)
-	for i, q := 1, make([]byte, 2); i < indexSizeSpecies; i++ {
-		p.v.ROM.Read(q)
-		if n := int(decUint16(q)); n <= p.Size() {
-			a[n-1] = Species{v: p.v, i: i}
+	return p.Range(1, p.Size())
+}
+
+func (p Pokedex) Range(from, to int) []pkm.Species {
+	if from <= 0 || to > p.Size() || from > to+1 {
+		panic("species number out of bounds")
+	}
+	m := p.dexMap()
+	a := make([]pkm.Species, to-from+1)
+	for n := from; n <= to; n++ {
+		if i := m.species[n]; i != 0 {
+			a[n-from] = Species{v: p.v, i: i}
 		}
 	}
 	return a
 }
 
 func (p Pokedex) SpeciesNumber(species pkm.Species) int {
-	b := readStruct(
-		p.v.ROM,
-		p.v.pokedex[p.i].Address,
-		species.Index()-1,
-		structDex,
-	)
-	return int(decUint16(b))
+	i := species.Index()
+	if i <= 0 || i >= indexSizeSpecies {
+		return 0
+	}
+	return p.dexMap().numbers[i]
+}
+
+// Returns the national pokedex number of a species.
+func (v *Version) speciesNumber(s Species) int {
+	return Pokedex{v: v, i: 0}.SpeciesNumber(s)
 }
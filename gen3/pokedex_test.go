@@ -6,9 +6,9 @@ import (
 )
 
 func TestPokedex(t *testing.T) {
-	ver := gen3.OpenROM(ROM(t))
-	if ver == nil {
-		t.Fatalf("failed to open ROM")
+	ver, err := gen3.OpenROM(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
 	}
 
 	dex := ver.Pokedex()[0]
@@ -16,7 +16,7 @@ func TestPokedex(t *testing.T) {
 		t.Errorf("Name: unexpected result \"%s\"", v)
 	}
 	if v := dex.Size(); v != 386 {
-		t.Errorf("Size: unexpected result \"%s\"", v)
+		t.Errorf("Size: unexpected result %d", v)
 	}
 
 	type sp struct {
@@ -52,3 +52,51 @@ func TestPokedex(t *testing.T) {
 	}
 
 }
+
+func TestPokedexSynthetic(t *testing.T) {
+	ver, err := gen3.OpenROM(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	dex := ver.Pokedex()[0]
+	if v := dex.Name(); v != "National" {
+		t.Errorf("Name: unexpected result \"%s\"", v)
+	}
+	if v := dex.Size(); v != 386 {
+		t.Errorf("Size: unexpected result %d", v)
+	}
+
+	type sp struct {
+		index int
+		num   int
+	}
+	for _, s := range []sp{{1, 1}, {151, 151}, {277, 252}, {411, 386}} {
+		species := ver.SpeciesByIndex(s.index)
+		if v := dex.Species(s.num); v != species {
+			if v == nil {
+				t.Errorf("Species: %s: unexpected result <nil>", species.Name())
+			} else {
+				t.Errorf("Species: %s: unexpected result %d (%s)", species.Name(), v.Index(), v.Name())
+			}
+		}
+		if v := dex.SpeciesNumber(species); v != s.num {
+			t.Errorf("SpeciesNumber: %s: unexpected result %d", species.Name(), v)
+		}
+	}
+
+	ExpectPanic(t, "Species", func() {
+		dex.Species(-1)
+	})
+	ExpectPanic(t, "Species", func() {
+		dex.Species(0)
+	})
+	ExpectPanic(t, "Species", func() {
+		dex.Species(dex.Size() + 1)
+	})
+
+	if v := dex.AllSpecies(); len(v) != dex.Size() {
+		t.Errorf("AllSpecies: unexpected length of result %d", len(v))
+	}
+
+}
@@ -0,0 +1,156 @@
+package rng
+
+import (
+	"github.com/anaminus/pkm"
+)
+
+// Method indicates the order in which the game draws random values when
+// generating a pokemon.
+type Method int
+
+const (
+	// The PID is followed immediately by both IV values. Used by most
+	// stationary and wild encounters.
+	Method1 Method = 1
+	// One frame is skipped between the PID and the IVs.
+	Method2 Method = 2
+	// One frame is skipped between the first and second IV values.
+	Method4 Method = 4
+)
+
+func (m Method) String() string {
+	switch m {
+	case Method1:
+		return "Method 1"
+	case Method2:
+		return "Method 2"
+	case Method4:
+		return "Method 4"
+	}
+	return "Unknown"
+}
+
+// Returns the number of frames after the seed at which each IV value is
+// drawn.
+func (m Method) ivFrames() (iv1, iv2 int64) {
+	switch m {
+	case Method2:
+		return 4, 5
+	case Method4:
+		return 3, 5
+	}
+	return 3, 4
+}
+
+// Spread is the result of generating a pokemon from a seed.
+type Spread struct {
+	// The seed from which the pokemon was generated.
+	Seed uint32
+	// The method by which the pokemon was generated.
+	Method Method
+	// The personality value of the pokemon.
+	PID pkm.PID
+	// The individual values of the pokemon, each being within 0-31.
+	IVs pkm.Stats
+}
+
+// Nature returns the nature of the pokemon.
+func (s Spread) Nature() pkm.Nature {
+	return s.PID.Nature()
+}
+
+// Shiny returns whether the pokemon is shiny for a trainer with the given
+// trainer ID and secret ID.
+func (s Spread) Shiny(tid, sid uint16) bool {
+	return s.PID.Shiny(tid, sid)
+}
+
+// Generate generates a pokemon from a seed using a given method. The seed is
+// the state of the generator before the first value is drawn.
+func Generate(seed uint32, method Method) Spread {
+	r := New(seed)
+	s := Spread{Seed: seed, Method: method}
+	s.PID = pkm.PID(r.Next32())
+	f1, f2 := method.ivFrames()
+	r.Jump(f1 - 3)
+	iv1 := r.Next()
+	r.Jump(f2 - f1 - 1)
+	iv2 := r.Next()
+	s.IVs = DecodeIVs(iv1, iv2)
+	return s
+}
+
+// DecodeIVs decodes the two random values from which IVs are generated.
+func DecodeIVs(iv1, iv2 uint16) pkm.Stats {
+	return pkm.Stats{
+		HitPoints: byte(iv1 & 31),
+		Attack:    byte(iv1 >> 5 & 31),
+		Defense:   byte(iv1 >> 10 & 31),
+		Speed:     byte(iv2 & 31),
+		SpAttack:  byte(iv2 >> 5 & 31),
+		SpDefense: byte(iv2 >> 10 & 31),
+	}
+}
+
+// EncodeIVs encodes IVs into the lower 15 bits of the two random values from
+// which they are generated.
+func EncodeIVs(ivs pkm.Stats) (iv1, iv2 uint16) {
+	iv1 = uint16(ivs.HitPoints&31) | uint16(ivs.Attack&31)<<5 | uint16(ivs.Defense&31)<<10
+	iv2 = uint16(ivs.Speed&31) | uint16(ivs.SpAttack&31)<<5 | uint16(ivs.SpDefense&31)<<10
+	return
+}
+
+// SeedsFromPID returns every seed from which a given PID can be generated.
+// The PID is drawn in the same way by every method.
+func SeedsFromPID(pid pkm.PID) []uint32 {
+	var seeds []uint32
+	hi := uint32(pid & 0xFFFF)
+	for lo := uint32(0); lo <= 0xFFFF; lo++ {
+		s := hi<<16 | lo
+		if uint16(Next(s)>>16) == uint16(pid>>16) {
+			seeds = append(seeds, Prev(s))
+		}
+	}
+	return seeds
+}
+
+// SeedsFromIVs returns every seed from which the given IVs can be generated
+// with a given method.
+func SeedsFromIVs(ivs pkm.Stats, method Method) []uint32 {
+	var seeds []uint32
+	iv1, iv2 := EncodeIVs(ivs)
+	f1, f2 := method.ivFrames()
+	// The top bit of each value is not used by the IVs.
+	for top := uint32(0); top <= 1; top++ {
+		hi := uint32(iv1) | top<<15
+		for lo := uint32(0); lo <= 0xFFFF; lo++ {
+			s := hi<<16 | lo
+			if uint16(Jump(s, f2-f1)>>16)&0x7FFF == iv2 {
+				seeds = append(seeds, Jump(s, -f1))
+			}
+		}
+	}
+	return seeds
+}
+
+// SpreadsFromPID returns every spread with a given PID that can be generated
+// with a given method.
+func SpreadsFromPID(pid pkm.PID, method Method) []Spread {
+	seeds := SeedsFromPID(pid)
+	spreads := make([]Spread, len(seeds))
+	for i, seed := range seeds {
+		spreads[i] = Generate(seed, method)
+	}
+	return spreads
+}
+
+// SpreadsFromIVs returns every spread with the given IVs that can be
+// generated with a given method.
+func SpreadsFromIVs(ivs pkm.Stats, method Method) []Spread {
+	seeds := SeedsFromIVs(ivs, method)
+	spreads := make([]Spread, len(seeds))
+	for i, seed := range seeds {
+		spreads[i] = Generate(seed, method)
+	}
+	return spreads
+}
@@ -0,0 +1,103 @@
+// Package rng implements the pseudo-random number generator of generation
+// III games, as well as the methods by which the games use it to generate
+// the personality values and individual values of pokemon.
+package rng
+
+const (
+	mult = 0x41C64E6D
+	add  = 0x00006073
+
+	// Constants of the inverse function.
+	rmult = 0xEEB9EB65
+	radd  = 0x0A3561A1
+)
+
+// Next returns the seed that follows a given seed.
+func Next(seed uint32) uint32 {
+	return seed*mult + add
+}
+
+// Prev returns the seed that precedes a given seed.
+func Prev(seed uint32) uint32 {
+	return seed*rmult + radd
+}
+
+// Jump returns the seed that is n frames away from a given seed. A negative n
+// jumps backwards.
+func Jump(seed uint32, n int64) uint32 {
+	var m, a uint32 = mult, add
+	if n < 0 {
+		m, a = rmult, radd
+		n = -n
+	}
+	// Apply the function in steps of powers of two, each step being the
+	// previous step composed with itself.
+	for ; n > 0; n >>= 1 {
+		if n&1 != 0 {
+			seed = seed*m + a
+		}
+		a *= m + 1
+		m *= m
+	}
+	return seed
+}
+
+// Distance returns the number of frames required to advance from one seed to
+// another.
+func Distance(from, to uint32) uint32 {
+	// Each power-of-two step is tested against the lowest bit that differs.
+	var m, a uint32 = mult, add
+	var n uint32
+	for bit := uint32(1); from != to && bit != 0; bit <<= 1 {
+		if (from^to)&bit != 0 {
+			from = from*m + a
+			n |= bit
+		}
+		a *= m + 1
+		m *= m
+	}
+	return n
+}
+
+// LCG is the linear congruential generator used by generation III games.
+// Each call to Next advances the state by one frame.
+type LCG struct {
+	Seed  uint32
+	Frame int64
+}
+
+// New returns an LCG starting at a given seed.
+func New(seed uint32) *LCG {
+	return &LCG{Seed: seed}
+}
+
+// Next advances the generator by one frame, returning the upper 16 bits of
+// the new seed, as the game's Random function does.
+func (r *LCG) Next() uint16 {
+	r.Seed = Next(r.Seed)
+	r.Frame++
+	return uint16(r.Seed >> 16)
+}
+
+// Next32 advances the generator by two frames, returning a 32-bit value whose
+// lower half is the first result, as the game's Random32 function does.
+func (r *LCG) Next32() uint32 {
+	lo := uint32(r.Next())
+	return lo | uint32(r.Next())<<16
+}
+
+// Prev reverses the generator by one frame, returning the upper 16 bits of
+// the seed that was current before the reversal.
+func (r *LCG) Prev() uint16 {
+	v := uint16(r.Seed >> 16)
+	r.Seed = Prev(r.Seed)
+	r.Frame--
+	return v
+}
+
+// Jump advances the generator by n frames. A negative n reverses the
+// generator.
+func (r *LCG) Jump(n int64) {
+	r.Seed = Jump(r.Seed, n)
+	r.Frame += n
+}
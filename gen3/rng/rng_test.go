@@ -0,0 +1,120 @@
+package rng_test
+
+import (
+	"github.com/anaminus/pkm"
+	"github.com/anaminus/pkm/gen3/rng"
+	"testing"
+)
+
+func TestLCG(t *testing.T) {
+	if v := rng.Next(0); v != 0x6073 {
+		t.Errorf("Next: unexpected result %08X", v)
+	}
+	for _, seed := range []uint32{0, 1, 0x6073, 0xDEADBEEF, 0xFFFFFFFF} {
+		if v := rng.Prev(rng.Next(seed)); v != seed {
+			t.Errorf("Prev: %08X: unexpected result %08X", seed, v)
+		}
+		s := seed
+		for n := int64(0); n <= 1000; n++ {
+			if v := rng.Jump(seed, n); v != s {
+				t.Errorf("Jump: %08X: %d: unexpected result %08X", seed, n, v)
+				break
+			}
+			if v := rng.Jump(s, -n); v != seed {
+				t.Errorf("Jump: %08X: %d: unexpected reverse result %08X", s, -n, v)
+				break
+			}
+			if v := rng.Distance(seed, s); int64(v) != n {
+				t.Errorf("Distance: %08X: unexpected result %d, expected %d", seed, v, n)
+				break
+			}
+			s = rng.Next(s)
+		}
+	}
+
+	r := rng.New(0)
+	if v := r.Next(); v != 0 {
+		t.Errorf("LCG.Next: unexpected result %04X", v)
+	}
+	r.Jump(-1)
+	if r.Seed != 0 || r.Frame != 0 {
+		t.Errorf("LCG.Jump: unexpected state %08X (%d)", r.Seed, r.Frame)
+	}
+	v := r.Next32()
+	if w := r.Prev(); uint32(w) != v>>16 {
+		t.Errorf("LCG.Prev: unexpected result %04X", w)
+	}
+	if w := r.Prev(); uint32(w) != v&0xFFFF {
+		t.Errorf("LCG.Prev: unexpected result %04X", w)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	seed := uint32(0x12345678)
+	for _, method := range []rng.Method{rng.Method1, rng.Method2, rng.Method4} {
+		s := rng.Generate(seed, method)
+
+		r := rng.New(seed)
+		pid := pkm.PID(uint32(r.Next()) | uint32(r.Next())<<16)
+		if s.PID != pid {
+			t.Errorf("Generate: %s: unexpected PID %08X", method, s.PID)
+		}
+		for _, v := range []byte{
+			s.IVs.HitPoints, s.IVs.Attack, s.IVs.Defense,
+			s.IVs.Speed, s.IVs.SpAttack, s.IVs.SpDefense,
+		} {
+			if v > 31 {
+				t.Errorf("Generate: %s: IV out of range: %d", method, v)
+			}
+		}
+
+		found := false
+		for _, seed := range rng.SeedsFromPID(s.PID) {
+			if seed == s.Seed {
+				found = true
+			}
+			if v := rng.Generate(seed, method); v.PID != s.PID {
+				t.Errorf("SeedsFromPID: %s: seed %08X generates PID %08X", method, seed, v.PID)
+			}
+		}
+		if !found {
+			t.Errorf("SeedsFromPID: %s: original seed not found", method)
+		}
+
+		found = false
+		for _, sp := range rng.SpreadsFromIVs(s.IVs, method) {
+			if sp.Seed == s.Seed {
+				found = true
+			}
+			if sp.IVs != s.IVs {
+				t.Errorf("SpreadsFromIVs: %s: seed %08X generates IVs %v", method, sp.Seed, sp.IVs)
+			}
+		}
+		if !found {
+			t.Errorf("SeedsFromIVs: %s: original seed not found", method)
+		}
+	}
+
+	ivs := pkm.Stats{HitPoints: 1, Attack: 2, Defense: 3, Speed: 4, SpAttack: 5, SpDefense: 6}
+	iv1, iv2 := rng.EncodeIVs(ivs)
+	if v := rng.DecodeIVs(iv1, iv2); v != ivs {
+		t.Errorf("DecodeIVs: unexpected result %v", v)
+	}
+}
+
+func TestPID(t *testing.T) {
+	pid := pkm.PID(0x12345678)
+	if v := pid.Nature(); v != pkm.Nature(0x12345678%25) {
+		t.Errorf("Nature: unexpected result %s", v)
+	}
+	tid, sid := uint16(0x1234), uint16(0x5678)
+	if !pid.Shiny(tid, sid) {
+		t.Errorf("Shiny: expected shiny")
+	}
+	if pid.Shiny(tid, sid^8) {
+		t.Errorf("Shiny: expected not shiny")
+	}
+	if v := (rng.Spread{PID: pid}).Shiny(tid, sid); !v {
+		t.Errorf("Spread.Shiny: expected shiny")
+	}
+}
@@ -0,0 +1,224 @@
+package rng
+
+import (
+	"github.com/anaminus/pkm"
+)
+
+// Ability indices that affect wild encounters.
+const (
+	abilityStatic      = 9
+	abilitySynchronize = 28
+	abilityMagnetPull  = 42
+	abilityPressure    = 46
+	abilityHustle      = 55
+	abilityCuteCharm   = 56
+	abilityVitalSpirit = 72
+)
+
+// Area indicates the kind of area in which a wild encounter occurs.
+type Area int
+
+const (
+	AreaGrass Area = iota
+	AreaWater
+	AreaRock
+	AreaOldRod
+	AreaGoodRod
+	AreaSuperRod
+)
+
+func (a Area) String() string {
+	switch a {
+	case AreaGrass:
+		return "Grass"
+	case AreaWater:
+		return "Water"
+	case AreaRock:
+		return "Rock"
+	case AreaOldRod:
+		return "Old Rod"
+	case AreaGoodRod:
+		return "Good Rod"
+	case AreaSuperRod:
+		return "Super Rod"
+	}
+	return "Unknown"
+}
+
+// Returns the index of the map's encounter list that contains the area.
+func (a Area) list() int {
+	switch a {
+	case AreaGrass:
+		return 0
+	case AreaWater:
+		return 1
+	case AreaRock:
+		return 2
+	}
+	return 3
+}
+
+// Lead describes the pokemon at the head of the player's party, which can
+// influence wild encounters through its ability.
+type Lead struct {
+	// The ability of the lead pokemon. May be nil, which also represents an
+	// egg.
+	Ability pkm.Ability
+	// The personality value of the lead pokemon. Used by Synchronize.
+	PID pkm.PID
+	// Whether the lead pokemon is female. Used by Cute Charm.
+	Female bool
+}
+
+func (l Lead) has(ability int) bool {
+	return l.Ability != nil && l.Ability.Index() == ability
+}
+
+// Wild is the result of simulating a wild encounter.
+type Wild struct {
+	// The index of the selected encounter slot.
+	Slot int
+	// The encountered species.
+	Species pkm.Species
+	// The level of the encountered pokemon.
+	Level int
+	// The personality value and IVs of the encountered pokemon. The seed of
+	// the spread is the seed from which the accepted PID was generated.
+	Spread
+	// The number of frames consumed by the encounter.
+	Frames int64
+}
+
+// Selects a slot from a percentage roll, given the upper bound of each slot.
+func slotFromRoll(roll int, bounds []int) int {
+	for i, b := range bounds {
+		if roll < b {
+			return i
+		}
+	}
+	return len(bounds) - 1
+}
+
+var (
+	slotsLand      = []int{20, 40, 50, 60, 70, 80, 85, 90, 94, 98, 99, 100}
+	slotsWaterRock = []int{60, 90, 95, 99, 100}
+	slotsOldRod    = []int{70, 100}
+	slotsGoodRod   = []int{60, 80, 100}
+	slotsSuperRod  = []int{40, 80, 95, 99, 100}
+)
+
+// Attempts to select a slot whose species has a given type, as done by
+// Static and Magnet Pull. Returns -1 if no slot was selected.
+func influencedSlot(r *LCG, list pkm.EncounterList, lead Lead, ability int, typ pkm.Type) int {
+	if !lead.has(ability) || r.Next()%2 != 0 {
+		return -1
+	}
+	// The game always checks the size of a grass table, even for water
+	// tables. Only the slots that exist in the table are considered here.
+	var slots []int
+	for i, e := range list.Encounters() {
+		t := e.Species().Type()
+		if t[0] == typ || t[1] == typ {
+			slots = append(slots, i)
+		}
+	}
+	// When every slot of the grass table has the type, the game falls back
+	// to the normal slot roll.
+	if len(slots) == 0 || len(slots) == len(slotsLand) {
+		return -1
+	}
+	return slots[int(r.Next())%len(slots)]
+}
+
+// SimulateWild predicts the wild pokemon that is generated in a given area of
+// a map, as done by Emerald. The seed is the state of the generator after the
+// encounter check has passed, immediately before the encounter slot is
+// selected. To start from a frame after some initial seed, use Jump.
+//
+// Returns false if the area has no encounters. Safari Zone effects are not
+// simulated.
+func SimulateWild(m pkm.Map, area Area, seed uint32, lead Lead) (w Wild, ok bool) {
+	lists := m.Encounters()
+	if area.list() >= len(lists) || !lists[area.list()].Populated() {
+		return w, false
+	}
+	list := lists[area.list()]
+	r := New(seed)
+
+	// Encounter slot.
+	slot := -1
+	switch area {
+	case AreaGrass, AreaWater:
+		if slot = influencedSlot(r, list, lead, abilityMagnetPull, pkm.TypeSteel); slot >= 0 {
+			break
+		}
+		if slot = influencedSlot(r, list, lead, abilityStatic, pkm.TypeElectric); slot >= 0 {
+			break
+		}
+		if area == AreaGrass {
+			slot = slotFromRoll(int(r.Next())%100, slotsLand)
+		} else {
+			slot = slotFromRoll(int(r.Next())%100, slotsWaterRock)
+		}
+	case AreaRock:
+		slot = slotFromRoll(int(r.Next())%100, slotsWaterRock)
+	case AreaOldRod:
+		slot = slotFromRoll(int(r.Next())%100, slotsOldRod)
+	case AreaGoodRod:
+		slot = 2 + slotFromRoll(int(r.Next())%100, slotsGoodRod)
+	case AreaSuperRod:
+		slot = 5 + slotFromRoll(int(r.Next())%100, slotsSuperRod)
+	}
+	e := list.Encounter(slot)
+	w.Slot = slot
+	w.Species = e.Species()
+
+	// Level.
+	lo, hi := e.MinLevel(), e.MaxLevel()
+	if hi < lo {
+		lo, hi = hi, lo
+	}
+	roll := int(r.Next()) % (hi - lo + 1)
+	w.Level = lo + roll
+	if lead.has(abilityHustle) || lead.has(abilityPressure) || lead.has(abilityVitalSpirit) {
+		if r.Next()%2 == 0 {
+			w.Level = hi
+		} else if roll != 0 {
+			w.Level--
+		}
+	}
+
+	// Gender, from Cute Charm.
+	ratio := w.Species.GenderRatio()
+	cuteCharm := false
+	switch ratio {
+	case 0, 254, 255:
+	default:
+		cuteCharm = lead.has(abilityCuteCharm) && r.Next()%3 != 0
+	}
+
+	// Nature, from Synchronize.
+	var nature pkm.Nature
+	if lead.has(abilitySynchronize) && r.Next()%2 == 0 {
+		nature = lead.PID.Nature()
+	} else {
+		nature = pkm.Nature(r.Next() % 25)
+	}
+
+	// Personality value, which is rerolled until it matches.
+	for {
+		w.Spread.Seed = r.Seed
+		w.PID = pkm.PID(r.Next32())
+		if w.PID.Nature() != nature {
+			continue
+		}
+		if cuteCharm && w.PID.Female(ratio) == lead.Female {
+			continue
+		}
+		break
+	}
+	w.Method = Method1
+	w.IVs = DecodeIVs(r.Next(), r.Next())
+	w.Frames = r.Frame
+	return w, true
+}
@@ -0,0 +1,150 @@
+package rng_test
+
+import (
+	"github.com/anaminus/pkm"
+	"github.com/anaminus/pkm/gen3/rng"
+	"testing"
+)
+
+type testSpecies struct {
+	pkm.Species
+	index int
+	types [2]pkm.Type
+	ratio pkm.GenderRatio
+}
+
+func (s testSpecies) Index() int                   { return s.index }
+func (s testSpecies) Type() [2]pkm.Type            { return s.types }
+func (s testSpecies) GenderRatio() pkm.GenderRatio { return s.ratio }
+
+type testEncounter struct {
+	species  pkm.Species
+	min, max int
+}
+
+func (e testEncounter) MinLevel() int        { return e.min }
+func (e testEncounter) MaxLevel() int        { return e.max }
+func (e testEncounter) Species() pkm.Species { return e.species }
+
+type testEncounterList struct {
+	pkm.EncounterList
+	encounters []pkm.Encounter
+}
+
+func (l testEncounterList) Populated() bool                   { return len(l.encounters) > 0 }
+func (l testEncounterList) EncounterIndexSize() int           { return len(l.encounters) }
+func (l testEncounterList) Encounters() []pkm.Encounter       { return l.encounters }
+func (l testEncounterList) Encounter(index int) pkm.Encounter { return l.encounters[index] }
+
+type testMap struct {
+	pkm.Map
+	lists []pkm.EncounterList
+}
+
+func (m testMap) Encounters() []pkm.EncounterList { return m.lists }
+
+type testAbility struct {
+	pkm.Ability
+	index int
+}
+
+func (a testAbility) Index() int { return a.index }
+
+func TestSimulateWild(t *testing.T) {
+	normal := testSpecies{index: 1, types: [2]pkm.Type{pkm.TypeNormal, pkm.TypeNormal}, ratio: 127}
+	electric := testSpecies{index: 2, types: [2]pkm.Type{pkm.TypeElectric, pkm.TypeElectric}, ratio: 127}
+	grass := testEncounterList{encounters: make([]pkm.Encounter, 12)}
+	for i := range grass.encounters {
+		grass.encounters[i] = testEncounter{species: normal, min: 10, max: 14}
+	}
+	grass.encounters[11] = testEncounter{species: electric, min: 20, max: 20}
+	m := testMap{lists: []pkm.EncounterList{grass, testEncounterList{}, testEncounterList{}, testEncounterList{}}}
+
+	if _, ok := rng.SimulateWild(m, rng.AreaWater, 0, rng.Lead{}); ok {
+		t.Errorf("SimulateWild: expected unpopulated area")
+	}
+
+	for seed := uint32(0); seed < 100; seed++ {
+		w, ok := rng.SimulateWild(m, rng.AreaGrass, seed*0x10001, rng.Lead{})
+		if !ok {
+			t.Fatalf("SimulateWild: expected populated area")
+		}
+
+		// Reproduce the encounter manually.
+		r := rng.New(seed * 0x10001)
+		roll := int(r.Next()) % 100
+		slot := 11
+		for i, b := range []int{20, 40, 50, 60, 70, 80, 85, 90, 94, 98, 99, 100} {
+			if roll < b {
+				slot = i
+				break
+			}
+		}
+		if w.Slot != slot {
+			t.Errorf("SimulateWild: %d: unexpected slot %d, expected %d", seed, w.Slot, slot)
+		}
+		e := grass.encounters[slot]
+		level := e.MinLevel() + int(r.Next())%(e.MaxLevel()-e.MinLevel()+1)
+		if w.Level != level {
+			t.Errorf("SimulateWild: %d: unexpected level %d, expected %d", seed, w.Level, level)
+		}
+		nature := pkm.Nature(r.Next() % 25)
+		if w.Nature() != nature {
+			t.Errorf("SimulateWild: %d: unexpected nature %s, expected %s", seed, w.Nature(), nature)
+		}
+		if v := rng.Generate(w.Spread.Seed, rng.Method1); v.PID != w.PID || v.IVs != w.IVs {
+			t.Errorf("SimulateWild: %d: spread does not match Method 1", seed)
+		}
+		if w.Frames < 7 {
+			t.Errorf("SimulateWild: %d: unexpected frame count %d", seed, w.Frames)
+		}
+	}
+
+	// Static selects the electric species whenever its check passes.
+	static := rng.Lead{Ability: testAbility{index: 9}}
+	for seed := uint32(0); seed < 100; seed++ {
+		w, _ := rng.SimulateWild(m, rng.AreaGrass, seed*0x10001, static)
+		if r := rng.New(seed * 0x10001); r.Next()%2 == 0 && w.Species.Index() != electric.index {
+			t.Errorf("SimulateWild: Static: %d: unexpected species %d", seed, w.Species.Index())
+		}
+	}
+
+	// When every slot has the type, Static falls back to the normal slot
+	// roll, after its check has consumed a frame.
+	all := testEncounterList{encounters: make([]pkm.Encounter, 12)}
+	for i := range all.encounters {
+		all.encounters[i] = testEncounter{species: electric, min: 1, max: 100}
+	}
+	allMap := testMap{lists: []pkm.EncounterList{all, testEncounterList{}, testEncounterList{}, testEncounterList{}}}
+	for seed := uint32(0); seed < 100; seed++ {
+		w, _ := rng.SimulateWild(allMap, rng.AreaGrass, seed*0x10001, static)
+		r := rng.New(seed * 0x10001)
+		r.Next()
+		expected, _ := rng.SimulateWild(allMap, rng.AreaGrass, r.Seed, rng.Lead{})
+		if w.Slot != expected.Slot || w.Level != expected.Level || w.Spread != expected.Spread || w.Frames != expected.Frames+1 {
+			t.Errorf("SimulateWild: Static: %d: unexpected result for table of one type", seed)
+		}
+	}
+
+	// Synchronize passes the lead's nature whenever its check passes.
+	sync := rng.Lead{Ability: testAbility{index: 28}, PID: 3}
+	for seed := uint32(0); seed < 100; seed++ {
+		w, _ := rng.SimulateWild(m, rng.AreaGrass, seed*0x10001, sync)
+		r := rng.New(seed * 0x10001)
+		r.Jump(2)
+		if r.Next()%2 == 0 && w.Nature() != sync.PID.Nature() {
+			t.Errorf("SimulateWild: Synchronize: %d: unexpected nature %s", seed, w.Nature())
+		}
+	}
+
+	// Cute Charm produces the opposite gender whenever its check passes.
+	charm := rng.Lead{Ability: testAbility{index: 56}, Female: true}
+	for seed := uint32(0); seed < 100; seed++ {
+		w, _ := rng.SimulateWild(m, rng.AreaGrass, seed*0x10001, charm)
+		r := rng.New(seed * 0x10001)
+		r.Jump(2)
+		if r.Next()%3 != 0 && w.PID.Female(normal.ratio) {
+			t.Errorf("SimulateWild: Cute Charm: %d: unexpected female", seed)
+		}
+	}
+}
@@ -0,0 +1,789 @@
+// Package romtest builds synthetic ROM images, so that the gen3 package can
+// be tested without a retail ROM.
+//
+// Build returns an image with a valid GBA header, identified as Pokemon
+// Emerald (BPEE). Each table read by a Version is placed at the address used
+// by the Version, and is filled with fixture values that are derived from the
+// index of each entry. The functions of this package return the fixture
+// values, so that tests can compare them with the values read from the image.
+//
+// The image contains two banks of maps. Bank 0 contains three maps, and bank 1
+// contains one map. The maps have layouts, borders, connections, events and
+// encounters. Maps use a compressed primary tileset and an uncompressed
+// secondary tileset.
+//
+// The encounter list is referred to by a pointer following the header, as the
+// game refers to it from its code.
+//
+// Pointed-to data, such as strings and learned moves, is placed after the
+// tables. The end of the image, starting at FreeStart, is filled with unused
+// bytes, so that writes that require free space can be tested.
+package romtest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/anaminus/pkm"
+	"github.com/anaminus/pkm/gba"
+	"github.com/anaminus/pkm/gba/compress"
+	"github.com/anaminus/pkm/gen3"
+)
+
+// Size is the size of a built image.
+const Size = 0x01000000
+
+// FreeStart is the offset from which the image is unused.
+const FreeStart = 0x00E00000
+
+// Offset of the first byte of pointed-to data.
+const dataStart = 0x00C00000
+
+// Address of a pointer to the encounter list, standing in for the pointer
+// within the code of the game.
+const addrEncounterListPtr = 0x08000200
+
+// Addresses of the pokedex tables of Emerald.
+const (
+	addrNationalDex = 0x0831DC82
+	addrHoennDex    = 0x0831D94C
+)
+
+// Sizes of the index spaces of Emerald.
+const (
+	indexSizeSpecies  = 412
+	indexSizeItem     = 377
+	indexSizeAbility  = 78
+	indexSizeMove     = 355
+	indexSizeTM       = 58
+	indexSizeMapLabel = 213
+	indexSizeTrainer  = 855
+)
+
+////////////////////////////////////////////////////////////////
+
+// SpeciesName returns the name of the species at index i.
+func SpeciesName(i int) string {
+	return fmt.Sprintf("SPECIES%d", i)
+}
+
+// SpeciesCategory returns the pokedex category of the species at index i.
+func SpeciesCategory(i int) string {
+	return fmt.Sprintf("CATEGORY%d", i)
+}
+
+// SpeciesDescription returns the pokedex description of the species at index
+// i.
+func SpeciesDescription(i int) string {
+	return fmt.Sprintf("SPECIES DESCRIPTION %d", i)
+}
+
+// DexScale returns the size comparison data of the species at index i.
+// Species with a higher index are displayed larger.
+func DexScale(i int) pkm.DexScale {
+	return pkm.DexScale{
+		PokemonScale:  512 - i%256,
+		PokemonOffset: i % 16,
+		TrainerScale:  256 + i%256,
+		TrainerOffset: -(i % 4),
+	}
+}
+
+// NationalNumber returns the national pokedex number of the species at index
+// i. As in the games, species 252 to 276 are unused, and have no number.
+func NationalNumber(i int) int {
+	switch {
+	case i <= 251:
+		return i
+	case i <= 276:
+		return 0
+	}
+	return i - 25
+}
+
+// HoennNumber returns the Hoenn pokedex number of the species at index i.
+// Species from 277 are numbered first, followed by species 1 to 66. The last
+// number, 202, has no species.
+func HoennNumber(i int) int {
+	switch {
+	case i >= 277:
+		return i - 276
+	case i >= 1 && i <= 66:
+		return i + 135
+	}
+	return 0
+}
+
+// BaseStats returns the base stats of the species at index i.
+func BaseStats(i int) pkm.Stats {
+	return pkm.Stats{
+		HitPoints: uint8(i%100 + 1),
+		Attack:    uint8(i%100 + 2),
+		Defense:   uint8(i%100 + 3),
+		Speed:     uint8(i%100 + 4),
+		SpAttack:  uint8(i%100 + 5),
+		SpDefense: uint8(i%100 + 6),
+	}
+}
+
+// SpeciesTypes returns the types of the species at index i.
+func SpeciesTypes(i int) [2]pkm.Type {
+	return [2]pkm.Type{pkm.Type(i % 9), pkm.Type(i%8 + 10)}
+}
+
+// SpeciesAbilities returns the ability indices of the species at index i.
+func SpeciesAbilities(i int) [2]int {
+	return [2]int{i%77 + 1, 0}
+}
+
+// LearnedMoves returns the level and move index of each move learned by the
+// species at index i.
+func LearnedMoves(i int) [][2]int {
+	return [][2]int{
+		{1, i%354 + 1},
+		{i%50 + 5, (i+100)%354 + 1},
+	}
+}
+
+// Evolution is the fixture of an evolution.
+type Evolution struct {
+	Method, Param, Target int
+}
+
+// Evolutions returns the evolutions of the species at index i. The species
+// at indices 1 and 2 evolve by leveling up, and the species at index 133
+// evolves in five ways. The species at indices 301 to 315 each evolve by
+// method i-300, so that every method is used.
+func Evolutions(i int) []Evolution {
+	switch {
+	case i == 1, i == 2:
+		return []Evolution{{Method: 4, Param: i * 16, Target: i + 1}}
+	case i == 133:
+		return []Evolution{
+			{Method: 7, Param: 96, Target: 135},
+			{Method: 7, Param: 97, Target: 134},
+			{Method: 7, Param: 95, Target: 136},
+			{Method: 2, Param: 0, Target: 196},
+			{Method: 3, Param: 0, Target: 197},
+		}
+	case i >= 301 && i <= 315:
+		return []Evolution{{Method: i - 300, Param: i - 290, Target: i + 1}}
+	}
+	return nil
+}
+
+// SpeciesTM returns the index of the only TM that can be learned by the
+// species at index i.
+func SpeciesTM(i int) int {
+	return i % 50
+}
+
+// MoveName returns the name of the move at index i.
+func MoveName(i int) string {
+	return fmt.Sprintf("MOVE%d", i)
+}
+
+// MoveDescription returns the description of the move at index i.
+func MoveDescription(i int) string {
+	return fmt.Sprintf("MOVE DESCRIPTION %d", i)
+}
+
+// MovePower returns the base power of the move at index i.
+func MovePower(i int) int {
+	return i % 150
+}
+
+// MoveType returns the type of the move at index i.
+func MoveType(i int) pkm.Type {
+	return pkm.Type(i % 9)
+}
+
+// MovePP returns the PP of the move at index i.
+func MovePP(i int) int {
+	return i%35 + 5
+}
+
+// TMMove returns the index of the move taught by the TM at index i.
+func TMMove(i int) int {
+	return i + 1
+}
+
+// ItemName returns the name of the item at index i.
+func ItemName(i int) string {
+	return fmt.Sprintf("ITEM%d", i)
+}
+
+// ItemDescription returns the description of the item at index i.
+func ItemDescription(i int) string {
+	return fmt.Sprintf("ITEM DESCRIPTION %d", i)
+}
+
+// ItemPrice returns the price of the item at index i.
+func ItemPrice(i int) int {
+	return i * 10
+}
+
+// AbilityName returns the name of the ability at index i.
+func AbilityName(i int) string {
+	return fmt.Sprintf("ABILITY%d", i)
+}
+
+// AbilityDescription returns the description of the ability at index i.
+func AbilityDescription(i int) string {
+	return fmt.Sprintf("ABILITY DESCRIPTION %d", i)
+}
+
+// TrainerName returns the name of the trainer at index i.
+func TrainerName(i int) string {
+	return fmt.Sprintf("TRAINER%d", i)
+}
+
+// TrainerParty returns the species and level of the only member of the party
+// of the trainer at index i. The trainer at index 0 has no party.
+func TrainerParty(i int) (species, level int) {
+	return i%411 + 1, i%100 + 1
+}
+
+// TrainerFlags returns the party flags of the trainer at index i. Bit 0
+// indicates custom moves, and bit 1 indicates held items. Each combination of
+// flags is used by every fourth trainer.
+func TrainerFlags(i int) int {
+	return i % 4
+}
+
+// TrainerHeldItem returns the item held by the party member of the trainer at
+// index i, when the party has held items.
+func TrainerHeldItem(i int) int {
+	return i%376 + 1
+}
+
+// TrainerMoves returns the custom moves of the party member of the trainer at
+// index i, when the party has custom moves.
+func TrainerMoves(i int) [4]int {
+	return [4]int{i%354 + 1, (i+1)%354 + 1, 0, 0}
+}
+
+// Starters are the species indices of the starters.
+var Starters = [3]int{1, 4, 7}
+
+// MapLabel returns the name of the map label at index i.
+func MapLabel(i int) string {
+	return fmt.Sprintf("LABEL%d", i)
+}
+
+// Map is the fixture of a map.
+type Map struct {
+	Bank, Index int
+	// Index of the map label.
+	Label         int
+	Width, Height int
+	Connections   []pkm.Connection
+	// Wild encounter rate and slots for each area, in the order of grass,
+	// water, rock and rod. Areas without encounters have a rate of 0.
+	EncounterRates [4]int
+	Encounters     [4][]Encounter
+	// Item within an item ball, or 0 if the map has no item ball.
+	ItemBall int
+	// Hidden item, or 0 if the map has no hidden item.
+	HiddenItem int
+}
+
+// Encounter is the fixture of an encounter slot.
+type Encounter struct {
+	MinLevel, MaxLevel int
+	Species            int
+}
+
+// Number of slots in each encounter area.
+var encounterSlots = [4]int{12, 5, 5, 10}
+
+// Returns the encounter slots of an area.
+func encounters(area, species, level int) []Encounter {
+	e := make([]Encounter, encounterSlots[area])
+	for i := range e {
+		e[i] = Encounter{MinLevel: level + i, MaxLevel: level + i + 2, Species: species + i}
+	}
+	return e
+}
+
+// Maps are the fixtures of each map, in order of bank and map index.
+var Maps = []Map{
+	{
+		Bank: 0, Index: 0, Label: 0, Width: 4, Height: 3,
+		Connections: []pkm.Connection{
+			{Direction: pkm.Right, Offset: 0, Bank: 0, Map: 1},
+		},
+		EncounterRates: [4]int{20, 0, 0, 0},
+		Encounters:     [4][]Encounter{encounters(0, 10, 2)},
+		ItemBall:       13,
+		HiddenItem:     14,
+	},
+	{
+		Bank: 0, Index: 1, Label: 1, Width: 3, Height: 3,
+		Connections: []pkm.Connection{
+			{Direction: pkm.Left, Offset: 0, Bank: 0, Map: 0},
+			{Direction: pkm.Down, Offset: -1, Bank: 1, Map: 0},
+		},
+		EncounterRates: [4]int{0, 4, 0, 30},
+		Encounters:     [4][]Encounter{1: encounters(1, 40, 20), 3: encounters(3, 60, 5)},
+	},
+	{
+		Bank: 0, Index: 2, Label: 3, Width: 2, Height: 2,
+		EncounterRates: [4]int{10, 4, 20, 30},
+		Encounters: [4][]Encounter{
+			encounters(0, 80, 20),
+			encounters(1, 100, 15),
+			encounters(2, 120, 10),
+			encounters(3, 140, 5),
+		},
+	},
+	{
+		Bank: 1, Index: 0, Label: 2, Width: 2, Height: 2,
+		Connections: []pkm.Connection{
+			{Direction: pkm.Up, Offset: 1, Bank: 0, Map: 1},
+		},
+	},
+}
+
+// Cell returns the block index of the cell at index i of the layout of a map.
+// The first block of the secondary tileset is 512.
+func (m Map) Cell(i int) int {
+	if i%2 == 0 {
+		return i % 4
+	}
+	return 512 + i%4
+}
+
+// Entries of the type effectiveness list, as attacker, defender and
+// effectiveness. ForesightEffects follow the Foresight separator.
+var (
+	TypeEffects = [][3]int{
+		{int(pkm.TypeFire), int(pkm.TypeGrass), int(pkm.SuperEffective)},
+		{int(pkm.TypeWater), int(pkm.TypeFire), int(pkm.SuperEffective)},
+		{int(pkm.TypeGrass), int(pkm.TypeFire), int(pkm.NotVeryEffective)},
+	}
+	ForesightEffects = [][3]int{
+		{int(pkm.TypeNormal), int(pkm.TypeGhost), int(pkm.NoEffect)},
+	}
+)
+
+// Number of sprites in the primary tileset image.
+const primarySprites = 64
+
+// Sprite returns the 4-bit color index of pixel p of sprite i of the primary
+// (0) or secondary (1) tileset.
+func Sprite(tileset, i, p int) int {
+	return (i + p/8 + tileset) % 16
+}
+
+// Color returns color c of palette i of the primary (0) or secondary (1)
+// tileset, as a 15-bit color.
+func Color(tileset, i, c int) uint16 {
+	return uint16(c | i<<5 | tileset<<10)
+}
+
+// Block returns tile j of block i of the primary (0) or secondary (1)
+// tileset. Blocks use sprite i of their tileset on both layers, with a
+// different palette for each tile.
+func Block(tileset, i, j int) pkm.Tile {
+	return pkm.Tile(i%primarySprites + tileset*512 | (tileset*6+j%4)<<12)
+}
+
+////////////////////////////////////////////////////////////////
+
+// Writes fixtures to an image.
+type builder struct {
+	b []byte
+	// Offset of the next pointed-to data.
+	next int
+}
+
+// Returns the offset of a GBA address.
+func offset(addr uint32) int {
+	return int(addr - 0x08000000)
+}
+
+// Returns the GBA address of an offset.
+func address(off int) uint32 {
+	return uint32(off + 0x08000000)
+}
+
+func (b *builder) put(addr uint32, data ...byte) {
+	copy(b.b[offset(addr):], data)
+}
+
+func (b *builder) put16(addr uint32, v int) {
+	binary.LittleEndian.PutUint16(b.b[offset(addr):], uint16(v))
+}
+
+func (b *builder) put32(addr uint32, v uint32) {
+	binary.LittleEndian.PutUint32(b.b[offset(addr):], v)
+}
+
+// Writes pointed-to data, returning its address.
+func (b *builder) alloc(data []byte) uint32 {
+	addr := address(b.next)
+	copy(b.b[b.next:], data)
+	b.next += (len(data) + 3) &^ 3
+	if b.next > FreeStart {
+		panic("romtest: data exceeds free space")
+	}
+	return addr
+}
+
+// Encodes a string, without a terminator.
+func encode(s string) []byte {
+	t, err := pkm.EncodeText(gen3.CodecUTF8, s)
+	if err != nil {
+		panic("romtest: " + err.Error())
+	}
+	return t
+}
+
+// Writes a terminated string to a fixed-size field.
+func (b *builder) name(addr uint32, size int, s string) {
+	t := encode(s)
+	if len(t) >= size {
+		panic("romtest: name " + s + " too long")
+	}
+	field := make([]byte, size)
+	for i := range field {
+		field[i] = 0xFF
+	}
+	copy(field, t)
+	b.put(addr, field...)
+}
+
+// Writes a terminated string as pointed-to data, returning its address.
+func (b *builder) text(s string) uint32 {
+	return b.alloc(append(encode(s), 0xFF))
+}
+
+func enc16(v int) []byte {
+	return []byte{byte(v), byte(v >> 8)}
+}
+
+func enc32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+// Build returns a synthetic ROM image.
+func Build() []byte {
+	b := &builder{b: make([]byte, Size), next: dataStart}
+	for i := FreeStart; i < Size; i++ {
+		b.b[i] = 0xFF
+	}
+	copy(b.b, gba.NewHeader(gen3.CodeEmeraldEN, "POKEMON EMER").Bytes())
+
+	// Addresses of the tables.
+	v, err := gen3.OpenROM(gen3.NewBuffer(b.b))
+	if err != nil {
+		panic("romtest: " + err.Error())
+	}
+	ver := v.(*gen3.Version)
+
+	b.species(ver)
+	b.moves(ver)
+	b.items(ver)
+	b.abilities(ver)
+	b.typeEffects(ver)
+	b.trainers(ver)
+	b.maps(ver)
+	return b.b
+}
+
+// Version returns a writable Version of a synthetic ROM image.
+func Version() *gen3.Version {
+	v, err := gen3.OpenROM(gen3.NewBuffer(Build()))
+	if err != nil {
+		panic("romtest: " + err.Error())
+	}
+	return v.(*gen3.Version)
+}
+
+func (b *builder) species(ver *gen3.Version) {
+	for i := 0; i < indexSizeSpecies; i++ {
+		b.name(uint32(ver.AddrSpeciesName)+uint32(i*11), 11, SpeciesName(i))
+
+		data := make([]byte, 28)
+		s := BaseStats(i)
+		copy(data, []byte{s.HitPoints, s.Attack, s.Defense, s.Speed, s.SpAttack, s.SpDefense})
+		t := SpeciesTypes(i)
+		data[6], data[7] = byte(t[0]), byte(t[1])
+		data[8] = byte(i)         // Catch rate
+		data[9] = byte(i * 2)     // Exp yield
+		data[16] = 127            // Gender ratio
+		data[17] = 20             // Egg cycles
+		data[18] = 70             // Base friendship
+		data[19] = byte(i % 6)    // Level type
+		data[20], data[21] = 1, 2 // Egg groups
+		a := SpeciesAbilities(i)
+		data[22], data[23] = byte(a[0]), byte(a[1])
+		data[24] = 30           // Safari rate
+		data[25] = byte(i % 10) // Color
+		b.put(uint32(ver.AddrSpeciesData)+uint32(i*28), data...)
+
+		var moves []byte
+		for _, lm := range LearnedMoves(i) {
+			moves = append(moves, byte(lm[1]), byte(lm[0]<<1|lm[1]>>8))
+		}
+		moves = append(moves, 0xFF, 0xFF)
+		b.put32(uint32(ver.AddrLevelMovePtr)+uint32(i*4), b.alloc(moves))
+
+		for j, e := range Evolutions(i) {
+			evo := uint32(ver.AddrSpeciesEvo) + uint32(i*40+j*8)
+			b.put16(evo, e.Method)
+			b.put16(evo+2, e.Param)
+			b.put16(evo+4, e.Target)
+		}
+
+		tm := SpeciesTM(i)
+		b.put(uint32(ver.AddrSpeciesTM)+uint32(i*8+tm/8), 1<<uint(tm%8))
+
+		if i > 0 {
+			b.put16(addrNationalDex+uint32((i-1)*2), NationalNumber(i))
+			b.put16(addrHoennDex+uint32((i-1)*2), HoennNumber(i))
+		}
+
+		// Pokedex data is indexed by national pokedex number.
+		n := NationalNumber(i)
+		if i > 0 && n == 0 {
+			continue
+		}
+		dex := uint32(ver.AddrPokedexData) + uint32(n*32)
+		b.name(dex, 12, SpeciesCategory(i))
+		b.put16(dex+12, i)    // Height
+		b.put16(dex+14, i*10) // Weight
+		b.put32(dex+16, b.text(SpeciesDescription(i)))
+		scale := DexScale(i)
+		b.put16(dex+22, scale.PokemonScale)
+		b.put16(dex+24, scale.PokemonOffset)
+		b.put16(dex+26, scale.TrainerScale)
+		b.put16(dex+28, scale.TrainerOffset)
+	}
+	for i, s := range Starters {
+		b.put16(uint32(ver.AddrStarters)+uint32(i*2), s)
+	}
+}
+
+func (b *builder) moves(ver *gen3.Version) {
+	for i := 0; i < indexSizeMove; i++ {
+		b.name(uint32(ver.AddrMoveName)+uint32(i*13), 13, MoveName(i))
+		data := make([]byte, 12)
+		data[1] = byte(MovePower(i))
+		data[2] = byte(MoveType(i))
+		data[3] = 100 // Accuracy
+		data[4] = byte(MovePP(i))
+		b.put(uint32(ver.AddrMoveData)+uint32(i*12), data...)
+		if i > 0 {
+			b.put32(uint32(ver.AddrMoveDescPtr)+uint32((i-1)*4), b.text(MoveDescription(i)))
+		}
+	}
+	for i := 0; i < indexSizeTM; i++ {
+		b.put16(uint32(ver.AddrTMMove)+uint32(i*2), TMMove(i))
+	}
+}
+
+func (b *builder) items(ver *gen3.Version) {
+	for i := 0; i < indexSizeItem; i++ {
+		item := uint32(ver.AddrItemData) + uint32(i*44)
+		b.name(item, 14, ItemName(i))
+		b.put16(item+14, i)
+		b.put16(item+16, ItemPrice(i))
+		b.put32(item+20, b.text(ItemDescription(i)))
+		b.put(item+26, byte(i%5+1)) // Pocket
+	}
+}
+
+func (b *builder) abilities(ver *gen3.Version) {
+	for i := 0; i < indexSizeAbility; i++ {
+		b.name(uint32(ver.AddrAbilityName)+uint32(i*13), 13, AbilityName(i))
+		b.put32(uint32(ver.AddrAbilityDescPtr)+uint32(i*4), b.text(AbilityDescription(i)))
+	}
+}
+
+func (b *builder) typeEffects(ver *gen3.Version) {
+	var list []byte
+	for _, e := range TypeEffects {
+		list = append(list, byte(e[0]), byte(e[1]), byte(e[2]))
+	}
+	list = append(list, 0xFE, 0xFE, 0x00)
+	for _, e := range ForesightEffects {
+		list = append(list, byte(e[0]), byte(e[1]), byte(e[2]))
+	}
+	list = append(list, 0xFF, 0xFF, 0x00)
+	b.put(uint32(ver.AddrTypeEffect), list...)
+}
+
+func (b *builder) trainers(ver *gen3.Version) {
+	for i := 1; i < indexSizeTrainer; i++ {
+		trainer := uint32(ver.AddrTrainerData) + uint32(i*40)
+		b.name(trainer+4, 12, TrainerName(i))
+		species, level := TrainerParty(i)
+		flags := TrainerFlags(i)
+		b.put(trainer, byte(flags))
+		party := []byte{0, 0} // IVs
+		party = append(party, enc16(level)...)
+		party = append(party, enc16(species)...)
+		if flags&2 != 0 {
+			party = append(party, enc16(TrainerHeldItem(i))...)
+		}
+		if flags&1 != 0 {
+			for _, m := range TrainerMoves(i) {
+				party = append(party, enc16(m)...)
+			}
+		}
+		if len(party)%4 != 0 {
+			party = append(party, 0, 0) // Padding
+		}
+		b.put32(trainer+32, 1)
+		b.put32(trainer+36, b.alloc(party))
+	}
+	b.name(uint32(ver.AddrTrainerData)+4, 12, TrainerName(0))
+}
+
+// Writes a tileset, returning the address of its header.
+func (b *builder) tileset(n int) uint32 {
+	image := make([]byte, primarySprites*32)
+	if n == 1 {
+		// The secondary image is read in full.
+		image = make([]byte, 512*32)
+	}
+	for i := 0; i < len(image)/32; i++ {
+		for p := 0; p < 64; p += 2 {
+			image[i*32+p/2] = byte(Sprite(n, i, p) | Sprite(n, i, p+1)<<4)
+		}
+	}
+	pal := make([]byte, 16*32)
+	for i := 0; i < 16; i++ {
+		for c := 0; c < 16; c++ {
+			binary.LittleEndian.PutUint16(pal[i*32+c*2:], Color(n, i, c))
+		}
+	}
+	blocks := make([]byte, 512*16)
+	for i := 0; i < 512; i++ {
+		for j := 0; j < 8; j++ {
+			binary.LittleEndian.PutUint16(blocks[i*16+j*2:], uint16(Block(n, i, j)))
+		}
+	}
+
+	header := make([]byte, 24)
+	if n == 0 {
+		header[0] = 1 // Compressed
+		image, err := compress.CompressLZ77(image)
+		if err != nil {
+			panic("romtest: " + err.Error())
+		}
+		copy(header[4:], enc32(b.alloc(image)))
+	} else {
+		header[1] = 1 // Uses palettes 6-11
+		copy(header[4:], enc32(b.alloc(image)))
+	}
+	copy(header[8:], enc32(b.alloc(pal)))
+	copy(header[12:], enc32(b.alloc(blocks)))
+	return b.alloc(header)
+}
+
+func (b *builder) maps(ver *gen3.Version) {
+	for i := 0; i < indexSizeMapLabel; i++ {
+		b.put32(uint32(ver.AddrMapLabel)+uint32(i*8+4), b.text(MapLabel(i)))
+	}
+
+	tilesets := [2]uint32{b.tileset(0), b.tileset(1)}
+	script := b.alloc([]byte{0x02}) // end
+
+	var banks [][]uint32
+	var list []byte
+	for _, m := range Maps {
+		cells := make([]byte, m.Width*m.Height*2)
+		for i := 0; i < m.Width*m.Height; i++ {
+			binary.LittleEndian.PutUint16(cells[i*2:], uint16(m.Cell(i)))
+		}
+		border := make([]byte, 8)
+		for i := 0; i < 4; i++ {
+			binary.LittleEndian.PutUint16(border[i*2:], uint16(i))
+		}
+		layout := make([]byte, 28)
+		copy(layout[0:], enc32(uint32(m.Width)))
+		copy(layout[4:], enc32(uint32(m.Height)))
+		copy(layout[8:], enc32(b.alloc(border)))
+		copy(layout[12:], enc32(b.alloc(cells)))
+		copy(layout[16:], enc32(tilesets[0]))
+		copy(layout[20:], enc32(tilesets[1]))
+		layout[24], layout[25] = 2, 2
+
+		events := make([]byte, 20)
+		if m.ItemBall != 0 {
+			s := []byte{0x1A, 0x00, 0x80, byte(m.ItemBall), byte(m.ItemBall >> 8), 0x1A, 0x01, 0x80, 0x01, 0x00}
+			object := make([]byte, 24)
+			copy(object[16:], enc32(b.alloc(s)))
+			events[0] = 1
+			copy(events[4:], enc32(b.alloc(object)))
+		}
+		if m.HiddenItem != 0 {
+			bg := make([]byte, 12)
+			bg[5] = 7 // Hidden item
+			copy(bg[8:], enc16(m.HiddenItem))
+			events[3] = 1
+			copy(events[16:], enc32(b.alloc(bg)))
+		}
+
+		var conns []byte
+		for _, c := range m.Connections {
+			conns = append(conns, enc32(uint32(c.Direction))...)
+			conns = append(conns, enc32(uint32(int32(c.Offset)))...)
+			conns = append(conns, byte(c.Bank), byte(c.Map), 0, 0)
+		}
+		connHeader := append(enc32(uint32(len(m.Connections))), enc32(b.alloc(conns))...)
+
+		header := make([]byte, 28)
+		copy(header[0:], enc32(b.alloc(layout)))
+		copy(header[4:], enc32(b.alloc(events)))
+		copy(header[8:], enc32(script))
+		copy(header[12:], enc32(b.alloc(connHeader)))
+		header[20] = byte(m.Label)
+
+		for len(banks) <= m.Bank {
+			banks = append(banks, nil)
+		}
+		banks[m.Bank] = append(banks[m.Bank], b.alloc(header))
+
+		entry := make([]byte, 20)
+		entry[0], entry[1] = byte(m.Bank), byte(m.Index)
+		found := false
+		for area, slots := range m.Encounters {
+			if len(slots) == 0 {
+				continue
+			}
+			found = true
+			var e []byte
+			for _, s := range slots {
+				e = append(e, byte(s.MinLevel), byte(s.MaxLevel))
+				e = append(e, enc16(s.Species)...)
+			}
+			h := []byte{byte(m.EncounterRates[area]), 0, 0, 0}
+			h = append(h, enc32(b.alloc(e))...)
+			copy(entry[4+area*4:], enc32(b.alloc(h)))
+		}
+		if found {
+			list = append(list, entry...)
+		}
+	}
+	list = append(list, 0xFF, 0xFF)
+	b.put(uint32(ver.AddrEncounterList), list...)
+	b.put32(addrEncounterListPtr, uint32(ver.AddrEncounterList))
+
+	// Map tables are terminated by a null pointer.
+	var table []byte
+	for _, maps := range banks {
+		var t []byte
+		for _, p := range maps {
+			t = append(t, enc32(p)...)
+		}
+		t = append(t, 0, 0, 0, 0)
+		table = append(table, enc32(b.alloc(t))...)
+	}
+	table = append(table, 0, 0, 0, 0)
+	b.put32(uint32(ver.AddrBanksPtr), b.alloc(table))
+}
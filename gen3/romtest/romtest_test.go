@@ -0,0 +1,487 @@
+package romtest_test
+
+import (
+	"fmt"
+	"github.com/anaminus/pkm"
+	"github.com/anaminus/pkm/gen3"
+	"github.com/anaminus/pkm/gen3/romtest"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	b := romtest.Build()
+	if len(b) != romtest.Size {
+		t.Fatalf("Build: unexpected size %d", len(b))
+	}
+	if v := b[romtest.FreeStart]; v != 0xFF {
+		t.Errorf("Build: unexpected free space byte %02X", v)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	ver := romtest.Version()
+	if errs := ver.Validate(); len(errs) > 0 {
+		for _, err := range errs {
+			t.Errorf("Validate: unexpected error: %s", err)
+		}
+	}
+}
+
+func TestSpecies(t *testing.T) {
+	ver := romtest.Version()
+	for _, i := range []int{1, 2, 133, 150, 305, 411} {
+		s := ver.SpeciesByIndex(i)
+		if v := s.Name(); v != romtest.SpeciesName(i) {
+			t.Errorf("Species.Name: %d: unexpected result %q", i, v)
+		}
+		if v := s.Category(); v != romtest.SpeciesCategory(i) {
+			t.Errorf("Species.Category: %d: unexpected result %q", i, v)
+		}
+		if v := s.Description(); v != romtest.SpeciesDescription(i) {
+			t.Errorf("Species.Description: %d: unexpected result %q", i, v)
+		}
+		if v := s.BaseStats(); v != romtest.BaseStats(i) {
+			t.Errorf("Species.BaseStats: %d: unexpected result %v", i, v)
+		}
+		if v := s.Type(); v != romtest.SpeciesTypes(i) {
+			t.Errorf("Species.Type: %d: unexpected result %v", i, v)
+		}
+		a := s.Ability()
+		if v := [2]int{a[0].Index(), a[1].Index()}; v != romtest.SpeciesAbilities(i) {
+			t.Errorf("Species.Ability: %d: unexpected result %v", i, v)
+		}
+		moves := s.LearnedMoves()
+		expected := romtest.LearnedMoves(i)
+		if len(moves) != len(expected) {
+			t.Errorf("Species.LearnedMoves: %d: unexpected length %d", i, len(moves))
+		} else {
+			for j, m := range moves {
+				if int(m.Level) != expected[j][0] || m.Move.Index() != expected[j][1] {
+					t.Errorf("Species.LearnedMoves: %d: unexpected move %d at level %d", i, m.Move.Index(), m.Level)
+				}
+			}
+		}
+		if v := s.CanLearnTM(ver.TMByIndex(romtest.SpeciesTM(i))); !v {
+			t.Errorf("Species.CanLearnTM: %d: expected true", i)
+		}
+		if v := len(s.LearnableTMs()); v != 1 {
+			t.Errorf("Species.LearnableTMs: %d: unexpected length %d", i, v)
+		}
+		evos := s.Evolutions()
+		expectedEvos := romtest.Evolutions(i)
+		if len(evos) != len(expectedEvos) {
+			t.Errorf("Species.Evolutions: %d: unexpected length %d", i, len(evos))
+		} else {
+			for j, e := range expectedEvos {
+				if int(evos[j].Method()) != e.Method || int(evos[j].Param()) != e.Param || evos[j].Target().Index() != e.Target {
+					t.Errorf("Species.Evolutions: %d: unexpected evolution %s to %d", i, evos[j].MethodString(), evos[j].Target().Index())
+				}
+			}
+		}
+	}
+	if v := ver.SpeciesByName(romtest.SpeciesName(25)); v == nil || v.Index() != 25 {
+		t.Errorf("SpeciesByName: unexpected result %v", v)
+	}
+}
+
+func TestPokedexEntry(t *testing.T) {
+	ver := romtest.Version()
+	ver.ScanBanks()
+	for _, i := range []int{1, 10, 40, 300} {
+		s := ver.SpeciesByIndex(i).(gen3.Species)
+		if v := s.DescriptionPages(); len(v) != 1 || v[0] != romtest.SpeciesDescription(i) {
+			t.Errorf("Species.DescriptionPages: %d: unexpected result %q", i, v)
+		}
+		if v := s.DexScale(); v != romtest.DexScale(i) {
+			t.Errorf("Species.DexScale: %d: unexpected result %+v", i, v)
+		}
+	}
+
+	// Species are encountered on maps according to the fixtures.
+	for _, i := range []int{1, 10, 21, 40, 65} {
+		var expected []string
+		for _, m := range romtest.Maps {
+		search:
+			for _, slots := range m.Encounters {
+				for _, e := range slots {
+					if e.Species == i {
+						expected = append(expected, fmt.Sprintf("%d.%d", m.Bank, m.Index))
+						break search
+					}
+				}
+			}
+		}
+		var areas []string
+		for _, m := range ver.SpeciesByIndex(i).(gen3.Species).Areas() {
+			areas = append(areas, fmt.Sprintf("%d.%d", m.BankIndex(), m.Index()))
+		}
+		if fmt.Sprint(areas) != fmt.Sprint(expected) {
+			t.Errorf("Species.Areas: %d: unexpected result %v, expected %v", i, areas, expected)
+		}
+	}
+
+	// A solid sprite at a scale of 512 is drawn at half size.
+	sprite := image.NewUniform(color.Black)
+	scale := pkm.DexScale{PokemonScale: 512, TrainerScale: 256, TrainerOffset: 8}
+	img := pkm.DrawSizeComparison(image.NewNRGBA(image.Rect(0, 0, 64, 64)), sprite, scale, color.White)
+	var trainer int
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			if img.NRGBAAt(x, y).A != 0 {
+				trainer++
+			}
+		}
+	}
+	if trainer != 64*64 {
+		t.Errorf("DrawSizeComparison: unexpected trainer area %d", trainer)
+	}
+	if v := img.NRGBAAt(152, 56+8-32); v.A == 0 {
+		t.Errorf("DrawSizeComparison: expected offset trainer")
+	}
+	solid := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(solid, solid.Bounds(), sprite, image.Point{}, draw.Src)
+	img = pkm.DrawSizeComparison(solid, nil, scale, color.White)
+	var pokemon int
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			if img.NRGBAAt(x, y).A != 0 {
+				pokemon++
+			}
+		}
+	}
+	if pokemon != 32*32 {
+		t.Errorf("DrawSizeComparison: unexpected pokemon area %d", pokemon)
+	}
+}
+
+func TestPokedex(t *testing.T) {
+	ver := romtest.Version()
+	numbers := map[string]func(int) int{
+		"National": romtest.NationalNumber,
+		"Hoenn":    romtest.HoennNumber,
+	}
+	if v := ver.PokedexByName("Standard"); v == nil || v.Name() != "Hoenn" {
+		t.Errorf("PokedexByName: unexpected result %v", v)
+	}
+	for _, dex := range ver.Pokedex() {
+		number := numbers[dex.Name()]
+		if number == nil {
+			t.Errorf("Pokedex.Name: unexpected result %q", dex.Name())
+			continue
+		}
+		for _, i := range []int{1, 100, 251, 252, 276, 277, 300, 411} {
+			s := ver.SpeciesByIndex(i)
+			n := number(i)
+			if v := dex.SpeciesNumber(s); v != n {
+				t.Errorf("Pokedex.SpeciesNumber: %s: %d: unexpected result %d", dex.Name(), i, v)
+			}
+			if n == 0 {
+				continue
+			}
+			if v := dex.Species(n); v == nil || v.Index() != i {
+				t.Errorf("Pokedex.Species: %s: %d: unexpected result %v", dex.Name(), n, v)
+			}
+		}
+
+		// Numbers without a species are nil.
+		all := dex.AllSpecies()
+		if len(all) != dex.Size() {
+			t.Errorf("Pokedex.AllSpecies: %s: unexpected length %d", dex.Name(), len(all))
+		}
+		for j, s := range all {
+			if s == nil {
+				if dex.Name() != "Hoenn" || j != 201 {
+					t.Errorf("Pokedex.AllSpecies: %s: unexpected nil at position %d", dex.Name(), j)
+				}
+				continue
+			}
+			if v := dex.SpeciesNumber(s); v != j+1 {
+				t.Errorf("Pokedex.AllSpecies: %s: species %d has number %d at position %d", dex.Name(), s.Index(), v, j)
+				break
+			}
+		}
+
+		r := dex.Range(10, 20)
+		if len(r) != 11 || r[0].Index() != all[9].Index() || r[10].Index() != all[19].Index() {
+			t.Errorf("Pokedex.Range: %s: unexpected result %v", dex.Name(), r)
+		}
+		if v := dex.Range(dex.Size(), dex.Size()); len(v) != 1 || (v[0] == nil) != (dex.Name() == "Hoenn") {
+			t.Errorf("Pokedex.Range: %s: unexpected result %v at end", dex.Name(), v)
+		}
+		if v := dex.Range(5, 4); len(v) != 0 {
+			t.Errorf("Pokedex.Range: %s: expected empty result", dex.Name())
+		}
+		for _, bounds := range [][2]int{{0, 1}, {1, dex.Size() + 1}, {3, 1}} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("Pokedex.Range: %s: %v: expected panic", dex.Name(), bounds)
+					}
+				}()
+				dex.Range(bounds[0], bounds[1])
+			}()
+		}
+	}
+}
+
+func TestMoves(t *testing.T) {
+	ver := romtest.Version()
+	for _, i := range []int{1, 33, 354} {
+		m := ver.MoveByIndex(i)
+		if v := m.Name(); v != romtest.MoveName(i) {
+			t.Errorf("Move.Name: %d: unexpected result %q", i, v)
+		}
+		if v := m.Description(); v != romtest.MoveDescription(i) {
+			t.Errorf("Move.Description: %d: unexpected result %q", i, v)
+		}
+		if v := int(m.BasePower()); v != romtest.MovePower(i) {
+			t.Errorf("Move.BasePower: %d: unexpected result %d", i, v)
+		}
+		if v := m.Type(); v != romtest.MoveType(i) {
+			t.Errorf("Move.Type: %d: unexpected result %v", i, v)
+		}
+		if v := int(m.PowerPoints()); v != romtest.MovePP(i) {
+			t.Errorf("Move.PowerPoints: %d: unexpected result %d", i, v)
+		}
+	}
+	for _, i := range []int{0, 49, 57} {
+		if v := ver.TMByIndex(i).Move().Index(); v != romtest.TMMove(i) {
+			t.Errorf("TM.Move: %d: unexpected result %d", i, v)
+		}
+	}
+}
+
+func TestItemsAndAbilities(t *testing.T) {
+	ver := romtest.Version()
+	for _, i := range []int{1, 13, 376} {
+		item := ver.ItemByIndex(i)
+		if v := item.Name(); v != romtest.ItemName(i) {
+			t.Errorf("Item.Name: %d: unexpected result %q", i, v)
+		}
+		if v := item.Description(); v != romtest.ItemDescription(i) {
+			t.Errorf("Item.Description: %d: unexpected result %q", i, v)
+		}
+		if v := item.Price(); v != romtest.ItemPrice(i) {
+			t.Errorf("Item.Price: %d: unexpected result %d", i, v)
+		}
+	}
+	for _, i := range []int{1, 77} {
+		a := ver.AbilityByIndex(i)
+		if v := a.Name(); v != romtest.AbilityName(i) {
+			t.Errorf("Ability.Name: %d: unexpected result %q", i, v)
+		}
+		if v := a.Description(); v != romtest.AbilityDescription(i) {
+			t.Errorf("Ability.Description: %d: unexpected result %q", i, v)
+		}
+	}
+}
+
+func TestTrainers(t *testing.T) {
+	ver := romtest.Version()
+	if v := ver.TrainerByIndex(0).Party(); len(v) != 0 {
+		t.Errorf("Trainer.Party: 0: unexpected length %d", len(v))
+	}
+	for _, i := range []int{1, 2, 3, 4, 853, 854} {
+		trainer := ver.TrainerByIndex(i)
+		if v := trainer.Name(); v != romtest.TrainerName(i) {
+			t.Errorf("Trainer.Name: %d: unexpected result %q", i, v)
+		}
+		party := trainer.Party()
+		if len(party) != 1 {
+			t.Errorf("Trainer.Party: %d: unexpected length %d", i, len(party))
+			continue
+		}
+		species, level := romtest.TrainerParty(i)
+		if v := party[0].Species().Index(); v != species {
+			t.Errorf("TrainerPokemon.Species: %d: unexpected result %d", i, v)
+		}
+		if v := party[0].Level(); v != level {
+			t.Errorf("TrainerPokemon.Level: %d: unexpected result %d", i, v)
+		}
+		flags := romtest.TrainerFlags(i)
+		if item := party[0].HeldItem(); flags&2 == 0 {
+			if item != nil {
+				t.Errorf("TrainerPokemon.HeldItem: %d: expected nil", i)
+			}
+		} else if item == nil || item.Index() != romtest.TrainerHeldItem(i) {
+			t.Errorf("TrainerPokemon.HeldItem: %d: unexpected result %v", i, item)
+		}
+		moves := party[0].Moves()
+		if flags&1 == 0 {
+			if moves != nil {
+				t.Errorf("TrainerPokemon.Moves: %d: expected nil", i)
+			}
+			continue
+		}
+		expected := romtest.TrainerMoves(i)
+		if i == 853 {
+			// Setting moves does not affect the other fields.
+			expected = [4]int{10, 20, 30, 40}
+			var set []pkm.Move
+			for _, m := range expected {
+				set = append(set, ver.MoveByIndex(m))
+			}
+			if err := party[0].SetMoves(set); err != nil {
+				t.Errorf("TrainerPokemon.SetMoves: %d: unexpected error: %s", i, err)
+			}
+			if v := party[0].Species().Index(); v != species {
+				t.Errorf("TrainerPokemon.SetMoves: %d: species changed to %d", i, v)
+			}
+			if v := party[0].Level(); v != level {
+				t.Errorf("TrainerPokemon.SetMoves: %d: level changed to %d", i, v)
+			}
+			moves = party[0].Moves()
+		}
+		for j, m := range moves {
+			// Unused slots are nil.
+			index := 0
+			if m != nil {
+				index = m.Index()
+			}
+			if index != expected[j] {
+				t.Errorf("TrainerPokemon.Moves: %d: unexpected move %d at %d", i, index, j)
+			}
+		}
+	}
+}
+
+func TestStartersAndTypes(t *testing.T) {
+	ver := romtest.Version()
+	for i, s := range ver.Starters() {
+		if s.Index() != romtest.Starters[i] {
+			t.Errorf("Starters: unexpected species %d at %d", s.Index(), i)
+		}
+	}
+	chart := ver.TypeChart()
+	for _, e := range romtest.TypeEffects {
+		if v := chart.Effect[e[0]][e[1]]; v != pkm.Effectiveness(e[2]) || chart.Foresight[e[0]][e[1]] {
+			t.Errorf("TypeChart: unexpected effectiveness %v of %v against %v", v, pkm.Type(e[0]), pkm.Type(e[1]))
+		}
+	}
+	for _, e := range romtest.ForesightEffects {
+		if v := chart.Effect[e[0]][e[1]]; v != pkm.Effectiveness(e[2]) || !chart.Foresight[e[0]][e[1]] {
+			t.Errorf("TypeChart: unexpected Foresight effectiveness %v of %v against %v", v, pkm.Type(e[0]), pkm.Type(e[1]))
+		}
+	}
+
+	// The chart used by TypeEffectiveness is not affected by changes to a
+	// returned chart, but is affected by writing the chart.
+	fire, grass := pkm.Type(pkm.TypeFire), [2]pkm.Type{pkm.TypeGrass, pkm.TypeGrass}
+	chart.Effect[fire][pkm.TypeGrass] = pkm.NoEffect
+	if v := ver.TypeEffectiveness(fire, grass); v != 2 {
+		t.Errorf("TypeEffectiveness: unexpected result %g", v)
+	}
+	if err := ver.SetTypeChart(chart); err != nil {
+		t.Fatalf("SetTypeChart: unexpected error: %s", err)
+	}
+	if v := ver.TypeEffectiveness(fire, grass); v != 0 {
+		t.Errorf("TypeEffectiveness: unexpected result %g after SetTypeChart", v)
+	}
+}
+
+func TestMaps(t *testing.T) {
+	ver := romtest.Version()
+	ver.ScanBanks()
+	if v := ver.BankIndexSize(); v != 2 {
+		t.Fatalf("BankIndexSize: unexpected result %d", v)
+	}
+	if v := len(ver.AllMaps()); v != len(romtest.Maps) {
+		t.Fatalf("AllMaps: unexpected length %d", v)
+	}
+	for _, fixture := range romtest.Maps {
+		m := ver.BankByIndex(fixture.Bank).MapByIndex(fixture.Index).(gen3.Map)
+		if v := m.Name(); v != romtest.MapLabel(fixture.Label) {
+			t.Errorf("Map.Name: %d.%d: unexpected result %q", fixture.Bank, fixture.Index, v)
+		}
+
+		l := m.Layout()
+		if l.Width() != fixture.Width || l.Height() != fixture.Height {
+			t.Errorf("Map.Layout: %d.%d: unexpected size %dx%d", fixture.Bank, fixture.Index, l.Width(), l.Height())
+		} else {
+			for i := 0; i < l.Width()*l.Height(); i++ {
+				if block, _ := l.Cell(i); block != fixture.Cell(i) {
+					t.Errorf("Layout.Cell: %d.%d: unexpected block %d at %d", fixture.Bank, fixture.Index, block, i)
+				}
+			}
+		}
+
+		conns := m.Connections()
+		if len(conns) != len(fixture.Connections) {
+			t.Errorf("Map.Connections: %d.%d: unexpected length %d", fixture.Bank, fixture.Index, len(conns))
+		} else {
+			for i, c := range conns {
+				if c != fixture.Connections[i] {
+					t.Errorf("Map.Connections: %d.%d: unexpected connection %v", fixture.Bank, fixture.Index, c)
+				}
+			}
+		}
+
+		for area, list := range m.Encounters() {
+			slots := fixture.Encounters[area]
+			if v := list.Populated(); v != (len(slots) > 0) {
+				t.Errorf("EncounterList.Populated: %d.%d: %s: unexpected result %t", fixture.Bank, fixture.Index, list.Name(), v)
+				continue
+			}
+			for i, e := range list.Encounters() {
+				if e.MinLevel() != slots[i].MinLevel || e.MaxLevel() != slots[i].MaxLevel || e.Species().Index() != slots[i].Species {
+					t.Errorf("EncounterList.Encounters: %d.%d: %s: unexpected encounter at %d", fixture.Bank, fixture.Index, list.Name(), i)
+				}
+			}
+		}
+
+		var ball, hidden int
+		for _, f := range m.FieldItems() {
+			if f.Hidden() {
+				hidden = f.Item().Index()
+			} else {
+				ball = f.Item().Index()
+			}
+		}
+		if ball != fixture.ItemBall || hidden != fixture.HiddenItem {
+			t.Errorf("Map.FieldItems: %d.%d: unexpected items %d, %d", fixture.Bank, fixture.Index, ball, hidden)
+		}
+	}
+}
+
+// Converts a 15-bit color to the color drawn by a map image.
+func drawn(c uint16) color.NRGBA {
+	return color.NRGBA{R: byte(c&31) * 8, G: byte(c>>5&31) * 8, B: byte(c>>10&31) * 8, A: 255}
+}
+
+func TestMapImage(t *testing.T) {
+	ver := romtest.Version()
+	ver.ScanBanks()
+	fixture := romtest.Maps[0]
+	m := ver.BankByIndex(fixture.Bank).MapByIndex(fixture.Index)
+	layers := m.Image()
+	if len(layers) != 2 {
+		t.Fatalf("Map.Image: unexpected length %d", len(layers))
+	}
+	if v := layers[0].Bounds().Dx(); v != fixture.Width*16 {
+		t.Errorf("Map.Image: unexpected width %d", v)
+	}
+	// Checks the second row of the upper-left tile of each cell in the first
+	// row of the layout. Cells alternate between the compressed primary
+	// tileset and the uncompressed secondary tileset.
+	for i := 0; i < fixture.Width; i++ {
+		block := fixture.Cell(i)
+		tileset := block / 512
+		tile := romtest.Block(tileset, block%512, 0)
+		palette := tile.PaletteIndex() - tileset*6
+		for x := 0; x < 8; x++ {
+			p := 8 + x
+			ci := romtest.Sprite(tileset, tile.SpriteIndex()%512, p)
+			var expected color.NRGBA
+			if ci > 0 {
+				// The secondary tileset selects palettes 6-11.
+				expected = drawn(romtest.Color(tileset, palette+tileset*6, ci))
+			}
+			if v := layers[0].NRGBAAt(i*16+x, 1); v != expected {
+				t.Errorf("Map.Image: cell %d: unexpected color %v at %d, expected %v", i, v, x, expected)
+			}
+		}
+	}
+}
@@ -3,6 +3,7 @@ package gen3
 import (
 	"fmt"
 	"github.com/anaminus/pkm"
+	"strings"
 )
 
 var (
@@ -35,16 +36,34 @@ var (
 		1, // 22 Color/Flip
 		2, // 23 Padding
 	)
+	// Pokedex data of Ruby, Sapphire, FireRed and LeafGreen. Descriptions
+	// have a second page, which is unused by FireRed and LeafGreen.
 	structDexData = makeStruct(
 		12, // 0 Category
 		2,  // 1 Height
 		2,  // 2 Weight
 		4,  // 3 DescPtr1
 		4,  // 4 DescPtr2
-		2,  // 5 PokémonScale
-		2,  // 6 PokémonOffset
-		2,  // 7 TrainerScale
-		2,  // 8 TrainerOffset
+		2,  // 5 Unused
+		2,  // 6 PokémonScale
+		2,  // 7 PokémonOffset
+		2,  // 8 TrainerScale
+		2,  // 9 TrainerOffset
+		2,  // 10 Padding
+	)
+	// Pokedex data of Emerald, which has no second page of the description.
+	structDexDataEmerald = makeStruct(
+		12, // 0 Category
+		2,  // 1 Height
+		2,  // 2 Weight
+		4,  // 3 DescPtr1
+		0,  // 4 DescPtr2
+		2,  // 5 Unused
+		2,  // 6 PokémonScale
+		2,  // 7 PokémonOffset
+		2,  // 8 TrainerScale
+		2,  // 9 TrainerOffset
+		2,  // 10 Padding
 	)
 	structSpeciesTM = makeStruct(
 		8, // 0 TMs
@@ -85,48 +104,118 @@ type Species struct {
 }
 
 func (s Species) Name() string {
-	b := readStruct(
-		s.v.ROM,
-		s.v.AddrSpeciesName,
-		s.i,
-		structSpeciesName,
-	)
-	return decodeTextString(b)
+	return s.v.text(textSpeciesName, s.i, indexSizeSpecies, func(j int) string {
+		b := readStruct(
+			s.v.table(tableSpeciesName),
+			s.v.AddrSpeciesName,
+			j,
+			structSpeciesName,
+		)
+		return decodeTextString(b)
+	})
+}
+
+// SetName sets the name of the species. Returns a *NameError if the name does
+// not fit.
+func (s Species) SetName(name string) error {
+	b, err := encodeName(name, structSpeciesName.Size())
+	if err != nil {
+		return err
+	}
+	w, err := s.v.writer()
+	if err != nil {
+		return err
+	}
+	return writeStruct(w, s.v.AddrSpeciesName, s.i, structSpeciesName, b)
 }
 
 func (s Species) Index() int {
 	return s.i
 }
 
+// Description returns the pokedex description of the species. A description
+// with two pages has the pages separated by a newline.
 func (s Species) Description() string {
+	return s.v.text(textSpeciesDescription, s.i, indexSizeSpecies, func(j int) string {
+		return strings.Join(Species{v: s.v, i: j}.DescriptionPages(), "\n")
+	})
+}
+
+// DescriptionPages returns each page of the pokedex description of the
+// species. Descriptions of Ruby and Sapphire have two pages, while
+// descriptions of other versions have one.
+func (s Species) DescriptionPages() []string {
+	st := s.v.dexData
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tablePokedexData),
 		s.v.AddrPokedexData,
-		s.v.Pokedex()[0].SpeciesNumber(s),
-		structDexData,
-		3,
+		s.v.speciesNumber(s),
+		st,
+		3, 4,
 	)
-	s.v.ROM.Seek(decPtr(b).ROM(), 0)
-	return readTextString(s.v.ROM)
+	pages := []string{readTextString(s.v.reader(decPtr(b[0:4]).ROM()))}
+	if gc := s.v.GameCode(); gc == CodeRubyEN || gc == CodeSapphireEN {
+		pages = append(pages, readTextString(s.v.reader(decPtr(b[4:8]).ROM())))
+	}
+	return pages
 }
 
-func (s Species) Category() string {
+// DexScale returns the scale and offset of the species and the trainer, as
+// displayed by the size comparison page of the pokedex.
+func (s Species) DexScale() pkm.DexScale {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tablePokedexData),
 		s.v.AddrPokedexData,
-		s.v.Pokedex()[0].SpeciesNumber(s),
-		structDexData,
-		0,
+		s.v.speciesNumber(s),
+		s.v.dexData,
+		6, 7, 8, 9,
 	)
-	return decodeTextString(b)
+	return pkm.DexScale{
+		PokemonScale:  int(decUint16(b[0:2])),
+		PokemonOffset: int(int16(decUint16(b[2:4]))),
+		TrainerScale:  int(decUint16(b[4:6])),
+		TrainerOffset: int(int16(decUint16(b[6:8]))),
+	}
+}
+
+// Areas returns the maps highlighted by the area page of the pokedex, which
+// are the maps where the species can be encountered in the wild. Panics if
+// banks have not been scanned.
+func (s Species) Areas() []pkm.Map {
+	var maps []pkm.Map
+	for _, m := range s.v.AllMaps() {
+	search:
+		for _, list := range m.Encounters() {
+			for _, e := range list.Encounters() {
+				if e.Species().Index() == s.i {
+					maps = append(maps, m)
+					break search
+				}
+			}
+		}
+	}
+	return maps
+}
+
+func (s Species) Category() string {
+	return s.v.text(textSpeciesCategory, s.i, indexSizeSpecies, func(j int) string {
+		b := readStruct(
+			s.v.table(tablePokedexData),
+			s.v.AddrPokedexData,
+			s.v.speciesNumber(Species{v: s.v, i: j}),
+			s.v.dexData,
+			0,
+		)
+		return decodeTextString(b)
+	})
 }
 
 func (s Species) Height() pkm.Height {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tablePokedexData),
 		s.v.AddrPokedexData,
-		s.v.Pokedex()[0].SpeciesNumber(s),
-		structDexData,
+		s.v.speciesNumber(s),
+		s.v.dexData,
 		1,
 	)
 	return pkm.Height(decUint16(b))
@@ -134,10 +223,10 @@ func (s Species) Height() pkm.Height {
 
 func (s Species) Weight() pkm.Weight {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tablePokedexData),
 		s.v.AddrPokedexData,
-		s.v.Pokedex()[0].SpeciesNumber(s),
-		structDexData,
+		s.v.speciesNumber(s),
+		s.v.dexData,
 		2,
 	)
 	return pkm.Weight(decUint16(b))
@@ -145,7 +234,7 @@ func (s Species) Weight() pkm.Weight {
 
 func (s Species) BaseStats() pkm.Stats {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tableSpeciesData),
 		s.v.AddrSpeciesData,
 		s.i,
 		structSpeciesData,
@@ -163,7 +252,7 @@ func (s Species) BaseStats() pkm.Stats {
 
 func (s Species) Type() [2]pkm.Type {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tableSpeciesData),
 		s.v.AddrSpeciesData,
 		s.i,
 		structSpeciesData,
@@ -177,7 +266,7 @@ func (s Species) Type() [2]pkm.Type {
 
 func (s Species) CatchRate() byte {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tableSpeciesData),
 		s.v.AddrSpeciesData,
 		s.i,
 		structSpeciesData,
@@ -188,7 +277,7 @@ func (s Species) CatchRate() byte {
 
 func (s Species) ExpYield() byte {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tableSpeciesData),
 		s.v.AddrSpeciesData,
 		s.i,
 		structSpeciesData,
@@ -199,7 +288,7 @@ func (s Species) ExpYield() byte {
 
 func (s Species) EffortPoints() pkm.EffortPoints {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tableSpeciesData),
 		s.v.AddrSpeciesData,
 		s.i,
 		structSpeciesData,
@@ -210,7 +299,7 @@ func (s Species) EffortPoints() pkm.EffortPoints {
 
 func (s Species) HeldItem() [2]pkm.Item {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tableSpeciesData),
 		s.v.AddrSpeciesData,
 		s.i,
 		structSpeciesData,
@@ -224,7 +313,7 @@ func (s Species) HeldItem() [2]pkm.Item {
 
 func (s Species) GenderRatio() pkm.GenderRatio {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tableSpeciesData),
 		s.v.AddrSpeciesData,
 		s.i,
 		structSpeciesData,
@@ -235,7 +324,7 @@ func (s Species) GenderRatio() pkm.GenderRatio {
 
 func (s Species) EggCycles() byte {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tableSpeciesData),
 		s.v.AddrSpeciesData,
 		s.i,
 		structSpeciesData,
@@ -246,7 +335,7 @@ func (s Species) EggCycles() byte {
 
 func (s Species) BaseFriendship() byte {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tableSpeciesData),
 		s.v.AddrSpeciesData,
 		s.i,
 		structSpeciesData,
@@ -257,7 +346,7 @@ func (s Species) BaseFriendship() byte {
 
 func (s Species) LevelType() pkm.LevelType {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tableSpeciesData),
 		s.v.AddrSpeciesData,
 		s.i,
 		structSpeciesData,
@@ -268,7 +357,7 @@ func (s Species) LevelType() pkm.LevelType {
 
 func (s Species) EggGroup() [2]pkm.EggGroup {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tableSpeciesData),
 		s.v.AddrSpeciesData,
 		s.i,
 		structSpeciesData,
@@ -282,7 +371,7 @@ func (s Species) EggGroup() [2]pkm.EggGroup {
 
 func (s Species) Ability() [2]pkm.Ability {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tableSpeciesData),
 		s.v.AddrSpeciesData,
 		s.i,
 		structSpeciesData,
@@ -296,7 +385,7 @@ func (s Species) Ability() [2]pkm.Ability {
 
 func (s Species) SafariRate() byte {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tableSpeciesData),
 		s.v.AddrSpeciesData,
 		s.i,
 		structSpeciesData,
@@ -307,7 +396,7 @@ func (s Species) SafariRate() byte {
 
 func (s Species) Color() pkm.SpeciesColor {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tableSpeciesData),
 		s.v.AddrSpeciesData,
 		s.i,
 		structSpeciesData,
@@ -318,7 +407,7 @@ func (s Species) Color() pkm.SpeciesColor {
 
 func (s Species) Flipped() bool {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tableSpeciesData),
 		s.v.AddrSpeciesData,
 		s.i,
 		structSpeciesData,
@@ -327,18 +416,141 @@ func (s Species) Flipped() bool {
 	return b[0]&128 != 0
 }
 
+// Writes the given fields of the species data.
+func (s Species) setData(b []byte, fields ...int) error {
+	w, err := s.v.writer()
+	if err != nil {
+		return err
+	}
+	return writeStruct(
+		w,
+		s.v.AddrSpeciesData,
+		s.i,
+		structSpeciesData,
+		b,
+		fields...,
+	)
+}
+
+func (s Species) SetBaseStats(stats pkm.Stats) error {
+	return s.setData([]byte{
+		stats.HitPoints,
+		stats.Attack,
+		stats.Defense,
+		stats.Speed,
+		stats.SpAttack,
+		stats.SpDefense,
+	}, 0, 1, 2, 3, 4, 5)
+}
+
+func (s Species) SetType(types [2]pkm.Type) error {
+	return s.setData([]byte{byte(types[0]), byte(types[1])}, 6, 7)
+}
+
+func (s Species) SetCatchRate(rate byte) error {
+	return s.setData([]byte{rate}, 8)
+}
+
+func (s Species) SetExpYield(exp byte) error {
+	return s.setData([]byte{exp}, 9)
+}
+
+func (s Species) SetEffortPoints(ep pkm.EffortPoints) error {
+	return s.setData(encUint16(uint16(ep)), 10)
+}
+
+// SetHeldItem sets the items that may be held by a wild pokemon of the
+// species. A nil item indicates no item.
+func (s Species) SetHeldItem(items [2]pkm.Item) error {
+	b := make([]byte, 0, 4)
+	for _, item := range items {
+		index := 0
+		if item != nil {
+			index = item.Index()
+		}
+		b = append(b, encUint16(uint16(index))...)
+	}
+	return s.setData(b, 11, 12)
+}
+
+func (s Species) SetGenderRatio(ratio pkm.GenderRatio) error {
+	return s.setData([]byte{byte(ratio)}, 13)
+}
+
+func (s Species) SetEggCycles(cycles byte) error {
+	return s.setData([]byte{cycles}, 14)
+}
+
+func (s Species) SetBaseFriendship(friendship byte) error {
+	return s.setData([]byte{friendship}, 15)
+}
+
+func (s Species) SetLevelType(t pkm.LevelType) error {
+	return s.setData([]byte{byte(t)}, 16)
+}
+
+func (s Species) SetEggGroup(groups [2]pkm.EggGroup) error {
+	return s.setData([]byte{byte(groups[0]), byte(groups[1])}, 17, 18)
+}
+
+// SetAbility sets the abilities that the species is able to have. A nil
+// ability indicates no ability.
+func (s Species) SetAbility(abilities [2]pkm.Ability) error {
+	b := make([]byte, 2)
+	for i, a := range abilities {
+		if a != nil {
+			b[i] = byte(a.Index())
+		}
+	}
+	return s.setData(b, 19, 20)
+}
+
+func (s Species) SetSafariRate(rate byte) error {
+	return s.setData([]byte{rate}, 21)
+}
+
+// SetColor sets the color of the species, preserving whether the species is
+// flipped.
+func (s Species) SetColor(color pkm.SpeciesColor) error {
+	b := readStruct(
+		s.v.table(tableSpeciesData),
+		s.v.AddrSpeciesData,
+		s.i,
+		structSpeciesData,
+		22,
+	)
+	return s.setData([]byte{b[0]&128 | byte(color)&127}, 22)
+}
+
+// SetFlipped sets whether the species is flipped, preserving the color of the
+// species.
+func (s Species) SetFlipped(flipped bool) error {
+	b := readStruct(
+		s.v.table(tableSpeciesData),
+		s.v.AddrSpeciesData,
+		s.i,
+		structSpeciesData,
+		22,
+	)
+	b[0] &= 127
+	if flipped {
+		b[0] |= 128
+	}
+	return s.setData(b, 22)
+}
+
 func (s Species) LearnedMoves() []pkm.LevelMove {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tableLevelMovePtr),
 		s.v.AddrLevelMovePtr,
 		s.i,
 		structPtr,
 	)
-	s.v.ROM.Seek(decPtr(b).ROM(), 0)
+	r := s.v.reader(decPtr(b).ROM())
 	b = make([]byte, 0, 32)
 	q := make([]byte, 2)
 	for {
-		s.v.ROM.Read(q)
+		r.Read(q)
 		if q[0] == strTerm && q[1] == strTerm {
 			break
 		}
@@ -354,16 +566,44 @@ func (s Species) LearnedMoves() []pkm.LevelMove {
 	return lms
 }
 
+// Returns the size of the species' learned-move data, including the
+// terminator.
+func (s Species) learnedMovesSize() int {
+	return (len(s.LearnedMoves()) + 1) * 2
+}
+
+// SetLearnedMoves sets the moves learned by a pokemon of the species. If the
+// list is longer than the current list, or the current list is shared with
+// other species, then the list is written to free space.
+func (s Species) SetLearnedMoves(moves []pkm.LevelMove) error {
+	b := make([]byte, 0, (len(moves)+1)*2)
+	for _, lm := range moves {
+		if lm.Move == nil || lm.Move.Index() < 0 || lm.Move.Index() > 511 {
+			return fmt.Errorf("invalid move for level %d", lm.Level)
+		}
+		if lm.Level > 127 {
+			return fmt.Errorf("level %d exceeds 127", lm.Level)
+		}
+		b = append(b, byte(lm.Move.Index()), lm.Level<<1|byte(lm.Move.Index()>>8))
+	}
+	b = append(b, strTerm, strTerm)
+	return s.v.writePointed(
+		s.v.AddrLevelMovePtr,
+		s.i,
+		b,
+		s.learnedMovesSize(),
+	)
+}
+
 func (s Species) CanLearnTM(tm pkm.TM) bool {
 	b := make([]byte, 1)
-	s.v.ROM.Seek(s.v.AddrSpeciesTM.ROM()+int64(s.i*8+tm.Index()/8), 0)
-	s.v.ROM.Read(b)
+	s.v.table(tableSpeciesTM).ReadAt(b, s.v.AddrSpeciesTM.ROM()+int64(s.i*8+tm.Index()/8))
 	return b[0]&(1<<uint(tm.Index()%8)) != 0
 }
 
 func (s Species) LearnableTMs() []pkm.TM {
 	b := readStruct(
-		s.v.ROM,
+		s.v.table(tableSpeciesTM),
 		s.v.AddrSpeciesTM,
 		s.i,
 		structSpeciesTM,
@@ -377,11 +617,58 @@ func (s Species) LearnableTMs() []pkm.TM {
 	return tms
 }
 
+// SetCanLearnTM sets whether a pokemon of the species can learn a move from a
+// given TM.
+func (s Species) SetCanLearnTM(tm pkm.TM, learnable bool) error {
+	w, err := s.v.writer()
+	if err != nil {
+		return err
+	}
+	b := readStruct(
+		s.v.table(tableSpeciesTM),
+		s.v.AddrSpeciesTM,
+		s.i,
+		structSpeciesTM,
+	)
+	if learnable {
+		b[tm.Index()/8] |= 1 << uint(tm.Index()%8)
+	} else {
+		b[tm.Index()/8] &^= 1 << uint(tm.Index()%8)
+	}
+	return writeStruct(
+		w,
+		s.v.AddrSpeciesTM,
+		s.i,
+		structSpeciesTM,
+		b,
+	)
+}
+
+// SetLearnableTMs sets the TMs that a pokemon of the species can learn,
+// replacing the existing TMs.
+func (s Species) SetLearnableTMs(tms []pkm.TM) error {
+	w, err := s.v.writer()
+	if err != nil {
+		return err
+	}
+	b := make([]byte, structSpeciesTM.Size())
+	for _, tm := range tms {
+		b[tm.Index()/8] |= 1 << uint(tm.Index()%8)
+	}
+	return writeStruct(
+		w,
+		s.v.AddrSpeciesTM,
+		s.i,
+		structSpeciesTM,
+		b,
+	)
+}
+
 func (s Species) Evolutions() []pkm.Evolution {
 	evos := make([]pkm.Evolution, 0, structEvoSubLen)
 	for i := 0; i < cap(evos); i++ {
 		b := readStruct(
-			s.v.ROM,
+			s.v.table(tableSpeciesEvo),
 			s.v.AddrSpeciesEvo,
 			s.i,
 			structEvolution,
@@ -407,7 +694,7 @@ type Evolution struct {
 
 func (e Evolution) Target() pkm.Species {
 	b := readStruct(
-		e.v.ROM,
+		e.v.table(tableSpeciesEvo),
 		e.v.AddrSpeciesEvo,
 		e.s,
 		structEvolution,
@@ -418,7 +705,7 @@ func (e Evolution) Target() pkm.Species {
 
 func (e Evolution) Method() uint16 {
 	b := readStruct(
-		e.v.ROM,
+		e.v.table(tableSpeciesEvo),
 		e.v.AddrSpeciesEvo,
 		e.s,
 		structEvolution,
@@ -429,7 +716,7 @@ func (e Evolution) Method() uint16 {
 
 func (e Evolution) Param() uint16 {
 	b := readStruct(
-		e.v.ROM,
+		e.v.table(tableSpeciesEvo),
 		e.v.AddrSpeciesEvo,
 		e.s,
 		structEvolution,
@@ -440,7 +727,7 @@ func (e Evolution) Param() uint16 {
 
 func (e Evolution) MethodString() (s string) {
 	b := readStruct(
-		e.v.ROM,
+		e.v.table(tableSpeciesEvo),
 		e.v.AddrSpeciesEvo,
 		e.s,
 		structEvolution,
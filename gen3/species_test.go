@@ -1,15 +1,20 @@
 package gen3_test
 
 import (
+	"bytes"
+	"errors"
 	"github.com/anaminus/pkm"
+	"github.com/anaminus/pkm/gba"
 	"github.com/anaminus/pkm/gen3"
+	"github.com/anaminus/pkm/gen3/romtest"
+	"strings"
 	"testing"
 )
 
 func TestSpecies(t *testing.T) {
-	ver := gen3.OpenROM(ROM(t))
-	if ver == nil {
-		t.Fatalf("failed to open ROM")
+	ver, err := gen3.OpenROM(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
 	}
 
 	species := ver.SpeciesByIndex(1)
@@ -31,7 +36,7 @@ func TestSpecies(t *testing.T) {
 	if v := species.Weight(); v != 69 {
 		t.Errorf("Weight: unexpected result %d", v)
 	}
-	if v := species.BaseStats(); v != (pkm.Stats{45, 49, 49, 45, 65, 65}) {
+	if v := species.BaseStats(); v != (pkm.Stats{HitPoints: 45, Attack: 49, Defense: 49, Speed: 45, SpAttack: 65, SpDefense: 65}) {
 		t.Errorf("BaseStats: unexpected result %#v", v)
 	}
 	if v := species.Type(); v != [2]pkm.Type{pkm.TypeGrass, pkm.TypePoison} {
@@ -166,3 +171,420 @@ func TestSpecies(t *testing.T) {
 		}
 	}
 }
+
+func TestSpeciesSynthetic(t *testing.T) {
+	ver, err := gen3.OpenROM(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	species := ver.SpeciesByIndex(1)
+	if v := species.Index(); v != 1 {
+		t.Errorf("Index: unexpected result %d", v)
+	}
+	if v := species.Name(); v != romtest.SpeciesName(1) {
+		t.Errorf("Name: unexpected result \"%s\"", v)
+	}
+	if v := species.Description(); v != romtest.SpeciesDescription(1) {
+		t.Errorf("Description: unexpected result \"%s\"", v)
+	}
+	if v := species.Category(); v != romtest.SpeciesCategory(1) {
+		t.Errorf("Category: unexpected result \"%s\"", v)
+	}
+	if v := species.Height(); v != 1 {
+		t.Errorf("Height: unexpected result %d", v)
+	}
+	if v := species.Weight(); v != 10 {
+		t.Errorf("Weight: unexpected result %d", v)
+	}
+	if v := species.BaseStats(); v != romtest.BaseStats(1) {
+		t.Errorf("BaseStats: unexpected result %#v", v)
+	}
+	if v := species.Type(); v != romtest.SpeciesTypes(1) {
+		t.Errorf("Type: unexpected result %#v", v)
+	}
+	if v := species.CatchRate(); v != 1 {
+		t.Errorf("CatchRate: unexpected result %d", v)
+	}
+	if v := species.ExpYield(); v != 2 {
+		t.Errorf("ExpYield: unexpected result %d", v)
+	}
+	if v := species.EffortPoints(); v != 0 {
+		t.Errorf("EffortPoints: unexpected result %d", v)
+	}
+	if v := species.HeldItem(); v != [2]pkm.Item{ver.ItemByIndex(0), ver.ItemByIndex(0)} {
+		t.Errorf("HeldItem: unexpected result %#v", v)
+	}
+	if v := species.GenderRatio(); v != 127 {
+		t.Errorf("GenderRatio: unexpected result %d", v)
+	}
+	if v := species.EggCycles(); v != 20 {
+		t.Errorf("EggCycles: unexpected result %d", v)
+	}
+	if v := species.BaseFriendship(); v != 70 {
+		t.Errorf("BaseFriendship: unexpected result %d", v)
+	}
+	if v := species.LevelType(); v != pkm.Erratic {
+		t.Errorf("LevelType: unexpected result %d", v)
+	}
+	if v := species.EggGroup(); v != [2]pkm.EggGroup{pkm.EggMonster, pkm.EggWater1} {
+		t.Errorf("EggGroup: unexpected result %#v", v)
+	}
+	if a := romtest.SpeciesAbilities(1); species.Ability() != [2]pkm.Ability{ver.AbilityByIndex(a[0]), ver.AbilityByIndex(a[1])} {
+		t.Errorf("Ability: unexpected result %#v", species.Ability())
+	}
+	if v := species.SafariRate(); v != 30 {
+		t.Errorf("SafariRate: unexpected result %d", v)
+	}
+	if v := species.Color(); v != pkm.ColorBlue {
+		t.Errorf("Color: unexpected result %s", v)
+	}
+	{
+		var moves []pkm.LevelMove
+		for _, m := range romtest.LearnedMoves(1) {
+			moves = append(moves, pkm.LevelMove{Level: byte(m[0]), Move: ver.MoveByIndex(m[1])})
+		}
+		v := species.LearnedMoves()
+		if len(v) != len(moves) {
+			t.Errorf("LearnedMoves: unexpected result length %d", len(v))
+		} else {
+			for i, m := range v {
+				if m.Level != moves[i].Level {
+					t.Errorf("LearnedMoves: %d unexpected level %d", i, m.Level)
+				}
+				if m.Move != moves[i].Move {
+					t.Errorf("LearnedMoves: %d unexpected move %d (%s)", i, m.Move.Index(), m.Move.Name())
+				}
+			}
+		}
+	}
+	if v := species.CanLearnTM(ver.TMByIndex(romtest.SpeciesTM(1))); !v {
+		t.Errorf("CanLearnTM: unexpected result: %t", v)
+	}
+	if v := species.CanLearnTM(ver.TMByIndex(5)); v {
+		t.Errorf("CanLearnTM: unexpected result: %t", v)
+	}
+	if v := species.LearnableTMs(); len(v) != 1 || v[0].Index() != romtest.SpeciesTM(1) {
+		t.Errorf("LearnableTMs: unexpected result %v", v)
+	}
+	if evos := species.Evolutions(); len(evos) != 1 {
+		t.Errorf("Evolutions: unexpected result length %d", len(evos))
+	} else {
+		if v := evos[0].Target(); v.Index() != 2 {
+			t.Errorf("Evolutions: unexpected target %d (%s)", v.Index(), v.Name())
+		}
+		if v := evos[0].Method(); v != 4 {
+			t.Errorf("Evolutions: unexpected method %d", v)
+		}
+		if v := evos[0].Param(); v != 16 {
+			t.Errorf("Evolutions: unexpected param %d", v)
+		}
+	}
+	species = ver.SpeciesByIndex(133)
+	if evos := species.Evolutions(); len(evos) != 5 {
+		t.Errorf("Evolutions: unexpected result length %d", len(evos))
+	} else {
+		target := [5]int{135, 134, 136, 196, 197}
+		method := [5]uint16{7, 7, 7, 2, 3}
+		param := [5]uint16{96, 97, 95, 0, 0}
+		for i, evo := range evos {
+			if v := evo.Target(); v.Index() != target[i] {
+				t.Errorf("Evolutions: %d: unexpected target %d (%s)", i, v.Index(), v.Name())
+			}
+			if v := evo.Method(); v != method[i] {
+				t.Errorf("Evolutions: %d: unexpected method %d", i, v)
+			}
+			if v := evo.Param(); v != param[i] {
+				t.Errorf("Evolutions: %d: unexpected param %d", i, v)
+			}
+		}
+	}
+	if species, ok := species.(gen3.Species); ok {
+		if v := species.Flipped(); v {
+			t.Errorf("Flipped: unexpected result %t", v)
+		}
+	}
+}
+
+func TestSpeciesWrite(t *testing.T) {
+	if ver, err := gen3.OpenROM(ROM(t)); err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	} else if err := ver.SpeciesByIndex(1).(gen3.Species).SetCatchRate(0); err != gen3.ErrReadOnly {
+		t.Errorf("SetCatchRate: expected ErrReadOnly, got %v", err)
+	}
+
+	buf, err := gen3.ReadBuffer(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to read ROM: %s", err)
+	}
+	ver, err := gen3.OpenROM(buf)
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+	species := ver.SpeciesByIndex(1).(gen3.Species)
+	next := ver.SpeciesByIndex(2)
+	stats := next.BaseStats()
+
+	check := func(name string, err error) {
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+		}
+	}
+	check("SetBaseStats", species.SetBaseStats(pkm.Stats{HitPoints: 1, Attack: 2, Defense: 3, Speed: 4, SpAttack: 5, SpDefense: 6}))
+	check("SetType", species.SetType([2]pkm.Type{pkm.TypeFire, pkm.TypeDragon}))
+	check("SetCatchRate", species.SetCatchRate(3))
+	check("SetExpYield", species.SetExpYield(200))
+	check("SetEffortPoints", species.SetEffortPoints(0x0C03))
+	check("SetHeldItem", species.SetHeldItem([2]pkm.Item{ver.ItemByIndex(13), nil}))
+	check("SetGenderRatio", species.SetGenderRatio(255))
+	check("SetEggCycles", species.SetEggCycles(5))
+	check("SetBaseFriendship", species.SetBaseFriendship(0))
+	check("SetLevelType", species.SetLevelType(pkm.Erratic))
+	check("SetEggGroup", species.SetEggGroup([2]pkm.EggGroup{pkm.EggDragon, pkm.EggDitto}))
+	check("SetAbility", species.SetAbility([2]pkm.Ability{ver.AbilityByIndex(1), ver.AbilityByIndex(2)}))
+	check("SetSafariRate", species.SetSafariRate(7))
+	check("SetFlipped", species.SetFlipped(true))
+	check("SetColor", species.SetColor(pkm.ColorPink))
+
+	if v := species.BaseStats(); v != (pkm.Stats{HitPoints: 1, Attack: 2, Defense: 3, Speed: 4, SpAttack: 5, SpDefense: 6}) {
+		t.Errorf("BaseStats: unexpected result %#v", v)
+	}
+	if v := species.Type(); v != [2]pkm.Type{pkm.TypeFire, pkm.TypeDragon} {
+		t.Errorf("Type: unexpected result %#v", v)
+	}
+	if v := species.CatchRate(); v != 3 {
+		t.Errorf("CatchRate: unexpected result %d", v)
+	}
+	if v := species.ExpYield(); v != 200 {
+		t.Errorf("ExpYield: unexpected result %d", v)
+	}
+	if v := species.EffortPoints(); v != 0x0C03 {
+		t.Errorf("EffortPoints: unexpected result %d", v)
+	}
+	if v := species.HeldItem(); v != [2]pkm.Item{ver.ItemByIndex(13), ver.ItemByIndex(0)} {
+		t.Errorf("HeldItem: unexpected result %#v", v)
+	}
+	if v := species.GenderRatio(); v != 255 {
+		t.Errorf("GenderRatio: unexpected result %d", v)
+	}
+	if v := species.EggCycles(); v != 5 {
+		t.Errorf("EggCycles: unexpected result %d", v)
+	}
+	if v := species.BaseFriendship(); v != 0 {
+		t.Errorf("BaseFriendship: unexpected result %d", v)
+	}
+	if v := species.LevelType(); v != pkm.Erratic {
+		t.Errorf("LevelType: unexpected result %d", v)
+	}
+	if v := species.EggGroup(); v != [2]pkm.EggGroup{pkm.EggDragon, pkm.EggDitto} {
+		t.Errorf("EggGroup: unexpected result %#v", v)
+	}
+	if v := species.Ability(); v != [2]pkm.Ability{ver.AbilityByIndex(1), ver.AbilityByIndex(2)} {
+		t.Errorf("Ability: unexpected result %#v", v)
+	}
+	if v := species.SafariRate(); v != 7 {
+		t.Errorf("SafariRate: unexpected result %d", v)
+	}
+	if v := species.Color(); v != pkm.ColorPink {
+		t.Errorf("Color: unexpected result %s", v)
+	}
+	if v := species.Flipped(); !v {
+		t.Errorf("Flipped: unexpected result %t", v)
+	}
+	if v := next.BaseStats(); v != stats {
+		t.Errorf("BaseStats: neighboring species was modified: %#v", v)
+	}
+
+	var out bytes.Buffer
+	if _, err := ver.(*gen3.Version).WriteTo(&out); err != nil {
+		t.Errorf("WriteTo: unexpected error: %s", err)
+	}
+	if saved, err := gen3.OpenROM(bytes.NewReader(out.Bytes())); err != nil {
+		t.Errorf("WriteTo: failed to open saved ROM: %s", err)
+	} else if v := saved.SpeciesByIndex(1).CatchRate(); v != 3 {
+		t.Errorf("WriteTo: unexpected saved result %d", v)
+	}
+}
+
+func TestSpeciesWriteSynthetic(t *testing.T) {
+	if ver, err := gen3.OpenROM(SyntheticROM()); err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	} else if err := ver.SpeciesByIndex(1).(gen3.Species).SetCatchRate(0); err != gen3.ErrReadOnly {
+		t.Errorf("SetCatchRate: expected ErrReadOnly, got %v", err)
+	}
+
+	buf, err := gen3.ReadBuffer(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to read ROM: %s", err)
+	}
+	ver, err := gen3.OpenROM(buf)
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+	species := ver.SpeciesByIndex(1).(gen3.Species)
+	next := ver.SpeciesByIndex(2)
+	stats := next.BaseStats()
+
+	check := func(name string, err error) {
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+		}
+	}
+	check("SetBaseStats", species.SetBaseStats(pkm.Stats{HitPoints: 1, Attack: 2, Defense: 3, Speed: 4, SpAttack: 5, SpDefense: 6}))
+	check("SetType", species.SetType([2]pkm.Type{pkm.TypeFire, pkm.TypeDragon}))
+	check("SetCatchRate", species.SetCatchRate(3))
+	check("SetExpYield", species.SetExpYield(200))
+	check("SetEffortPoints", species.SetEffortPoints(0x0C03))
+	check("SetHeldItem", species.SetHeldItem([2]pkm.Item{ver.ItemByIndex(13), nil}))
+	check("SetGenderRatio", species.SetGenderRatio(255))
+	check("SetEggCycles", species.SetEggCycles(5))
+	check("SetBaseFriendship", species.SetBaseFriendship(0))
+	check("SetLevelType", species.SetLevelType(pkm.Erratic))
+	check("SetEggGroup", species.SetEggGroup([2]pkm.EggGroup{pkm.EggDragon, pkm.EggDitto}))
+	check("SetAbility", species.SetAbility([2]pkm.Ability{ver.AbilityByIndex(1), ver.AbilityByIndex(2)}))
+	check("SetSafariRate", species.SetSafariRate(7))
+	check("SetFlipped", species.SetFlipped(true))
+	check("SetColor", species.SetColor(pkm.ColorPink))
+
+	if v := species.BaseStats(); v != (pkm.Stats{HitPoints: 1, Attack: 2, Defense: 3, Speed: 4, SpAttack: 5, SpDefense: 6}) {
+		t.Errorf("BaseStats: unexpected result %#v", v)
+	}
+	if v := species.Type(); v != [2]pkm.Type{pkm.TypeFire, pkm.TypeDragon} {
+		t.Errorf("Type: unexpected result %#v", v)
+	}
+	if v := species.CatchRate(); v != 3 {
+		t.Errorf("CatchRate: unexpected result %d", v)
+	}
+	if v := species.ExpYield(); v != 200 {
+		t.Errorf("ExpYield: unexpected result %d", v)
+	}
+	if v := species.EffortPoints(); v != 0x0C03 {
+		t.Errorf("EffortPoints: unexpected result %d", v)
+	}
+	if v := species.HeldItem(); v != [2]pkm.Item{ver.ItemByIndex(13), ver.ItemByIndex(0)} {
+		t.Errorf("HeldItem: unexpected result %#v", v)
+	}
+	if v := species.GenderRatio(); v != 255 {
+		t.Errorf("GenderRatio: unexpected result %d", v)
+	}
+	if v := species.EggCycles(); v != 5 {
+		t.Errorf("EggCycles: unexpected result %d", v)
+	}
+	if v := species.BaseFriendship(); v != 0 {
+		t.Errorf("BaseFriendship: unexpected result %d", v)
+	}
+	if v := species.LevelType(); v != pkm.Erratic {
+		t.Errorf("LevelType: unexpected result %d", v)
+	}
+	if v := species.EggGroup(); v != [2]pkm.EggGroup{pkm.EggDragon, pkm.EggDitto} {
+		t.Errorf("EggGroup: unexpected result %#v", v)
+	}
+	if v := species.Ability(); v != [2]pkm.Ability{ver.AbilityByIndex(1), ver.AbilityByIndex(2)} {
+		t.Errorf("Ability: unexpected result %#v", v)
+	}
+	if v := species.SafariRate(); v != 7 {
+		t.Errorf("SafariRate: unexpected result %d", v)
+	}
+	if v := species.Color(); v != pkm.ColorPink {
+		t.Errorf("Color: unexpected result %s", v)
+	}
+	if v := species.Flipped(); !v {
+		t.Errorf("Flipped: unexpected result %t", v)
+	}
+	if v := next.BaseStats(); v != stats {
+		t.Errorf("BaseStats: neighboring species was modified: %#v", v)
+	}
+
+	var out bytes.Buffer
+	if _, err := ver.(*gen3.Version).WriteTo(&out); err != nil {
+		t.Errorf("WriteTo: unexpected error: %s", err)
+	}
+	if saved, err := gen3.OpenROM(bytes.NewReader(out.Bytes())); err != nil {
+		t.Errorf("WriteTo: failed to open saved ROM: %s", err)
+	} else if v := saved.SpeciesByIndex(1).CatchRate(); v != 3 {
+		t.Errorf("WriteTo: unexpected saved result %d", v)
+	}
+}
+
+func TestDexDataSize(t *testing.T) {
+	for _, c := range []struct {
+		code pkm.GameCode
+		size int
+	}{
+		{gen3.CodeRubyEN, 0x24},
+		{gen3.CodeSapphireEN, 0x24},
+		{gen3.CodeEmeraldEN, 0x20},
+		{gen3.CodeFireRedEN, 0x24},
+		{gen3.CodeLeafGreenEN, 0x24},
+	} {
+		b := make([]byte, 0x1000)
+		copy(b, gba.NewHeader(c.code, "POKEMON").Bytes())
+		ver := openVersion(t, gen3.NewBuffer(b))
+		if v := gen3.DexDataSize(ver); v != c.size {
+			t.Errorf("%s: unexpected pokedex data size 0x%X", c.code, v)
+		}
+	}
+}
+
+func TestWriteInvalidAddress(t *testing.T) {
+	// The tables of Ruby are not known.
+	b := make([]byte, 0x1000)
+	copy(b, gba.NewHeader(gen3.CodeRubyEN, "POKEMON RUBY").Bytes())
+	orig := append([]byte{}, b...)
+	ver, err := gen3.OpenROM(gen3.NewBuffer(b))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+	species := ver.SpeciesByIndex(1).(gen3.Species)
+	if err := species.SetCatchRate(3); !errors.Is(err, gen3.ErrInvalidAddress) {
+		t.Errorf("SetCatchRate: expected ErrInvalidAddress, got %v", err)
+	}
+	if err := ver.(*gen3.Version).SetTypeChart(pkm.NewTypeChart()); !errors.Is(err, gen3.ErrInvalidAddress) {
+		t.Errorf("SetTypeChart: expected ErrInvalidAddress, got %v", err)
+	}
+	if !bytes.Equal(b, orig) {
+		t.Errorf("ROM was modified")
+	}
+}
+
+func TestSetName(t *testing.T) {
+	ver, _ := emptyROM(t, 0x1000000)
+	type namer interface {
+		Name() string
+		SetName(string) error
+	}
+	for _, c := range []struct {
+		v   namer
+		max int
+	}{
+		{ver.SpeciesByIndex(1).(namer), 10},
+		{ver.MoveByIndex(1).(namer), 12},
+		{ver.AbilityByIndex(1).(namer), 12},
+		{ver.ItemByIndex(1).(namer), 13},
+	} {
+		name := strings.Repeat("A", c.max)
+		if err := c.v.SetName(name); err != nil {
+			t.Errorf("SetName: %T: unexpected error: %s", c.v, err)
+		}
+		if v := c.v.Name(); v != name {
+			t.Errorf("SetName: %T: unexpected name %q", c.v, v)
+		}
+		if err := c.v.SetName("Mr. Mime"); err != nil {
+			t.Errorf("SetName: %T: unexpected error: %s", c.v, err)
+		}
+		if v := c.v.Name(); v != "Mr. Mime" {
+			t.Errorf("SetName: %T: name was not padded: %q", c.v, v)
+		}
+		err, ok := c.v.SetName(name + "A").(*gen3.NameError)
+		if !ok || err.Len != c.max+1 || err.Max != c.max {
+			t.Errorf("SetName: %T: unexpected error for long name: %v", c.v, err)
+		}
+		err, ok = c.v.SetName("A~B").(*gen3.NameError)
+		if !ok || err.Char != '~' {
+			t.Errorf("SetName: %T: unexpected error for invalid character: %v", c.v, err)
+		}
+		if v := c.v.Name(); v != "Mr. Mime" {
+			t.Errorf("SetName: %T: rejected name was written: %q", c.v, v)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package gen3
+
+import (
+	"github.com/anaminus/pkm"
+)
+
+var structStarter = makeStruct(
+	2, // 0 Species
+)
+
+// Starters returns the species that can be chosen as a starter.
+func (v *Version) Starters() []pkm.Species {
+	a := make([]pkm.Species, indexSizeStarter)
+	for i := range a {
+		b := readStruct(v.ROM, v.AddrStarters, i, structStarter)
+		a[i] = Species{v: v, i: int(decUint16(b))}
+	}
+	return a
+}
+
+// SetStarter sets the species of the starter at the given index.
+func (v *Version) SetStarter(index int, species pkm.Species) error {
+	if index < 0 || index >= indexSizeStarter {
+		panic("starter index out of bounds")
+	}
+	w, err := v.writer()
+	if err != nil {
+		return err
+	}
+	return writeStruct(w, v.AddrStarters, index, structStarter, encUint16(uint16(species.Index())))
+}
@@ -0,0 +1,76 @@
+package gen3
+
+import (
+	"github.com/anaminus/pkm"
+)
+
+// EncounterSummary describes the chance of encountering a species in an area
+// of a map, combined from every encounter slot containing the species.
+type EncounterSummary struct {
+	// The name of the area. Areas of an EncounterRod are split by rod, and
+	// are named after the rod (e.g. "Old Rod").
+	Area string
+	// The probability (0-1) that traversing a block in the area will lead to
+	// an encounter.
+	EncounterRate float64
+	// The encountered species.
+	Species pkm.Species
+	// The probability (0-1) that an encounter in the area will be the
+	// species.
+	Rate float64
+	// The lowest level at which the species may be encountered.
+	MinLevel int
+	// The highest level at which the species may be encountered.
+	MaxLevel int
+}
+
+// SummarizeEncounters merges the encounter slots of each area of a map into
+// one summary per species. Within an area, summaries are ordered by the first
+// slot in which each species appears.
+func SummarizeEncounters(m pkm.Map) []EncounterSummary {
+	var summaries []EncounterSummary
+	for _, list := range m.Encounters() {
+		if !list.Populated() {
+			continue
+		}
+		rod, isRod := list.(EncounterRod)
+		// Index of a summary in the current area, by area and species.
+		type key struct {
+			area    string
+			species int
+		}
+		index := map[key]int{}
+		for i, e := range list.Encounters() {
+			area := list.Name()
+			if isRod {
+				area = rod.RodType(i).String() + " Rod"
+			}
+			k := key{area: area, species: e.Species().Index()}
+			lo, hi := e.MinLevel(), e.MaxLevel()
+			if hi < lo {
+				lo, hi = hi, lo
+			}
+			if j, ok := index[k]; ok {
+				s := &summaries[j]
+				s.Rate += list.SpeciesRate(i)
+				if lo < s.MinLevel {
+					s.MinLevel = lo
+				}
+				if hi > s.MaxLevel {
+					s.MaxLevel = hi
+				}
+				continue
+			}
+			index[k] = len(summaries)
+			summaries = append(summaries, EncounterSummary{
+				Area:          area,
+				EncounterRate: list.EncounterRate(),
+				Species:       e.Species(),
+				Rate:          list.SpeciesRate(i),
+				MinLevel:      lo,
+				MaxLevel:      hi,
+			})
+		}
+	}
+	return summaries
+}
@@ -0,0 +1,218 @@
+package gen3
+
+import (
+	"github.com/anaminus/pkm"
+)
+
+var (
+	structTrainerData = makeStruct(
+		1,  // 0 Party flags
+		1,  // 1 Trainer class
+		1,  // 2 Encounter music and gender
+		1,  // 3 Trainer picture
+		12, // 4 Name
+		8,  // 5 Items
+		4,  // 6 Double battle
+		4,  // 7 AI flags
+		4,  // 8 Party size
+		4,  // 9 Pointer to party
+	)
+	structTrainerPokemon = makeStruct(
+		2, // 0 IVs
+		2, // 1 Level
+		2, // 2 Species
+		2, // 3 Held item
+	)
+	structTrainerPokemonNoItemMoves = makeStruct(
+		2, // 0 IVs
+		2, // 1 Level
+		2, // 2 Species
+		0, // 3 Held item
+		8, // 4 Moves
+		2, // 5 Padding
+	)
+	structTrainerPokemonMoves = makeStruct(
+		2, // 0 IVs
+		2, // 1 Level
+		2, // 2 Species
+		2, // 3 Held item
+		8, // 4 Moves
+	)
+)
+
+// Party flags of a trainer.
+const (
+	partyCustomMoves = 1 << iota
+	partyHeldItem
+)
+
+// Trainer is an opponent that can be battled.
+type Trainer struct {
+	v *Version
+	i int
+}
+
+func (v *Version) TrainerIndexSize() int {
+	return indexSizeTrainer
+}
+
+func (v *Version) Trainers() []Trainer {
+	a := make([]Trainer, indexSizeTrainer)
+	for i := range a {
+		a[i] = Trainer{v: v, i: i}
+	}
+	return a
+}
+
+func (v *Version) TrainerByIndex(index int) Trainer {
+	if index < 0 || index >= indexSizeTrainer {
+		panic("trainer index out of bounds")
+	}
+	return Trainer{v: v, i: index}
+}
+
+func (t Trainer) Index() int {
+	return t.i
+}
+
+func (t Trainer) Name() string {
+	b := readStruct(
+		t.v.ROM,
+		t.v.AddrTrainerData,
+		t.i,
+		structTrainerData,
+		4,
+	)
+	return decodeTextString(b)
+}
+
+// Party returns the pokemon used by the trainer.
+func (t Trainer) Party() []TrainerPokemon {
+	b := readStruct(
+		t.v.ROM,
+		t.v.AddrTrainerData,
+		t.i,
+		structTrainerData,
+		0, 8, 9,
+	)
+	p := decPtr(b[5:9])
+	if !p.ValidROM() {
+		return nil
+	}
+	party := make([]TrainerPokemon, b[1])
+	for i := range party {
+		party[i] = TrainerPokemon{v: t.v, p: p, i: i, flags: b[0]}
+	}
+	return party
+}
+
+////////////////////////////////////////////////////////////////
+
+// TrainerPokemon is a member of a trainer's party.
+type TrainerPokemon struct {
+	v     *Version
+	p     ptr
+	i     int
+	flags byte
+}
+
+// Returns the layout of the party, which depends on its flags. A party
+// without custom moves has the same layout with or without held items.
+func (m TrainerPokemon) stct() stct {
+	switch {
+	case m.flags&partyCustomMoves == 0:
+		return structTrainerPokemon
+	case m.flags&partyHeldItem == 0:
+		return structTrainerPokemonNoItemMoves
+	}
+	return structTrainerPokemonMoves
+}
+
+func (m TrainerPokemon) Level() int {
+	b := readStruct(m.v.ROM, m.p, m.i, m.stct(), 1)
+	return int(decUint16(b))
+}
+
+func (m TrainerPokemon) Species() pkm.Species {
+	b := readStruct(m.v.ROM, m.p, m.i, m.stct(), 2)
+	return Species{v: m.v, i: int(decUint16(b))}
+}
+
+// HeldItem returns the item held by the pokemon, or nil if the party does not
+// have held items.
+func (m TrainerPokemon) HeldItem() pkm.Item {
+	if m.flags&partyHeldItem == 0 {
+		return nil
+	}
+	b := readStruct(m.v.ROM, m.p, m.i, m.stct(), 3)
+	return Item{v: m.v, i: int(decUint16(b))}
+}
+
+// Moves returns the moves of the pokemon, or nil if the party does not have
+// custom moves. Unused slots are nil.
+func (m TrainerPokemon) Moves() []pkm.Move {
+	if m.flags&partyCustomMoves == 0 {
+		return nil
+	}
+	b := readStruct(m.v.ROM, m.p, m.i, m.stct(), 4)
+	moves := make([]pkm.Move, 4)
+	for i := range moves {
+		if idx := int(decUint16(b[i*2:])); idx != 0 {
+			moves[i] = Move{v: m.v, i: idx}
+		}
+	}
+	return moves
+}
+
+func (m TrainerPokemon) setData(b []byte, field int) error {
+	w, err := m.v.writer()
+	if err != nil {
+		return err
+	}
+	return writeStruct(w, m.p, m.i, m.stct(), b, field)
+}
+
+func (m TrainerPokemon) SetLevel(level int) error {
+	if err := checkLevel(level); err != nil {
+		return err
+	}
+	return m.setData(encUint16(uint16(level)), 1)
+}
+
+func (m TrainerPokemon) SetSpecies(species pkm.Species) error {
+	return m.setData(encUint16(uint16(species.Index())), 2)
+}
+
+// SetHeldItem sets the item held by the pokemon. A nil item removes the held
+// item. Has no effect if the party does not have held items.
+func (m TrainerPokemon) SetHeldItem(item pkm.Item) error {
+	if m.flags&partyHeldItem == 0 {
+		return nil
+	}
+	var b []byte
+	if item == nil {
+		b = encUint16(0)
+	} else {
+		b = encUint16(uint16(item.Index()))
+	}
+	return m.setData(b, 3)
+}
+
+// SetMoves sets the moves of the pokemon. Up to 4 moves are written, with
+// remaining slots left empty. Has no effect if the party does not have custom
+// moves.
+func (m TrainerPokemon) SetMoves(moves []pkm.Move) error {
+	if m.flags&partyCustomMoves == 0 {
+		return nil
+	}
+	b := make([]byte, 8)
+	for i, move := range moves {
+		if i >= 4 {
+			break
+		}
+		if move != nil {
+			copy(b[i*2:], encUint16(uint16(move.Index())))
+		}
+	}
+	return m.setData(b, 4)
+}
@@ -0,0 +1,173 @@
+package gen3_test
+
+import (
+	"github.com/anaminus/pkm/gen3"
+	"github.com/anaminus/pkm/gen3/romtest"
+	"testing"
+)
+
+func TestTrainer(t *testing.T) {
+	ver := openVersion(t, ROM(t))
+	if v := len(ver.Trainers()); v != ver.TrainerIndexSize() {
+		t.Errorf("Trainers: unexpected length %d", v)
+	}
+	trainer := ver.TrainerByIndex(1)
+	if v := trainer.Name(); v != "SAWYER" {
+		t.Errorf("Trainer.Name: unexpected result %q", v)
+	}
+	party := trainer.Party()
+	if len(party) == 0 {
+		t.Fatalf("Trainer.Party: expected party")
+	}
+	for i, p := range party {
+		if v := p.Level(); v < 1 || v > 100 {
+			t.Errorf("TrainerPokemon.Level: %d: unexpected result %d", i, v)
+		}
+		if v := p.Species().Index(); v == 0 {
+			t.Errorf("TrainerPokemon.Species: %d: unexpected empty species", i)
+		}
+	}
+	if err := party[0].SetLevel(10); err != gen3.ErrReadOnly {
+		t.Errorf("TrainerPokemon.SetLevel: expected ErrReadOnly, got %v", err)
+	}
+
+	buf, err := gen3.ReadBuffer(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to read ROM: %s", err)
+	}
+	ver = openVersion(t, buf)
+	p := ver.TrainerByIndex(1).Party()[0]
+	if err := p.SetSpecies(ver.SpeciesByIndex(25)); err != nil {
+		t.Errorf("TrainerPokemon.SetSpecies: unexpected error: %s", err)
+	}
+	if err := p.SetLevel(42); err != nil {
+		t.Errorf("TrainerPokemon.SetLevel: unexpected error: %s", err)
+	}
+	p = ver.TrainerByIndex(1).Party()[0]
+	if p.Species().Index() != 25 || p.Level() != 42 {
+		t.Errorf("TrainerPokemon: unexpected result %d, %d", p.Species().Index(), p.Level())
+	}
+}
+
+func TestTrainerSynthetic(t *testing.T) {
+	ver := openVersion(t, SyntheticROM())
+	if v := len(ver.Trainers()); v != ver.TrainerIndexSize() {
+		t.Errorf("Trainers: unexpected length %d", v)
+	}
+	trainer := ver.TrainerByIndex(1)
+	if v := trainer.Name(); v != romtest.TrainerName(1) {
+		t.Errorf("Trainer.Name: unexpected result %q", v)
+	}
+	party := trainer.Party()
+	if len(party) == 0 {
+		t.Fatalf("Trainer.Party: expected party")
+	}
+	for i, p := range party {
+		if v := p.Level(); v < 1 || v > 100 {
+			t.Errorf("TrainerPokemon.Level: %d: unexpected result %d", i, v)
+		}
+		if v := p.Species().Index(); v == 0 {
+			t.Errorf("TrainerPokemon.Species: %d: unexpected empty species", i)
+		}
+	}
+	if err := party[0].SetLevel(10); err != gen3.ErrReadOnly {
+		t.Errorf("TrainerPokemon.SetLevel: expected ErrReadOnly, got %v", err)
+	}
+
+	buf, err := gen3.ReadBuffer(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to read ROM: %s", err)
+	}
+	ver = openVersion(t, buf)
+	p := ver.TrainerByIndex(1).Party()[0]
+	if err := p.SetSpecies(ver.SpeciesByIndex(25)); err != nil {
+		t.Errorf("TrainerPokemon.SetSpecies: unexpected error: %s", err)
+	}
+	if err := p.SetLevel(42); err != nil {
+		t.Errorf("TrainerPokemon.SetLevel: unexpected error: %s", err)
+	}
+	p = ver.TrainerByIndex(1).Party()[0]
+	if p.Species().Index() != 25 || p.Level() != 42 {
+		t.Errorf("TrainerPokemon: unexpected result %d, %d", p.Species().Index(), p.Level())
+	}
+}
+
+func TestStarters(t *testing.T) {
+	buf, err := gen3.ReadBuffer(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to read ROM: %s", err)
+	}
+	ver := openVersion(t, buf)
+	for i, index := range []int{277, 280, 283} {
+		if v := ver.Starters()[i].Index(); v != index {
+			t.Errorf("Starters: %d: unexpected species %d", i, v)
+		}
+	}
+	if err := ver.SetStarter(1, ver.SpeciesByIndex(25)); err != nil {
+		t.Errorf("SetStarter: unexpected error: %s", err)
+	}
+	if v := ver.Starters()[1].Index(); v != 25 {
+		t.Errorf("SetStarter: unexpected species %d", v)
+	}
+}
+
+func TestStartersSynthetic(t *testing.T) {
+	buf, err := gen3.ReadBuffer(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to read ROM: %s", err)
+	}
+	ver := openVersion(t, buf)
+	for i, index := range romtest.Starters {
+		if v := ver.Starters()[i].Index(); v != index {
+			t.Errorf("Starters: %d: unexpected species %d", i, v)
+		}
+	}
+	if err := ver.SetStarter(1, ver.SpeciesByIndex(25)); err != nil {
+		t.Errorf("SetStarter: unexpected error: %s", err)
+	}
+	if v := ver.Starters()[1].Index(); v != 25 {
+		t.Errorf("SetStarter: unexpected species %d", v)
+	}
+}
+
+func TestFieldItems(t *testing.T) {
+	ver := openVersion(t, ROM(t))
+	ver.ScanBanks()
+	var visible, hidden int
+	for _, m := range ver.AllMaps() {
+		for _, f := range m.(gen3.Map).FieldItems() {
+			if f.Hidden() {
+				hidden++
+			} else {
+				visible++
+			}
+			if i := f.Item().Index(); i == 0 || i >= ver.ItemIndexSize() {
+				t.Errorf("FieldItems: %s: unexpected item %d", m.Name(), i)
+			}
+		}
+	}
+	if visible == 0 || hidden == 0 {
+		t.Errorf("FieldItems: unexpected counts %d, %d", visible, hidden)
+	}
+}
+
+func TestFieldItemsSynthetic(t *testing.T) {
+	ver := openVersion(t, SyntheticROM())
+	ver.ScanBanks()
+	var visible, hidden int
+	for _, m := range ver.AllMaps() {
+		for _, f := range m.(gen3.Map).FieldItems() {
+			if f.Hidden() {
+				hidden++
+			} else {
+				visible++
+			}
+			if i := f.Item().Index(); i == 0 || i >= ver.ItemIndexSize() {
+				t.Errorf("FieldItems: %s: unexpected item %d", m.Name(), i)
+			}
+		}
+	}
+	if visible == 0 || hidden == 0 {
+		t.Errorf("FieldItems: unexpected counts %d, %d", visible, hidden)
+	}
+}
@@ -1,17 +1,32 @@
 package gen3
 
 import (
+	"bytes"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"github.com/anaminus/pkm"
 	"io"
 )
 
 const addrROM = 0x08000000
+
+// Maximum size of a ROM.
+const sizeROM = 0x02000000
 const addrGameCode ptr = 0x080000AC
 const strTerm = 0xFF
 
 var defaultCodec = CodecUTF8
 
+// ErrReadOnly is returned when attempting to modify a Version whose ROM does
+// not implement io.WriterAt.
+var ErrReadOnly = errors.New("ROM is read-only")
+
+// ErrInvalidAddress is returned when attempting to write to a table whose
+// address is not within the ROM, such as a table that is not known for a
+// version.
+var ErrInvalidAddress = errors.New("address is not within the ROM")
+
 var structPtr = makeStruct(
 	4, // Pointer
 )
@@ -31,6 +46,21 @@ func readTextString(r io.Reader) string {
 	return s
 }
 
+// Returns the size of the encoded text read from a Reader, including the
+// string terminator.
+func textSize(r io.Reader) int {
+	q := make([]byte, 1)
+	for n := 0; ; {
+		if _, err := r.Read(q); err != nil {
+			return n
+		}
+		n++
+		if q[0] == strTerm {
+			return n
+		}
+	}
+}
+
 // Truncate text to string terminator.
 func truncateText(b []byte) []byte {
 	for i, c := range b {
@@ -53,6 +83,48 @@ func encodeText(s string) []byte {
 	return b
 }
 
+// NameError is returned when a name cannot be written to a fixed-width name
+// field.
+type NameError struct {
+	// The rejected name.
+	Name string
+	// The first character of the name that cannot be encoded, or 0 if every
+	// character can be encoded.
+	Char rune
+	// The length of the encoded name.
+	Len int
+	// The maximum length of an encoded name, excluding the terminator.
+	Max int
+}
+
+func (err *NameError) Error() string {
+	if err.Char != 0 {
+		return fmt.Sprintf("name %q contains character %q, which cannot be encoded", err.Name, err.Char)
+	}
+	return fmt.Sprintf("name %q is %d characters long when encoded, exceeding the maximum of %d", err.Name, err.Len, err.Max)
+}
+
+// Encode a name using default encoding, padded with string terminators to
+// fill a field of the given size. The field must include at least one
+// terminator.
+func encodeName(name string, size int) ([]byte, error) {
+	for _, r := range name {
+		if b := encodeText(string(r)); len(b) == 0 || bytes.IndexByte(b, strTerm) >= 0 {
+			return nil, &NameError{Name: name, Char: r, Max: size - 1}
+		}
+	}
+	enc := encodeText(name)
+	if len(enc) > size-1 {
+		return nil, &NameError{Name: name, Len: len(enc), Max: size - 1}
+	}
+	b := make([]byte, size)
+	copy(b, enc)
+	for i := len(enc); i < size; i++ {
+		b[i] = strTerm
+	}
+	return b, nil
+}
+
 func decUint16(b []byte) uint16 {
 	return binary.LittleEndian.Uint16(b)
 }
@@ -65,49 +137,12 @@ func decUint64(b []byte) uint64 {
 	return binary.LittleEndian.Uint64(b)
 }
 
-func readLZ77(r io.Reader) ([]byte, bool) {
-	q := make([]byte, 4)
-	r.Read(q[:1])
-	if q[0] != 0x10 {
-		return nil, false
-	}
-	r.Read(q[:3])
-	q[3] = 0
-	size := int(binary.LittleEndian.Uint32(q))
-	output := make([]byte, size)
-	p := 0
-	for p < size {
-		r.Read(q[:1])
-		flags := q[0]
-		for i := 0; i < 8; i++ {
-			if flags&(0x80>>uint(i)) == 0 {
-				r.Read(q[:1])
-				output[p] = q[0]
-				p++
-			} else {
-				r.Read(q[:2])
-				b := binary.LittleEndian.Uint16(q[:2])
-				c := int(((b >> 4) & 0xF) + 3)
-				n := p - int(((b&0xF)<<8)|((b>>8)&0xFF)) - 1
-				for i := 0; i < c; i++ {
-					output[p+i] = output[n+i]
-				}
-				p += c
-			}
-			if p >= size {
-				break
-			}
-		}
-	}
-	return output, true
-}
-
 ////////////////////////////////////////////////////////////////
 
 type ptr uint32
 
 func (p ptr) ValidROM() bool {
-	return addrROM <= p && p < addrROM+0x01000000
+	return addrROM <= p && p < addrROM+sizeROM
 }
 
 func (p ptr) ROM() int64 {
@@ -156,7 +191,17 @@ func (s stct) FieldOffset(f int) int {
 	return s[f]
 }
 
-func readStruct(r io.ReadSeeker, addr ptr, index int, s stct, fields ...int) []byte {
+// Reads the given fields of a struct. Accessors have no way to report an
+// error, so an error from the ROM is discarded, leaving the unread bytes
+// zeroed.
+func readStruct(r io.ReaderAt, addr ptr, index int, s stct, fields ...int) []byte {
+	b, _ := readStructErr(r, addr, index, s, fields...)
+	return b
+}
+
+// Reads the given fields of a struct, returning the first error from the ROM.
+// The bytes of the fields are returned even if an error occurs.
+func readStructErr(r io.ReaderAt, addr ptr, index int, s stct, fields ...int) (b []byte, err error) {
 	if len(fields) == 0 {
 		fields = make([]int, s.Len())
 		for i := range fields {
@@ -164,19 +209,67 @@ func readStruct(r io.ReadSeeker, addr ptr, index int, s stct, fields ...int) []b
 		}
 	}
 
-	off, _ := r.Seek(addr.ROM()+int64(index*s.Size()), 0)
+	off := addr.ROM() + int64(index*s.Size())
 
 	n := 0
 	for _, f := range fields {
 		n += s.FieldSize(f)
 	}
 
-	b := make([]byte, n)
+	b = make([]byte, n)
 	n = 0
 	for _, f := range fields {
-		r.Seek(off+int64(s.FieldOffset(f)), 0)
-		r.Read(b[n : n+s.FieldSize(f)])
+		size := s.FieldSize(f)
+		m, rerr := r.ReadAt(b[n:n+size], off+int64(s.FieldOffset(f)))
+		if rerr != nil && m < size && err == nil {
+			err = fmt.Errorf("read 0x%08X: %w", uint32(addrROM+off+int64(s.FieldOffset(f))), rerr)
+		}
+		n += size
+	}
+	return b, err
+}
+
+// Write the given fields of a struct. b contains the content of each field,
+// in the order given.
+func writeStruct(w io.WriterAt, addr ptr, index int, s stct, b []byte, fields ...int) error {
+	if !addr.ValidROM() {
+		return fmt.Errorf("%w: 0x%08X", ErrInvalidAddress, uint32(addr))
+	}
+	if len(fields) == 0 {
+		fields = make([]int, s.Len())
+		for i := range fields {
+			fields[i] = i
+		}
+	}
+
+	off := addr.ROM() + int64(index*s.Size())
+	n := 0
+	for _, f := range fields {
+		if _, err := w.WriteAt(b[n:n+s.FieldSize(f)], off+int64(s.FieldOffset(f))); err != nil {
+			return err
+		}
 		n += s.FieldSize(f)
 	}
+	return nil
+}
+
+// Write a slice of bytes to a given address.
+func writeBytes(w io.WriterAt, addr ptr, b []byte) error {
+	if !addr.ValidROM() {
+		return fmt.Errorf("%w: 0x%08X", ErrInvalidAddress, uint32(addr))
+	}
+	_, err := w.WriteAt(b, addr.ROM())
+	return err
+}
+
+func encUint16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, v)
+	return b
+}
+
+func encUint32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
 	return b
 }
@@ -0,0 +1,248 @@
+package gen3
+
+import (
+	"fmt"
+)
+
+// TableError indicates that a table of the ROM is not located within the ROM.
+type TableError struct {
+	Table string // Name of the table.
+	Addr  uint32 // Address of the table.
+	Size  int    // Size of the table, in bytes.
+	// Size of the ROM. If zero, then the address of the table is invalid.
+	ROMSize int64
+}
+
+func (err TableError) Error() string {
+	if err.ROMSize == 0 {
+		return fmt.Sprintf("%s table address 0x%08X invalid", err.Table, err.Addr)
+	}
+	return fmt.Sprintf("%s table at 0x%08X (%d bytes) exceeds ROM size %d", err.Table, err.Addr, err.Size, err.ROMSize)
+}
+
+// PointerError indicates that a pointer within a table does not point to a
+// location within the ROM.
+type PointerError struct {
+	Table string // Name of the table.
+	Index int    // Index of the entry containing the pointer.
+	Field string // Name of the pointer.
+	Ptr   uint32 // Value of the pointer.
+}
+
+func (err PointerError) Error() string {
+	return fmt.Sprintf("%s %d %s pointer 0x%08X invalid", err.Table, err.Index, err.Field, err.Ptr)
+}
+
+// Validate checks the ROM for structural problems, such as a ROM that has
+// been truncated, or tables containing pointers that do not point into the
+// ROM. The maps reachable from the bank table are checked, along with their
+// layouts, tilesets, events and connections. Each problem found is returned as
+// an error, which will usually be a TableError or a PointerError. An error
+// from reading the ROM is also returned. Returns nil if no problems were found.
+//
+// Accessors do not report such problems themselves, instead returning zeroed
+// or garbage data, so Validate should be used to check a ROM from an untrusted
+// source before it is read.
+func (v *Version) Validate() (errs []error) {
+	size, err := v.romSize()
+	if err != nil {
+		errs = append(errs, err)
+		size = sizeROM
+	}
+
+	tables := []struct {
+		name string
+		addr ptr
+		s    stct
+		n    int
+	}{
+		{"species name", v.AddrSpeciesName, structSpeciesName, indexSizeSpecies},
+		{"species data", v.AddrSpeciesData, structSpeciesData, indexSizeSpecies},
+		{"species evolution", v.AddrSpeciesEvo, structEvolution, indexSizeSpecies},
+		{"species TM", v.AddrSpeciesTM, structSpeciesTM, indexSizeSpecies},
+		{"learned move pointer", v.AddrLevelMovePtr, structPtr, indexSizeSpecies},
+		{"pokedex data", v.AddrPokedexData, v.dexData, v.pokedex[0].Size + 1},
+		{"move name", v.AddrMoveName, structMoveName, indexSizeMove},
+		{"move data", v.AddrMoveData, structMoveData, indexSizeMove},
+		{"move description pointer", v.AddrMoveDescPtr, structPtr, indexSizeMove - 1},
+		{"ability name", v.AddrAbilityName, structAbilityName, indexSizeAbility},
+		{"ability description pointer", v.AddrAbilityDescPtr, structPtr, indexSizeAbility},
+		{"item data", v.AddrItemData, structItemData, indexSizeItem},
+		{"TM move", v.AddrTMMove, structTMMove, indexSizeTM},
+		{"map label", v.AddrMapLabel, structMapLabel, indexSizeMapName},
+		{"trainer data", v.AddrTrainerData, structTrainerData, indexSizeTrainer},
+		{"starter", v.AddrStarters, structStarter, indexSizeStarter},
+	}
+	for _, p := range v.pokedex {
+		tables = append(tables, struct {
+			name string
+			addr ptr
+			s    stct
+			n    int
+		}{p.Name + " pokedex", p.Address, structDex, indexSizeSpecies - 1})
+	}
+	valid := map[string]bool{}
+	for _, t := range tables {
+		n := t.s.Size() * t.n
+		if !t.addr.ValidROM() {
+			errs = append(errs, TableError{Table: t.name, Addr: uint32(t.addr), Size: n})
+			continue
+		}
+		if t.addr.ROM()+int64(n) > size {
+			errs = append(errs, TableError{Table: t.name, Addr: uint32(t.addr), Size: n, ROMSize: size})
+			continue
+		}
+		valid[t.name] = true
+	}
+
+	// Checks that a pointer points into the ROM. Reports whether the pointer
+	// is valid.
+	check := func(table string, index int, field string, b []byte) bool {
+		if p := decPtr(b); !p.ValidROM() || p.ROM() >= size {
+			errs = append(errs, PointerError{Table: table, Index: index, Field: field, Ptr: uint32(p)})
+			return false
+		}
+		return true
+	}
+
+	// Reads a struct, reporting the first error from the ROM.
+	var readErr error
+	read := func(addr ptr, index int, s stct, fields ...int) []byte {
+		b, err := readStructErr(v.ROM, addr, index, s, fields...)
+		if err != nil && readErr == nil {
+			readErr = err
+			errs = append(errs, err)
+		}
+		return b
+	}
+
+	if valid["pokedex data"] && valid["National pokedex"] {
+		for i := 1; i < indexSizeSpecies; i++ {
+			n := v.speciesNumber(Species{v: v, i: i})
+			b := read(v.AddrPokedexData, n, v.dexData, 3, 4)
+			check("species", i, "description", b[0:4])
+			if gc := v.GameCode(); gc == CodeRubyEN || gc == CodeSapphireEN {
+				check("species", i, "second description", b[4:8])
+			}
+		}
+	}
+	if valid["learned move pointer"] {
+		for i := 0; i < indexSizeSpecies; i++ {
+			check("species", i, "learned move", read(v.AddrLevelMovePtr, i, structPtr))
+		}
+	}
+	if valid["move description pointer"] {
+		for i := 1; i < indexSizeMove; i++ {
+			check("move", i, "description", read(v.AddrMoveDescPtr, i-1, structPtr))
+		}
+	}
+	if valid["ability description pointer"] {
+		for i := 0; i < indexSizeAbility; i++ {
+			check("ability", i, "description", read(v.AddrAbilityDescPtr, i, structPtr))
+		}
+	}
+	if valid["item data"] {
+		for i := 0; i < indexSizeItem; i++ {
+			check("item", i, "description", read(v.AddrItemData, i, structItemData, 5))
+		}
+	}
+	if valid["map label"] {
+		for i := 0; i < indexSizeMapName; i++ {
+			check("map label", i, "name", read(v.AddrMapLabel, i, structMapLabel, 4))
+		}
+	}
+	if valid["trainer data"] {
+		for i := 0; i < indexSizeTrainer; i++ {
+			b := read(v.AddrTrainerData, i, structTrainerData, 8, 9)
+			// A trainer without a party may have a null pointer.
+			if decUint32(b[0:4]) > 0 {
+				check("trainer", i, "party", b[4:8])
+			}
+		}
+	}
+
+	if !v.AddrBanksPtr.ValidROM() {
+		errs = append(errs, TableError{Table: "bank pointer", Addr: uint32(v.AddrBanksPtr), Size: structPtr.Size()})
+	} else if b := read(v.AddrBanksPtr, 0, structPtr); check("bank pointer", 0, "bank table", b) {
+		// Maps are located as by ScanBanks.
+		banks := decPtr(b)
+		v.ScanBanks()
+		tilesets := map[ptr]bool{}
+		for bank := 0; bank < v.BankIndexSize(); bank++ {
+			b := read(banks, bank, structPtr)
+			if !check("bank", bank, "map table", b) {
+				continue
+			}
+			maps := decPtr(b)
+			table := fmt.Sprintf("bank %d map", bank)
+			for i := 0; i < v.sizeMapTable[bank]; i++ {
+				b := read(maps, i, structPtr)
+				if !check(table, i, "header", b) {
+					continue
+				}
+				header := read(decPtr(b), 0, structMapHeader, 0, 1, 2, 3)
+				check(table, i, "event", header[4:8])
+				check(table, i, "script", header[8:12])
+				// Maps without connections have a null pointer.
+				if decPtr(header[12:16]) != 0 && check(table, i, "connection", header[12:16]) {
+					b := read(decPtr(header[12:16]), 0, structConnHeader)
+					if decUint32(b[0:4]) > 0 {
+						check(table, i, "connection data", b[4:8])
+					}
+				}
+				if !check(table, i, "layout", header[0:4]) {
+					continue
+				}
+				layout := read(decPtr(header[0:4]), 0, structMapLayoutData, 2, 3, 4, 5)
+				check(table, i, "border", layout[0:4])
+				check(table, i, "tile", layout[4:8])
+				for t, name := range [2]string{"primary tileset", "secondary tileset"} {
+					field := layout[8+t*4 : 12+t*4]
+					p := decPtr(field)
+					// Tilesets are shared between maps, so each is checked once.
+					if tilesets[p] {
+						continue
+					}
+					tilesets[p] = true
+					if !check(table, i, name, field) {
+						continue
+					}
+					ts := read(p, 0, structTilesetHeader, 3, 4, 5, 6, 7)
+					check(table, i, name+" image", ts[0:4])
+					check(table, i, name+" palette", ts[4:8])
+					check(table, i, name+" block", ts[8:12])
+					// A tileset without an animation has a null routine
+					// pointer. The order of the routine and behavior pointers
+					// differs between versions, so either may be null.
+					if decPtr(ts[12:16]) != 0 {
+						check(table, i, name+" animation", ts[12:16])
+					}
+					if decPtr(ts[16:20]) != 0 {
+						check(table, i, name+" behavior", ts[16:20])
+					}
+				}
+			}
+		}
+	}
+
+	if list := v.encounterList(); !list.ValidROM() {
+		errs = append(errs, TableError{Table: "encounter list", Addr: uint32(list), Size: structEncounterPtrs.Size()})
+	} else {
+		areas := [4]string{"grass", "water", "rock", "rod"}
+		for i := 0; int64(list.ROM())+int64((i+1)*structEncounterPtrs.Size()) <= size; i++ {
+			b := read(list, i, structEncounterPtrs)
+			if b[0] == 0xFF && b[1] == 0xFF {
+				break
+			}
+			for a, area := range areas {
+				// Areas without encounters have null pointers.
+				p := b[4+a*4 : 8+a*4]
+				if decPtr(p) == 0 {
+					continue
+				}
+				check("encounter list", i, area+" encounter table", p)
+			}
+		}
+	}
+	return errs
+}
@@ -2,10 +2,13 @@ package gen3
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/anaminus/pkm"
+	"github.com/anaminus/pkm/gba"
 	"io"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 ////////////////////////////////////////////////////////////////
@@ -18,15 +21,31 @@ var (
 	)
 )
 
+const (
+	// Marks the end of the type effectiveness list.
+	typeEffectTerm = 0xFF
+	// Separates entries that are ignored when a defender is identified by
+	// Foresight.
+	typeEffectForesight = 0xFE
+)
+
 type Version struct {
-	ROM                io.ReadSeeker
+	ROM                io.ReaderAt
 	name               string
 	pokedex            []pokedexData
+	dexData            stct      // Structure of the pokedex data table.
+	dexMaps            []*dexMap // Parallel to pokedex.
+	typeChart          *typeChartCache
+	scan               *sync.Mutex // Guards sizeMapTable.
 	sizeMapTable       []int
+	free               *FreeSpace
+	cache              *tableCache
+	encounterOnce      *sync.Once
+	encounterPtr       ptr // Code pointer to the encounter list, or 0.
 	AddrAbilityName    ptr // Table of ability names.
 	AddrAbilityDescPtr ptr // Table of pointers to ability descriptions.
 	AddrBanksPtr       ptr // Pointer to bank pointer table.
-	AddrEncounterList  ptr // List of map references to encounter table pointers.
+	AddrEncounterList  ptr // Original list of map references to encounter table pointers.
 	AddrItemData       ptr // Table of item data.
 	AddrLevelMovePtr   ptr // Table of pointers to learned-move data.
 	AddrMapLabel       ptr // Table of map label data.
@@ -38,19 +57,72 @@ type Version struct {
 	AddrSpeciesEvo     ptr // Table of species evolution data.
 	AddrSpeciesName    ptr // Table of species names.
 	AddrSpeciesTM      ptr // Table of species TM compatibility.
+	AddrStarters       ptr // Table of starter species.
 	AddrTypeEffect     ptr // List of type effectiveness.
 	AddrTMMove         ptr // Table of TM move mappings.
+	AddrTrainerData    ptr // Table of trainer data.
 }
 
 var _ = pkm.Version(&Version{})
 
+// Returns the ROM as a writer. Returns ErrReadOnly if the ROM cannot be
+// written to.
+func (v *Version) writer() (io.WriterAt, error) {
+	if w, ok := v.ROM.(io.WriterAt); ok {
+		return headerWriter{v: v, w: w}, nil
+	}
+	return nil, ErrReadOnly
+}
+
+// Writes to the ROM of a Version, fixing the complement check of the GBA
+// header after each write that changes the header. Each write discards the
+// type chart decoded by the Version, and the tables read by a cached Version.
+type headerWriter struct {
+	v *Version
+	w io.WriterAt
+}
+
+func (h headerWriter) WriteAt(p []byte, off int64) (n int, err error) {
+	n, err = h.w.WriteAt(p, off)
+	h.v.typeChart.reset()
+	if h.v.cache != nil {
+		h.v.cache.reset()
+	}
+	if err != nil {
+		return n, err
+	}
+	if off < gba.HeaderSize {
+		err = gba.FixComplement(struct {
+			io.ReaderAt
+			io.WriterAt
+		}{h.v.ROM, h.w})
+	}
+	return n, err
+}
+
+// Returns a reader that reads sequentially from the ROM, starting at a given
+// offset. Each reader has its own position, so separate readers may be used
+// concurrently.
+func (v *Version) reader(off int64) *io.SectionReader {
+	return io.NewSectionReader(v.ROM, off, sizeROM-off)
+}
+
+// WriteTo writes the entire contents of the ROM to w. This can be used to
+// save the changes made to a writable Version.
+func (v *Version) WriteTo(w io.Writer) (n int64, err error) {
+	size, err := v.romSize()
+	if err != nil {
+		return 0, err
+	}
+	return io.Copy(w, io.NewSectionReader(v.ROM, 0, size))
+}
+
 func (v *Version) Name() string {
 	return v.name
 }
 
 func (v *Version) GameCode() (gc pkm.GameCode) {
-	v.ROM.Seek(addrGameCode.ROM(), 0)
-	v.ROM.Read(gc[:])
+	v.ROM.ReadAt(gc[:], addrGameCode.ROM())
 	return
 }
 
@@ -84,9 +156,9 @@ func (v *Version) SpeciesByIndex(index int) pkm.Species {
 func (v *Version) SpeciesByName(name string) pkm.Species {
 	encName := encodeText(strings.ToUpper(name))
 	b := make([]byte, structSpeciesName.Size())
-	v.ROM.Seek(v.AddrSpeciesName.ROM(), 0)
+	r := io.NewSectionReader(v.table(tableSpeciesName), v.AddrSpeciesName.ROM(), int64(structSpeciesName.Size()*indexSizeSpecies))
 	for i := 0; i < indexSizeSpecies; i++ {
-		v.ROM.Read(b)
+		r.Read(b)
 		if bytes.Equal(encName, truncateText(b)) {
 			return Species{v: v, i: i}
 		}
@@ -102,11 +174,13 @@ func (v *Version) Pokedex() []pkm.Pokedex {
 	return a
 }
 
+// PokedexByName returns the pokedex of the given name. The regional pokedex
+// may also be found by the name "Standard".
 func (v *Version) PokedexByName(name string) pkm.Pokedex {
 	name = strings.ToUpper(name)
-	for _, dex := range v.Pokedex() {
-		if strings.ToUpper(dex.Name()) == name {
-			return dex
+	for i, dex := range v.pokedex {
+		if strings.ToUpper(dex.Name) == name || dex.Alias != "" && strings.ToUpper(dex.Alias) == name {
+			return Pokedex{v: v, i: i}
 		}
 	}
 	return nil
@@ -135,7 +209,7 @@ func (v *Version) ItemByName(name string) pkm.Item {
 	encName := encodeText(strings.ToUpper(name))
 	for i := 0; i < indexSizeSpecies; i++ {
 		b := readStruct(
-			v.ROM,
+			v.table(tableItemData),
 			v.AddrItemData,
 			i,
 			structItemData,
@@ -170,9 +244,9 @@ func (v *Version) AbilityByIndex(index int) pkm.Ability {
 func (v *Version) AbilityByName(name string) pkm.Ability {
 	encName := encodeText(strings.ToUpper(name))
 	b := make([]byte, structAbilityName.Size())
-	v.ROM.Seek(v.AddrAbilityName.ROM(), 0)
+	r := v.reader(v.AddrAbilityName.ROM())
 	for i := 0; i < indexSizeAbility; i++ {
-		v.ROM.Read(b)
+		r.Read(b)
 		if bytes.Equal(encName, truncateText(b)) {
 			return Ability{v: v, i: i}
 		}
@@ -202,9 +276,9 @@ func (v *Version) MoveByIndex(index int) pkm.Move {
 func (v *Version) MoveByName(name string) pkm.Move {
 	encName := encodeText(strings.ToUpper(name))
 	b := make([]byte, structMoveName.Size())
-	v.ROM.Seek(v.AddrMoveName.ROM(), 0)
+	r := io.NewSectionReader(v.table(tableMoveName), v.AddrMoveName.ROM(), int64(structMoveName.Size()*indexSizeMove))
 	for i := 0; i < indexSizeMove; i++ {
-		v.ROM.Read(b)
+		r.Read(b)
 		if bytes.Equal(encName, truncateText(b)) {
 			return Move{v: v, i: i}
 		}
@@ -255,10 +329,9 @@ func (v *Version) TMByName(name string) pkm.TM {
 	return TM{v: v, i: int(n) + off}
 }
 
-func validMapHeader(rom io.ReadSeeker, p ptr) bool {
+func validMapHeader(rom io.ReaderAt, p ptr) bool {
 	maph := make([]byte, structMapHeader.Size())
-	rom.Seek(p.ROM(), 0)
-	rom.Read(maph)
+	rom.ReadAt(maph, p.ROM())
 	if p := decPtr(maph[0:4]); !p.ValidROM() {
 		return false
 	}
@@ -272,7 +345,9 @@ func validMapHeader(rom io.ReadSeeker, p ptr) bool {
 }
 
 func (v *Version) ScanBanks() {
-	if len(v.sizeMapTable) > 0 {
+	v.scan.Lock()
+	defer v.scan.Unlock()
+	if v.sizeMapTable != nil {
 		return
 	}
 
@@ -281,9 +356,7 @@ func (v *Version) ScanBanks() {
 	// Find size of bank pointer table.
 	size := 256
 	banks := make([]byte, 256*ps)
-	v.ROM.Seek(v.AddrBanksPtr.ROM(), 0)
-	v.ROM.Seek(readPtr(v.ROM).ROM(), 0)
-	v.ROM.Read(banks)
+	v.ROM.ReadAt(banks, readPtr(v.reader(v.AddrBanksPtr.ROM())).ROM())
 	for i := 0; i < len(banks); i += ps {
 		bptr := decPtr(banks[i : i+ps])
 		// Stop if pointer isn't valid.
@@ -308,8 +381,7 @@ func (v *Version) ScanBanks() {
 	maps := make([]byte, 256*ps)
 	for i := 0; i < size; i++ {
 		bptr := decPtr(banks[i*ps : i*ps+ps])
-		v.ROM.Seek(bptr.ROM(), 0)
-		v.ROM.Read(maps)
+		v.ROM.ReadAt(maps, bptr.ROM())
 		v.sizeMapTable[i] = 256
 		for j := 0; j < len(maps); j += ps {
 			mptr := decPtr(maps[j : j+ps])
@@ -328,7 +400,7 @@ func (v *Version) ScanBanks() {
 }
 
 func (v *Version) BankIndexSize() int {
-	if len(v.sizeMapTable) == 0 {
+	if v.sizeMapTable == nil {
 		panic("banks have not been scanned")
 	}
 
@@ -336,7 +408,7 @@ func (v *Version) BankIndexSize() int {
 }
 
 func (v *Version) Banks() []pkm.Bank {
-	if len(v.sizeMapTable) == 0 {
+	if v.sizeMapTable == nil {
 		panic("banks have not been scanned")
 	}
 
@@ -348,7 +420,7 @@ func (v *Version) Banks() []pkm.Bank {
 }
 
 func (v *Version) BankByIndex(index int) pkm.Bank {
-	if len(v.sizeMapTable) == 0 {
+	if v.sizeMapTable == nil {
 		panic("banks have not been scanned")
 	}
 
@@ -359,7 +431,7 @@ func (v *Version) BankByIndex(index int) pkm.Bank {
 }
 
 func (v *Version) AllMaps() []pkm.Map {
-	if len(v.sizeMapTable) == 0 {
+	if v.sizeMapTable == nil {
 		panic("banks have not been scanned")
 	}
 
@@ -373,7 +445,7 @@ func (v *Version) AllMaps() []pkm.Map {
 }
 
 func (v *Version) MapByName(name string) pkm.Map {
-	if len(v.sizeMapTable) == 0 {
+	if v.sizeMapTable == nil {
 		panic("banks have not been scanned")
 	}
 
@@ -386,23 +458,121 @@ func (v *Version) MapByName(name string) pkm.Map {
 	return nil
 }
 
+// Type chart decoded from the type effectiveness list of a Version.
+type typeChartCache struct {
+	mu    sync.Mutex
+	chart *pkm.TypeChart
+}
+
+// Returns the decoded chart, decoding it if necessary. The result must not be
+// modified.
+func (c *typeChartCache) get(v *Version) *pkm.TypeChart {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.chart == nil {
+		c.chart = v.readTypeChart()
+	}
+	return c.chart
+}
+
+func (c *typeChartCache) reset() {
+	c.mu.Lock()
+	c.chart = nil
+	c.mu.Unlock()
+}
+
+// TypeEffectiveness returns the effectiveness of an attacking type against a
+// defender's types. The type chart is decoded from the ROM once, and decoded
+// again only after the ROM is written through the Version.
 func (v *Version) TypeEffectiveness(atk pkm.Type, def [2]pkm.Type) float64 {
-	v.ROM.Seek(v.AddrTypeEffect.ROM(), 0)
-	var mult byte = 4
+	return v.typeChart.get(v).Effectiveness(atk, def)
+}
+
+// TypeChart returns a copy of the type chart, which may be modified freely.
+func (v *Version) TypeChart() *pkm.TypeChart {
+	chart := *v.typeChart.get(v)
+	return &chart
+}
+
+// Decodes the type chart from the type effectiveness list.
+func (v *Version) readTypeChart() *pkm.TypeChart {
+	chart := pkm.NewTypeChart()
+	r := v.reader(v.AddrTypeEffect.ROM())
+	foresight := false
 	for q := make([]byte, structTypeEffect.Size()); ; {
-		v.ROM.Read(q)
-		if a, d := pkm.Type(q[0]), pkm.Type(q[1]); a == 0xFF || d == 0xFF {
+		if _, err := r.Read(q); err != nil {
+			break
+		}
+		if q[0] == typeEffectTerm || q[1] == typeEffectTerm {
+			break
+		} else if q[0] == typeEffectForesight {
+			foresight = true
+			continue
+		}
+		a, d := pkm.Type(q[0]), pkm.Type(q[1])
+		if a >= pkm.TypeIndexSize || d >= pkm.TypeIndexSize {
+			continue
+		}
+		chart.Effect[a][d] = pkm.Effectiveness(q[2])
+		chart.Foresight[a][d] = foresight
+	}
+	return chart
+}
+
+// Returns the number of entries in the type effectiveness list, including the
+// Foresight separator and the terminator.
+func (v *Version) typeEffectLen() int {
+	r := v.reader(v.AddrTypeEffect.ROM())
+	n := 1
+	for q := make([]byte, structTypeEffect.Size()); ; n++ {
+		if _, err := r.Read(q); err != nil {
 			break
-		} else if a == atk && (d == def[0] || d == def[1]) {
-			switch q[2] {
-			case 0:
-				mult = 0
-			case 5:
-				mult >>= 1
-			case 20:
-				mult <<= 1
+		}
+		if q[0] == typeEffectTerm || q[1] == typeEffectTerm {
+			break
+		}
+	}
+	return n
+}
+
+// SetTypeChart writes a type chart to the type effectiveness list. Only
+// entries that are not normally effective are written. Entries flagged with
+// Foresight are written after the Foresight separator. Returns an error if
+// the encoded list does not fit in the space of the current list.
+func (v *Version) SetTypeChart(chart *pkm.TypeChart) error {
+	w, err := v.writer()
+	if err != nil {
+		return err
+	}
+
+	size := structTypeEffect.Size()
+	var normal, foresight []byte
+	for a := range chart.Effect {
+		for d, e := range chart.Effect[a] {
+			if e == pkm.NormalEffect {
+				continue
+			}
+			entry := []byte{byte(a), byte(d), byte(e)}
+			if chart.Foresight[a][d] {
+				foresight = append(foresight, entry...)
+			} else {
+				normal = append(normal, entry...)
 			}
 		}
 	}
-	return float64(mult) / 4
+	b := make([]byte, 0, len(normal)+len(foresight)+size*2)
+	b = append(b, normal...)
+	b = append(b, typeEffectForesight, typeEffectForesight, 0)
+	b = append(b, foresight...)
+	b = append(b, typeEffectTerm, typeEffectTerm, 0)
+
+	n := v.typeEffectLen()
+	if len(b)/size > n {
+		return fmt.Errorf("type chart requires %d entries, but only %d fit", len(b)/size, n)
+	}
+	// Clear the remains of the previous list.
+	for len(b) < n*size {
+		b = append(b, typeEffectTerm)
+	}
+	return writeBytes(w, v.AddrTypeEffect, b)
 }
@@ -1,15 +1,20 @@
 package gen3_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/anaminus/pkm"
 	"github.com/anaminus/pkm/gen3"
+	"github.com/anaminus/pkm/gen3/romtest"
 	"strings"
 	"testing"
 )
 
 func TestVersion(t *testing.T) {
-	ver := gen3.OpenROM(ROM(t))
-	if ver == nil {
-		t.Fatalf("failed to open ROM")
+	ver, err := gen3.OpenROM(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
 	}
 
 	if !strings.Contains(ver.Name(), "Emerald") {
@@ -73,6 +78,9 @@ func TestVersion(t *testing.T) {
 	if ver.PokedexByName("Standard") == nil {
 		t.Errorf("PokedexByName: expected pokedex")
 	}
+	if ver.PokedexByName("Hoenn") == nil {
+		t.Errorf("PokedexByName: expected pokedex")
+	}
 	if ver.PokedexByName("") != nil {
 		t.Errorf("PokedexByName: unexpected pokedex")
 	}
@@ -262,10 +270,10 @@ func TestVersion(t *testing.T) {
 		t.Errorf("Banks: unexpected result length %d", len(v))
 	}
 	if v := ver.BankByIndex(0); v == nil || v.Index() != 0 {
-		t.Errorf("BankByIndex: unexpected result %d", v.Index)
+		t.Errorf("BankByIndex: unexpected result %v", v)
 	}
 	if v := ver.AllMaps(); len(v) != 518 {
-		t.Errorf("AllMaps: unexpected result length %d", v)
+		t.Errorf("AllMaps: unexpected result length %d", len(v))
 	}
 	if m := ver.MapByName("RUSTURF TUNNEL"); m == nil {
 		t.Errorf("MapByName: unexpected result <nil>")
@@ -281,3 +289,480 @@ func TestVersion(t *testing.T) {
 		t.Logf("Result: %d.%d", v.BankIndex(), v.Index())
 	}
 }
+
+func TestVersionSynthetic(t *testing.T) {
+	ver, err := gen3.OpenROM(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	if !strings.Contains(ver.Name(), "Emerald") {
+		t.Errorf("Name: unexpected name `%s`", ver.Name())
+	}
+	if ver.GameCode() != gen3.CodeEmeraldEN {
+		t.Errorf("GameCode: got game code %s, expected %s", ver.GameCode(), gen3.CodeEmeraldEN)
+	}
+	if ver.Query() == nil {
+		t.Errorf("Query: expected Query")
+	}
+	if len(ver.Codecs()) == 0 {
+		t.Errorf("Codecs: expected at least one codec")
+	}
+	if ver.DefaultCodec() == nil {
+		t.Errorf("DefaultCodec: expected default codec")
+	}
+	if ver.Codecs()[0] != ver.DefaultCodec() {
+		t.Errorf("Codecs: first codec should be default codec")
+	}
+
+	ExpectPanic(t, "SpeciesByIndex", func() {
+		ver.SpeciesByIndex(-1)
+	})
+	ExpectPanic(t, "SpeciesByIndex", func() {
+		ver.SpeciesByIndex(ver.SpeciesIndexSize())
+	})
+	ExpectPanic(t, "SpeciesByIndex", func() {
+		ver.SpeciesByIndex(ver.SpeciesIndexSize() + 1)
+	})
+	for i := 0; i < ver.SpeciesIndexSize(); i++ {
+		s := ver.SpeciesByIndex(i)
+		if s.Index() != i {
+			t.Errorf("SpeciesByIndex: returned index %d, expected %d", s.Index(), i)
+			break
+		}
+	}
+	if ver.SpeciesByName(romtest.SpeciesName(1)) == nil {
+		t.Errorf("SpeciesByName: returned nil species")
+	} else {
+		if ver.SpeciesByName(romtest.SpeciesName(1)).Index() != 1 {
+			t.Errorf("SpeciesByName: expected index 1")
+		}
+		if ver.SpeciesByName(strings.ToLower(romtest.SpeciesName(1))) == nil {
+			t.Errorf("SpeciesByName: not case-insensitive")
+		}
+	}
+	if ver.SpeciesByName("") != nil {
+		t.Errorf("SpeciesByName: expected nil")
+	}
+	if ver.SpeciesByName(ver.SpeciesByIndex(ver.SpeciesIndexSize()-1).Name()) == nil {
+		t.Errorf("SpeciesByName: returned nil last species")
+	}
+
+	if len(ver.Pokedex()) == 0 {
+		t.Errorf("Pokedex: expected at least one pokedex")
+	}
+	if ver.PokedexByName("National") == nil {
+		t.Errorf("PokedexByName: expected pokedex")
+	}
+	if ver.PokedexByName("Standard") == nil {
+		t.Errorf("PokedexByName: expected pokedex")
+	}
+	if ver.PokedexByName("Hoenn") == nil {
+		t.Errorf("PokedexByName: expected pokedex")
+	}
+	if ver.PokedexByName("") != nil {
+		t.Errorf("PokedexByName: unexpected pokedex")
+	}
+
+	if len(ver.Items()) != ver.ItemIndexSize() {
+		t.Errorf("Items: expected length %d, got %d", ver.ItemIndexSize(), len(ver.Items()))
+	}
+	ExpectPanic(t, "ItemByIndex", func() {
+		ver.ItemByIndex(-1)
+	})
+	ExpectPanic(t, "ItemByIndex", func() {
+		ver.ItemByIndex(ver.ItemIndexSize())
+	})
+	ExpectPanic(t, "ItemByIndex", func() {
+		ver.ItemByIndex(ver.ItemIndexSize() + 1)
+	})
+	for i := 0; i < ver.ItemIndexSize(); i++ {
+		s := ver.ItemByIndex(i)
+		if s.Index() != i {
+			t.Errorf("ItemByIndex: returned index %d, expected %d", s.Index(), i)
+			break
+		}
+	}
+	if ver.ItemByName(romtest.ItemName(1)) == nil {
+		t.Errorf("ItemByName: returned nil item")
+	} else {
+		if ver.ItemByName(romtest.ItemName(1)).Index() != 1 {
+			t.Errorf("ItemByName: expected index 1")
+		}
+		if ver.ItemByName(strings.ToLower(romtest.ItemName(1))) == nil {
+			t.Errorf("ItemByName: not case-insensitive")
+		}
+	}
+	if ver.ItemByName("") != nil {
+		t.Errorf("ItemByName: expected nil")
+	}
+	if ver.ItemByName(ver.ItemByIndex(ver.ItemIndexSize()-1).Name()) == nil {
+		t.Errorf("ItemByName: returned nil last species")
+	}
+
+	if len(ver.Abilities()) != ver.AbilityIndexSize() {
+		t.Errorf("Abilities: expected length %d, got %d", ver.AbilityIndexSize(), len(ver.Abilities()))
+	}
+	ExpectPanic(t, "AbilityByIndex", func() {
+		ver.AbilityByIndex(-1)
+	})
+	ExpectPanic(t, "AbilityByIndex", func() {
+		ver.AbilityByIndex(ver.AbilityIndexSize())
+	})
+	ExpectPanic(t, "AbilityByIndex", func() {
+		ver.AbilityByIndex(ver.AbilityIndexSize() + 1)
+	})
+	for i := 0; i < ver.AbilityIndexSize(); i++ {
+		s := ver.AbilityByIndex(i)
+		if s.Index() != i {
+			t.Errorf("AbilityByIndex: returned index %d, expected %d", s.Index(), i)
+			break
+		}
+	}
+	if ver.AbilityByName(romtest.AbilityName(1)) == nil {
+		t.Errorf("AbilityByName: returned nil item")
+	} else {
+		if ver.AbilityByName(romtest.AbilityName(1)).Index() != 1 {
+			t.Errorf("AbilityByName: expected index 1")
+		}
+		if ver.AbilityByName(strings.ToLower(romtest.AbilityName(1))) == nil {
+			t.Errorf("AbilityByName: not case-insensitive")
+		}
+	}
+	if ver.AbilityByName("") != nil {
+		t.Errorf("AbilityByName: expected nil")
+	}
+	if ver.AbilityByName(ver.AbilityByIndex(ver.AbilityIndexSize()-1).Name()) == nil {
+		t.Errorf("AbilityByName: returned nil last species")
+	}
+
+	if len(ver.Moves()) != ver.MoveIndexSize() {
+		t.Errorf("Moves: expected length %d, got %d", ver.MoveIndexSize(), len(ver.Moves()))
+	}
+	ExpectPanic(t, "MoveByIndex", func() {
+		ver.MoveByIndex(-1)
+	})
+	ExpectPanic(t, "MoveByIndex", func() {
+		ver.MoveByIndex(ver.MoveIndexSize())
+	})
+	ExpectPanic(t, "MoveByIndex", func() {
+		ver.MoveByIndex(ver.MoveIndexSize() + 1)
+	})
+	for i := 0; i < ver.MoveIndexSize(); i++ {
+		s := ver.MoveByIndex(i)
+		if s.Index() != i {
+			t.Errorf("MoveByIndex: returned index %d, expected %d", s.Index(), i)
+			break
+		}
+	}
+	if ver.MoveByName(romtest.MoveName(1)) == nil {
+		t.Errorf("MoveByName: returned nil item")
+	} else {
+		if ver.MoveByName(romtest.MoveName(1)).Index() != 1 {
+			t.Errorf("MoveByName: expected index 1")
+		}
+		if ver.MoveByName(strings.ToLower(romtest.MoveName(1))) == nil {
+			t.Errorf("MoveByName: not case-insensitive")
+		}
+	}
+	if ver.MoveByName("") != nil {
+		t.Errorf("MoveByName: expected nil")
+	}
+	if ver.MoveByName(ver.MoveByIndex(ver.MoveIndexSize()-1).Name()) == nil {
+		t.Errorf("MoveByName: returned nil last species")
+	}
+
+	if len(ver.TMs()) != ver.TMIndexSize() {
+		t.Errorf("TMs: expected length %d, got %d", ver.TMIndexSize(), len(ver.TMs()))
+	}
+	ExpectPanic(t, "TMByIndex", func() {
+		ver.TMByIndex(-1)
+	})
+	ExpectPanic(t, "TMByIndex", func() {
+		ver.TMByIndex(ver.TMIndexSize())
+	})
+	ExpectPanic(t, "TMByIndex", func() {
+		ver.TMByIndex(ver.TMIndexSize() + 1)
+	})
+	for i := 0; i < ver.TMIndexSize(); i++ {
+		s := ver.TMByIndex(i)
+		if s.Index() != i {
+			t.Errorf("TMByIndex: returned index %d, expected %d", s.Index(), i)
+			break
+		}
+	}
+	if ver.TMByName("TM01") == nil {
+		t.Errorf("TMByName: returned nil item")
+	} else {
+		if ver.TMByName("TM01").Index() != 0 {
+			t.Errorf("TMByName: expected index 1")
+		}
+		if ver.TMByName("tm01") == nil {
+			t.Errorf("TMByName: not case-insensitive")
+		}
+	}
+	if ver.TMByName("") != nil {
+		t.Errorf("TMByName: expected nil")
+	}
+	if ver.TMByName("AAAA") != nil {
+		t.Errorf("TMByName: expected nil")
+	}
+	if ver.TMByName("MM01") != nil {
+		t.Errorf("TMByName: expected nil")
+	}
+	if ver.TMByName("TM99") != nil {
+		t.Errorf("TMByName: expected nil")
+	}
+	if ver.TMByName("AAAAA") != nil {
+		t.Errorf("TMByName: expected nil")
+	}
+	if ver.TMByName(ver.TMByIndex(ver.TMIndexSize()-1).Name()) == nil {
+		t.Errorf("TMByName: returned nil last species")
+	}
+
+	ExpectPanic(t, "BankIndexSize", func() {
+		ver.BankIndexSize()
+	})
+	ExpectPanic(t, "Banks", func() {
+		ver.Banks()
+	})
+	ExpectPanic(t, "BankByIndex", func() {
+		ver.BankByIndex(0)
+	})
+	ExpectPanic(t, "AllMaps", func() {
+		ver.AllMaps()
+	})
+	ExpectPanic(t, "MapByName", func() {
+		ver.MapByName("")
+	})
+
+	ver.ScanBanks()
+
+	ExpectPanic(t, "BankByIndex", func() {
+		ver.BankByIndex(257)
+	})
+
+	if v := ver.BankIndexSize(); v != 2 {
+		t.Errorf("BankIndexSize: unexpected result %d", v)
+	}
+	if v := ver.Banks(); len(v) != 2 {
+		t.Errorf("Banks: unexpected result length %d", len(v))
+	}
+	if v := ver.BankByIndex(0); v == nil || v.Index() != 0 {
+		t.Errorf("BankByIndex: unexpected result %v", v)
+	}
+	if v := ver.AllMaps(); len(v) != len(romtest.Maps) {
+		t.Errorf("AllMaps: unexpected result length %d", len(v))
+	}
+	if m := ver.MapByName(romtest.MapLabel(3)); m == nil {
+		t.Errorf("MapByName: unexpected result <nil>")
+	} else if m.BankIndex() != 0 || m.Index() != 2 {
+		t.Errorf("MapByName: unexpected result %d.%d (%s)", m.BankIndex(), m.Index(), m.Name())
+	} else {
+		if v := ver.MapByName(strings.ToLower(romtest.MapLabel(3))); v != m {
+			t.Errorf("MapByName: not case-insensitive")
+		}
+	}
+	if v := ver.MapByName("unknown"); v != nil {
+		t.Errorf("MapByName: expected nil result")
+		t.Logf("Result: %d.%d", v.BankIndex(), v.Index())
+	}
+}
+
+func TestTypeChart(t *testing.T) {
+	ver, err := gen3.OpenROM(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	chart := ver.TypeChart()
+	if v := chart.Effect[pkm.TypeFire][pkm.TypeGrass]; v != pkm.SuperEffective {
+		t.Errorf("TypeChart: Fire vs Grass: unexpected result %s", v)
+	}
+	if v := chart.Effect[pkm.TypeWater][pkm.TypeGrass]; v != pkm.NotVeryEffective {
+		t.Errorf("TypeChart: Water vs Grass: unexpected result %s", v)
+	}
+	if v := chart.Effect[pkm.TypeElectric][pkm.TypeGround]; v != pkm.NoEffect {
+		t.Errorf("TypeChart: Electric vs Ground: unexpected result %s", v)
+	} else if chart.Foresight[pkm.TypeElectric][pkm.TypeGround] {
+		t.Errorf("TypeChart: Electric vs Ground: unexpected Foresight flag")
+	}
+	if v := chart.Effect[pkm.TypeNormal][pkm.TypeGhost]; v != pkm.NoEffect {
+		t.Errorf("TypeChart: Normal vs Ghost: unexpected result %s", v)
+	} else if !chart.Foresight[pkm.TypeNormal][pkm.TypeGhost] {
+		t.Errorf("TypeChart: Normal vs Ghost: expected Foresight flag")
+	}
+	if v := chart.IdentifiedEffectiveness(pkm.TypeFighting, [2]pkm.Type{pkm.TypeGhost, pkm.TypeGhost}); v != 1 {
+		t.Errorf("IdentifiedEffectiveness: unexpected result %g", v)
+	}
+
+	for a := pkm.Type(0); a < pkm.TypeIndexSize; a++ {
+		for d := pkm.Type(0); d < pkm.TypeIndexSize; d++ {
+			def := [2]pkm.Type{d, pkm.TypeFlying}
+			if v, c := ver.TypeEffectiveness(a, def), chart.Effectiveness(a, def); v != c {
+				t.Errorf("TypeEffectiveness: %s vs %s: result %g does not match chart %g", a, d, v, c)
+			}
+		}
+	}
+
+	if v, ok := ver.(*gen3.Version); ok {
+		if err := v.SetTypeChart(chart); err != gen3.ErrReadOnly {
+			t.Errorf("SetTypeChart: expected ErrReadOnly, got %v", err)
+		}
+	}
+}
+
+func TestTypeChartSynthetic(t *testing.T) {
+	ver, err := gen3.OpenROM(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+
+	chart := ver.TypeChart()
+	if v := chart.Effect[pkm.TypeFire][pkm.TypeGrass]; v != pkm.SuperEffective {
+		t.Errorf("TypeChart: Fire vs Grass: unexpected result %s", v)
+	}
+	if v := chart.Effect[pkm.TypeGrass][pkm.TypeFire]; v != pkm.NotVeryEffective {
+		t.Errorf("TypeChart: Grass vs Fire: unexpected result %s", v)
+	} else if chart.Foresight[pkm.TypeGrass][pkm.TypeFire] {
+		t.Errorf("TypeChart: Grass vs Fire: unexpected Foresight flag")
+	}
+	if v := chart.Effect[pkm.TypeElectric][pkm.TypeGround]; v != pkm.NormalEffect {
+		t.Errorf("TypeChart: Electric vs Ground: unexpected result %s", v)
+	}
+	if v := chart.Effect[pkm.TypeNormal][pkm.TypeGhost]; v != pkm.NoEffect {
+		t.Errorf("TypeChart: Normal vs Ghost: unexpected result %s", v)
+	} else if !chart.Foresight[pkm.TypeNormal][pkm.TypeGhost] {
+		t.Errorf("TypeChart: Normal vs Ghost: expected Foresight flag")
+	}
+	if v := chart.IdentifiedEffectiveness(pkm.TypeNormal, [2]pkm.Type{pkm.TypeGhost, pkm.TypeGhost}); v != 1 {
+		t.Errorf("IdentifiedEffectiveness: unexpected result %g", v)
+	}
+
+	for a := pkm.Type(0); a < pkm.TypeIndexSize; a++ {
+		for d := pkm.Type(0); d < pkm.TypeIndexSize; d++ {
+			def := [2]pkm.Type{d, pkm.TypeFlying}
+			if v, c := ver.TypeEffectiveness(a, def), chart.Effectiveness(a, def); v != c {
+				t.Errorf("TypeEffectiveness: %s vs %s: result %g does not match chart %g", a, d, v, c)
+			}
+		}
+	}
+
+	if v, ok := ver.(*gen3.Version); ok {
+		if err := v.SetTypeChart(chart); err != gen3.ErrReadOnly {
+			t.Errorf("SetTypeChart: expected ErrReadOnly, got %v", err)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a, err := gen3.OpenROM(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+	buf, err := gen3.ReadBuffer(ROM(t))
+	if err != nil {
+		t.Fatalf("failed to read ROM: %s", err)
+	}
+	b, err := gen3.OpenROM(buf)
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+	if v := pkm.Diff(a, b); len(v) != 0 {
+		t.Fatalf("Diff: unexpected differences in identical versions: %v", v)
+	}
+
+	species := b.SpeciesByName("sceptile").(gen3.Species)
+	stats := species.BaseStats()
+	stats.Speed = 125
+	if err := species.SetBaseStats(stats); err != nil {
+		t.Fatalf("SetBaseStats: unexpected error: %s", err)
+	}
+	b.ScanBanks()
+	e := b.BankByIndex(0).MapByIndex(16).Encounters()[0].Encounter(3).(gen3.Encounter)
+	if err := e.SetSpecies(b.SpeciesByName("poochyena")); err != nil {
+		t.Fatalf("SetSpecies: unexpected error: %s", err)
+	}
+
+	diffs := pkm.Diff(a, b)
+	var text bytes.Buffer
+	if err := diffs.WriteText(&text); err != nil {
+		t.Fatalf("WriteText: unexpected error: %s", err)
+	}
+	for _, s := range []string{
+		"SCEPTILE BaseStats.Speed 120 -> 125\n",
+		"ROUTE 101 (0.16) Grass slot 3 ",
+	} {
+		if !strings.Contains(text.String(), s) {
+			t.Errorf("Diff: missing %q in:\n%s", s, text.String())
+		}
+	}
+
+	var js bytes.Buffer
+	if err := diffs.WriteJSON(&js); err != nil {
+		t.Fatalf("WriteJSON: unexpected error: %s", err)
+	}
+	var decoded []pkm.Difference
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatalf("WriteJSON: invalid JSON: %s", err)
+	}
+	if len(decoded) != len(diffs) {
+		t.Errorf("WriteJSON: unexpected length %d", len(decoded))
+	}
+}
+
+func TestDiffSynthetic(t *testing.T) {
+	a, err := gen3.OpenROM(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+	buf, err := gen3.ReadBuffer(SyntheticROM())
+	if err != nil {
+		t.Fatalf("failed to read ROM: %s", err)
+	}
+	b, err := gen3.OpenROM(buf)
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+	if v := pkm.Diff(a, b); len(v) != 0 {
+		t.Fatalf("Diff: unexpected differences in identical versions: %v", v)
+	}
+
+	species := b.SpeciesByIndex(254).(gen3.Species)
+	stats := species.BaseStats()
+	stats.Speed = 125
+	if err := species.SetBaseStats(stats); err != nil {
+		t.Fatalf("SetBaseStats: unexpected error: %s", err)
+	}
+	b.ScanBanks()
+	e := b.BankByIndex(0).MapByIndex(0).Encounters()[0].Encounter(3).(gen3.Encounter)
+	if err := e.SetSpecies(b.SpeciesByIndex(286)); err != nil {
+		t.Fatalf("SetSpecies: unexpected error: %s", err)
+	}
+
+	diffs := pkm.Diff(a, b)
+	var text bytes.Buffer
+	if err := diffs.WriteText(&text); err != nil {
+		t.Fatalf("WriteText: unexpected error: %s", err)
+	}
+	for _, s := range []string{
+		fmt.Sprintf("%s BaseStats.Speed %d -> 125\n", romtest.SpeciesName(254), romtest.BaseStats(254).Speed),
+		romtest.MapLabel(0) + " (0.0) Grass slot 3 ",
+	} {
+		if !strings.Contains(text.String(), s) {
+			t.Errorf("Diff: missing %q in:\n%s", s, text.String())
+		}
+	}
+
+	var js bytes.Buffer
+	if err := diffs.WriteJSON(&js); err != nil {
+		t.Fatalf("WriteJSON: unexpected error: %s", err)
+	}
+	var decoded []pkm.Difference
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatalf("WriteJSON: invalid JSON: %s", err)
+	}
+	if len(decoded) != len(diffs) {
+		t.Errorf("WriteJSON: unexpected length %d", len(decoded))
+	}
+}
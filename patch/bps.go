@@ -0,0 +1,141 @@
+package patch
+
+import (
+	"bytes"
+	"hash/crc32"
+)
+
+const bpsMagic = "BPS1"
+
+// BPS actions.
+const (
+	bpsSourceRead = iota
+	bpsTargetRead
+	bpsSourceCopy
+	bpsTargetCopy
+)
+
+// Unchanged bytes separated by fewer changed bytes are included in a
+// TargetRead action rather than a separate SourceRead action.
+const bpsMinSourceRead = 4
+
+func appendBPSAction(p []byte, action, length int) []byte {
+	return appendNumber(p, uint64(length-1)<<2|uint64(action))
+}
+
+// CreateBPS returns a BPS patch that transforms src into dst. Unchanged bytes
+// are read from the source at the same offset, and changed bytes are stored
+// in the patch.
+func CreateBPS(src, dst []byte) ([]byte, error) {
+	p := []byte(bpsMagic)
+	p = appendNumber(p, uint64(len(src)))
+	p = appendNumber(p, uint64(len(dst)))
+	// No metadata.
+	p = appendNumber(p, 0)
+	// Returns the number of unchanged bytes at i, up to max.
+	same := func(i, max int) int {
+		n := 0
+		for i+n < len(dst) && i+n < len(src) && n < max && src[i+n] == dst[i+n] {
+			n++
+		}
+		return n
+	}
+	for i := 0; i < len(dst); {
+		if n := same(i, len(dst)); n >= bpsMinSourceRead || n > 0 && i+n == len(dst) {
+			p = appendBPSAction(p, bpsSourceRead, n)
+			i += n
+			continue
+		}
+		// Read changed bytes up to the next long run of unchanged bytes.
+		start := i
+		for i < len(dst) {
+			n := same(i, bpsMinSourceRead)
+			if n >= bpsMinSourceRead || n > 0 && i+n == len(dst) {
+				break
+			}
+			if n == 0 {
+				n = 1
+			}
+			i += n
+		}
+		p = appendBPSAction(p, bpsTargetRead, i-start)
+		p = append(p, dst[start:i]...)
+	}
+	p = appendChecksum(p, src)
+	p = appendChecksum(p, dst)
+	p = appendChecksum(p, p)
+	return p, nil
+}
+
+// ApplyBPS applies a BPS patch to src, returning the patched image.
+func ApplyBPS(src, patch []byte) ([]byte, error) {
+	if !bytes.HasPrefix(patch, []byte(bpsMagic)) {
+		return nil, ErrFormat
+	}
+	body, srcSum, dstSum, err := splitFooter(patch)
+	if err != nil {
+		return nil, err
+	}
+	r := &reader{b: body, i: len(bpsMagic)}
+	srcSize := r.number()
+	dstSize := r.number()
+	r.bytes(r.number()) // Metadata.
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(src) != srcSize || crc32.ChecksumIEEE(src) != srcSum {
+		return nil, ErrSource
+	}
+	out := make([]byte, 0, dstSize)
+	var srcRel, dstRel int
+	offset := func() int {
+		n := r.number()
+		if n&1 != 0 {
+			return -(n >> 1)
+		}
+		return n >> 1
+	}
+	for r.i < len(body) {
+		n := r.number()
+		action, length := n&3, n>>2+1
+		if r.err != nil {
+			return nil, r.err
+		}
+		if len(out)+length > dstSize {
+			return nil, ErrFormat
+		}
+		switch action {
+		case bpsSourceRead:
+			if len(out)+length > len(src) {
+				return nil, ErrFormat
+			}
+			out = append(out, src[len(out):len(out)+length]...)
+		case bpsTargetRead:
+			out = append(out, r.bytes(length)...)
+		case bpsSourceCopy:
+			srcRel += offset()
+			if srcRel < 0 || srcRel+length > len(src) {
+				return nil, ErrFormat
+			}
+			out = append(out, src[srcRel:srcRel+length]...)
+			srcRel += length
+		case bpsTargetCopy:
+			dstRel += offset()
+			if dstRel < 0 || dstRel >= len(out) {
+				return nil, ErrFormat
+			}
+			// The copy may overlap the bytes it produces.
+			for ; length > 0; length-- {
+				out = append(out, out[dstRel])
+				dstRel++
+			}
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+	}
+	if len(out) != dstSize || crc32.ChecksumIEEE(out) != dstSum {
+		return nil, ErrTarget
+	}
+	return out, nil
+}
@@ -0,0 +1,173 @@
+package patch
+
+import (
+	"bytes"
+	"errors"
+)
+
+const (
+	ipsMagic = "PATCH"
+	ipsEOF   = "EOF"
+	// Records cannot be addressed beyond this offset.
+	ipsMaxOffset = 0xFFFFFF
+	// The maximum size of a record.
+	ipsMaxRecord = 0xFFFF
+	// The minimum length of a run of identical bytes encoded as RLE.
+	ipsMinRun = 9
+	// Differences separated by fewer unchanged bytes are merged into one
+	// record.
+	ipsMergeGap = 6
+)
+
+// ErrIPSSize is returned when an image is too large to be addressed by an IPS
+// patch.
+var ErrIPSSize = errors.New("image too large for IPS")
+
+// Offset 0x454F46 is equal to the EOF marker, and cannot start a record.
+var ipsEOFOffset = int(ipsEOF[0])<<16 | int(ipsEOF[1])<<8 | int(ipsEOF[2])
+
+func appendIPSOffset(p []byte, off int) []byte {
+	return append(p, byte(off>>16), byte(off>>8), byte(off))
+}
+
+// Returns the length of the run of identical bytes at the start of b.
+func runLength(b []byte) int {
+	n := 1
+	for n < len(b) && b[n] == b[0] {
+		n++
+	}
+	return n
+}
+
+// Appends records that write dst[off:end].
+func appendIPSRecords(p []byte, dst []byte, off, end int) []byte {
+	for off < end {
+		if off == ipsEOFOffset {
+			// Rewrite the preceding byte so that the record starts elsewhere.
+			p = appendIPSOffset(p, off-1)
+			p = append(p, 0, 2, dst[off-1], dst[off])
+			off++
+			continue
+		}
+		if n := runLength(dst[off:end]); n >= ipsMinRun {
+			p = appendIPSOffset(p, off)
+			p = append(p, 0, 0, byte(n>>8), byte(n), dst[off])
+			off += n
+			continue
+		}
+		// Literal bytes up to the next long run.
+		n := 0
+		for off+n < end {
+			r := runLength(dst[off+n : end])
+			if r >= ipsMinRun {
+				break
+			}
+			n += r
+		}
+		p = appendIPSOffset(p, off)
+		p = append(p, byte(n>>8), byte(n))
+		p = append(p, dst[off:off+n]...)
+		off += n
+	}
+	return p
+}
+
+// CreateIPS returns an IPS patch that transforms src into dst. IPS patches
+// cannot address more than 16 MiB, and do not contain checksums. If dst is
+// shorter than src, the patch uses the truncation extension.
+func CreateIPS(src, dst []byte) ([]byte, error) {
+	if len(dst) > ipsMaxOffset+1 {
+		return nil, ErrIPSSize
+	}
+	p := []byte(ipsMagic)
+	differ := func(i int) bool {
+		return i >= len(src) || src[i] != dst[i]
+	}
+	for i := 0; i < len(dst); {
+		if !differ(i) {
+			i++
+			continue
+		}
+		start := i
+		end := i + 1
+		for end < len(dst) && end-start < ipsMaxRecord {
+			if differ(end) {
+				end++
+				continue
+			}
+			// Merge across short gaps of unchanged bytes.
+			gap := end
+			for gap < len(dst) && gap-end < ipsMergeGap && !differ(gap) {
+				gap++
+			}
+			if gap >= len(dst) || gap-end >= ipsMergeGap || gap-start >= ipsMaxRecord {
+				break
+			}
+			end = gap
+		}
+		p = appendIPSRecords(p, dst, start, end)
+		i = end
+	}
+	p = append(p, ipsEOF...)
+	if len(dst) < len(src) {
+		n := len(dst)
+		p = append(p, byte(n>>16), byte(n>>8), byte(n))
+	}
+	return p, nil
+}
+
+// ApplyIPS applies an IPS patch to src, returning the patched image. IPS
+// patches do not contain checksums, so the source cannot be verified.
+func ApplyIPS(src, patch []byte) ([]byte, error) {
+	if !bytes.HasPrefix(patch, []byte(ipsMagic)) {
+		return nil, ErrFormat
+	}
+	out := make([]byte, len(src))
+	copy(out, src)
+	r := &reader{b: patch, i: len(ipsMagic)}
+	grow := func(n int) {
+		if n > len(out) {
+			out = append(out, make([]byte, n-len(out))...)
+		}
+	}
+	for {
+		b := r.bytes(3)
+		if r.err != nil {
+			return nil, r.err
+		}
+		if string(b) == ipsEOF {
+			break
+		}
+		off := int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+		b = r.bytes(2)
+		if r.err != nil {
+			return nil, r.err
+		}
+		size := int(b[0])<<8 | int(b[1])
+		if size == 0 {
+			b = r.bytes(3)
+			if r.err != nil {
+				return nil, r.err
+			}
+			size = int(b[0])<<8 | int(b[1])
+			grow(off + size)
+			for i := off; i < off+size; i++ {
+				out[i] = b[2]
+			}
+			continue
+		}
+		data := r.bytes(size)
+		if r.err != nil {
+			return nil, r.err
+		}
+		grow(off + size)
+		copy(out[off:], data)
+	}
+	// Truncation extension.
+	if b := r.bytes(3); r.err == nil {
+		if n := int(b[0])<<16 | int(b[1])<<8 | int(b[2]); n < len(out) {
+			out = out[:n]
+		}
+	}
+	return out, nil
+}
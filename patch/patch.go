@@ -0,0 +1,173 @@
+// Package patch creates and applies patches between byte images, such as an
+// original ROM and a ROM modified through the gen3 package. The IPS, UPS and
+// BPS formats are supported.
+//
+// Patches are applied in memory. The result can be opened directly:
+//
+//	b, err := patch.Apply(original, p)
+//	if err != nil {
+//	    return err
+//	}
+//	v, err := gen3.OpenROM(gen3.NewBuffer(b))
+package patch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrFormat is returned when a patch is malformed.
+	ErrFormat = errors.New("invalid patch format")
+	// ErrPatchChecksum is returned when the checksum of a patch does not
+	// match its content.
+	ErrPatchChecksum = errors.New("patch checksum mismatch")
+	// ErrSource is returned when the source image does not match the size
+	// or checksum expected by a patch.
+	ErrSource = errors.New("source does not match patch")
+	// ErrTarget is returned when the result of applying a patch does not
+	// match the size or checksum expected by the patch.
+	ErrTarget = errors.New("result does not match patch")
+)
+
+// Format indicates the format of a patch.
+type Format int
+
+const (
+	Unknown Format = iota
+	IPS
+	UPS
+	BPS
+)
+
+func (f Format) String() string {
+	switch f {
+	case IPS:
+		return "IPS"
+	case UPS:
+		return "UPS"
+	case BPS:
+		return "BPS"
+	}
+	return "Unknown"
+}
+
+// Detect returns the format of a patch, determined by its header.
+func Detect(patch []byte) Format {
+	switch {
+	case bytes.HasPrefix(patch, []byte(ipsMagic)):
+		return IPS
+	case bytes.HasPrefix(patch, []byte(upsMagic)):
+		return UPS
+	case bytes.HasPrefix(patch, []byte(bpsMagic)):
+		return BPS
+	}
+	return Unknown
+}
+
+// Create returns a patch of the given format that transforms src into dst.
+func Create(format Format, src, dst []byte) ([]byte, error) {
+	switch format {
+	case IPS:
+		return CreateIPS(src, dst)
+	case UPS:
+		return CreateUPS(src, dst)
+	case BPS:
+		return CreateBPS(src, dst)
+	}
+	return nil, fmt.Errorf("unknown patch format %d", format)
+}
+
+// Apply applies a patch to src, returning the patched image. The format of
+// the patch is detected from its header. src is not modified.
+func Apply(src, patch []byte) ([]byte, error) {
+	switch Detect(patch) {
+	case IPS:
+		return ApplyIPS(src, patch)
+	case UPS:
+		return ApplyUPS(src, patch)
+	case BPS:
+		return ApplyBPS(src, patch)
+	}
+	return nil, ErrFormat
+}
+
+// Returns the byte at index i, or 0 if i is out of range.
+func at(b []byte, i int) byte {
+	if i < len(b) {
+		return b[i]
+	}
+	return 0
+}
+
+// Appends a variable-length number, as used by UPS and BPS.
+func appendNumber(b []byte, n uint64) []byte {
+	for {
+		x := byte(n & 0x7F)
+		n >>= 7
+		if n == 0 {
+			return append(b, 0x80|x)
+		}
+		b = append(b, x)
+		n--
+	}
+}
+
+// Reads a variable-length number from b, returning the number and the number
+// of bytes read. The number of bytes is 0 if the number is malformed.
+func readNumber(b []byte) (n uint64, size int) {
+	shift := uint64(1)
+	for i, x := range b {
+		if i >= 10 {
+			break
+		}
+		n += uint64(x&0x7F) * shift
+		if x&0x80 != 0 {
+			return n, i + 1
+		}
+		shift <<= 7
+		n += shift
+	}
+	return 0, 0
+}
+
+// Reads sequential values from a patch.
+type reader struct {
+	b   []byte
+	i   int
+	err error
+}
+
+func (r *reader) number() int {
+	if r.err != nil {
+		return 0
+	}
+	n, size := readNumber(r.b[r.i:])
+	if size == 0 || n > 1<<31 {
+		r.err = ErrFormat
+		return 0
+	}
+	r.i += size
+	return int(n)
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.b)-r.i {
+		r.err = ErrFormat
+		return nil
+	}
+	b := r.b[r.i : r.i+n]
+	r.i += n
+	return b
+}
+
+func (r *reader) byte() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
@@ -0,0 +1,144 @@
+package patch_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/anaminus/pkm/patch"
+	"hash/crc32"
+	"math/rand"
+	"testing"
+)
+
+// Returns pairs of source and target images.
+func images() (src, dst [][]byte) {
+	rnd := rand.New(rand.NewSource(1))
+	base := make([]byte, 0x460000)
+	rnd.Read(base)
+	add := func(f func(b []byte) []byte) {
+		b := make([]byte, len(base))
+		copy(b, base)
+		src = append(src, base)
+		dst = append(dst, f(b))
+	}
+	// Unchanged.
+	add(func(b []byte) []byte { return b })
+	// Scattered edits.
+	add(func(b []byte) []byte {
+		for i := 0; i < 1000; i++ {
+			b[rnd.Intn(len(b))] ^= byte(rnd.Intn(255) + 1)
+		}
+		return b
+	})
+	// Long runs, including at the IPS EOF offset.
+	add(func(b []byte) []byte {
+		for i := 0x100; i < 0x30100; i++ {
+			b[i] = 0xFF
+		}
+		for i := 0x454F46; i < 0x454F50; i++ {
+			b[i] = 0
+		}
+		copy(b[0x454F40:], []byte{1, 2, 3, 4, 5, 6, 7})
+		return b
+	})
+	// Expanded.
+	add(func(b []byte) []byte {
+		b = append(b, make([]byte, 0x1000)...)
+		for i := len(base); i < len(b); i += 3 {
+			b[i] = byte(i)
+		}
+		return b
+	})
+	// Truncated.
+	add(func(b []byte) []byte {
+		b[0] = ^b[0]
+		return b[:len(b)-0x1234]
+	})
+	return src, dst
+}
+
+func TestRoundTrip(t *testing.T) {
+	src, dst := images()
+	for _, format := range []patch.Format{patch.IPS, patch.UPS, patch.BPS} {
+		for i := range src {
+			p, err := patch.Create(format, src[i], dst[i])
+			if err != nil {
+				t.Errorf("%s: %d: Create: unexpected error: %s", format, i, err)
+				continue
+			}
+			if f := patch.Detect(p); f != format {
+				t.Errorf("%s: %d: Detect: unexpected result %s", format, i, f)
+			}
+			b, err := patch.Apply(src[i], p)
+			if err != nil {
+				t.Errorf("%s: %d: Apply: unexpected error: %s", format, i, err)
+				continue
+			}
+			if !bytes.Equal(b, dst[i]) {
+				t.Errorf("%s: %d: Apply: result does not match target", format, i)
+			}
+		}
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	src := []byte("The quick brown fox jumps over the lazy dog.")
+	dst := []byte("The quick green fox leaps over the lazy dog!")
+	for _, format := range []patch.Format{patch.UPS, patch.BPS} {
+		p, _ := patch.Create(format, src, dst)
+
+		wrong := append([]byte{}, src...)
+		wrong[0] = 't'
+		if _, err := patch.Apply(wrong, p); err != patch.ErrSource {
+			t.Errorf("%s: expected ErrSource, got %v", format, err)
+		}
+
+		corrupt := append([]byte{}, p...)
+		corrupt[len(corrupt)/2] ^= 1
+		if _, err := patch.Apply(src, corrupt); err != patch.ErrPatchChecksum {
+			t.Errorf("%s: expected ErrPatchChecksum, got %v", format, err)
+		}
+	}
+
+	// UPS patches can be applied in reverse.
+	p, _ := patch.CreateUPS(src, dst)
+	if b, err := patch.ApplyUPS(dst, p); err != nil || !bytes.Equal(b, src) {
+		t.Errorf("ApplyUPS: unexpected reverse result %q (%v)", b, err)
+	}
+
+	if _, err := patch.Apply(src, []byte("NOTAPATCH")); err != patch.ErrFormat {
+		t.Errorf("Apply: expected ErrFormat, got %v", err)
+	}
+	if _, err := patch.CreateIPS(nil, make([]byte, 0x1000001)); err != patch.ErrIPSSize {
+		t.Errorf("CreateIPS: expected ErrIPSSize, got %v", err)
+	}
+}
+
+func TestBPSCopy(t *testing.T) {
+	src := []byte("abcdef")
+	dst := []byte("defabcxxxxxx")
+	p, _ := patch.CreateBPS(src, dst)
+	// Replace the actions with SourceCopy and TargetCopy actions.
+	body := []byte("BPS1")
+	body = append(body, 0x86, 0x8C, 0x80)
+	body = append(body,
+		0x8A, 0x86, // SourceCopy 3 from +3.
+		0x8A, 0x8D, // SourceCopy 3 from -6.
+		0x81, 'x', // TargetRead 1.
+		0x93, 0x8C, // TargetCopy 5 from +6.
+	)
+	n := len(p)
+	body = append(body, p[n-12:n-4]...)
+	if _, err := patch.ApplyBPS(src, body); err != patch.ErrPatchChecksum {
+		t.Errorf("ApplyBPS: expected ErrPatchChecksum, got %v", err)
+	}
+	body = appendCRC(body)
+	if b, err := patch.ApplyBPS(src, body); err != nil || !bytes.Equal(b, dst) {
+		t.Errorf("ApplyBPS: unexpected result %q (%v)", b, err)
+	}
+}
+
+func appendCRC(b []byte) []byte {
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc32.ChecksumIEEE(b))
+	return append(b, sum[:]...)
+}
@@ -0,0 +1,112 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+)
+
+const upsMagic = "UPS1"
+
+// CreateUPS returns a UPS patch that transforms src into dst.
+func CreateUPS(src, dst []byte) ([]byte, error) {
+	p := []byte(upsMagic)
+	p = appendNumber(p, uint64(len(src)))
+	p = appendNumber(p, uint64(len(dst)))
+	n := len(src)
+	if len(dst) > n {
+		n = len(dst)
+	}
+	last := 0
+	for i := 0; i < n; {
+		if at(src, i) == at(dst, i) {
+			i++
+			continue
+		}
+		p = appendNumber(p, uint64(i-last))
+		for ; i < n && at(src, i) != at(dst, i); i++ {
+			p = append(p, at(src, i)^at(dst, i))
+		}
+		// The terminator corresponds to an unchanged byte.
+		p = append(p, 0)
+		i++
+		last = i
+	}
+	p = appendChecksum(p, src)
+	p = appendChecksum(p, dst)
+	p = appendChecksum(p, p)
+	return p, nil
+}
+
+// ApplyUPS applies a UPS patch to src, returning the patched image. UPS
+// patches are reversible; if src matches the target of the patch, then the
+// original source is returned.
+func ApplyUPS(src, patch []byte) ([]byte, error) {
+	if !bytes.HasPrefix(patch, []byte(upsMagic)) || len(patch) < len(upsMagic)+12 {
+		return nil, ErrFormat
+	}
+	body, srcSum, dstSum, err := splitFooter(patch)
+	if err != nil {
+		return nil, err
+	}
+	r := &reader{b: body, i: len(upsMagic)}
+	srcSize := r.number()
+	dstSize := r.number()
+	if r.err != nil {
+		return nil, r.err
+	}
+	sum := crc32.ChecksumIEEE(src)
+	switch {
+	case len(src) == srcSize && sum == srcSum:
+	case len(src) == dstSize && sum == dstSum:
+		// Apply in reverse.
+		srcSize, dstSize = dstSize, srcSize
+		srcSum, dstSum = dstSum, srcSum
+	default:
+		return nil, ErrSource
+	}
+	out := make([]byte, dstSize)
+	copy(out, src)
+	for i := 0; r.i < len(body); {
+		i += r.number()
+		for {
+			x := r.byte()
+			if r.err != nil {
+				return nil, r.err
+			}
+			if x == 0 {
+				i++
+				break
+			}
+			if i < len(out) {
+				out[i] ^= x
+			}
+			i++
+		}
+	}
+	if crc32.ChecksumIEEE(out) != dstSum {
+		return nil, ErrTarget
+	}
+	return out, nil
+}
+
+func appendChecksum(b, data []byte) []byte {
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc32.ChecksumIEEE(data))
+	return append(b, sum[:]...)
+}
+
+// Verifies the checksum of a patch with a UPS or BPS footer, returning the
+// body of the patch, and the checksums of the source and target.
+func splitFooter(patch []byte) (body []byte, src, dst uint32, err error) {
+	n := len(patch)
+	if n < 12 {
+		return nil, 0, 0, ErrFormat
+	}
+	if crc32.ChecksumIEEE(patch[:n-4]) != binary.LittleEndian.Uint32(patch[n-4:]) {
+		return nil, 0, 0, ErrPatchChecksum
+	}
+	src = binary.LittleEndian.Uint32(patch[n-12:])
+	dst = binary.LittleEndian.Uint32(patch[n-8:])
+	return patch[:n-12], src, dst, nil
+}
@@ -55,6 +55,11 @@ type Version interface {
 	// Returns the game code of the version.
 	GameCode() GameCode
 
+	// Checks the data of the version for structural problems, such as
+	// truncated tables or invalid pointers. Returns an error for each problem
+	// found, or nil if there are none.
+	Validate() []error
+
 	// Returns a Query value that can be used to used for deep searching.
 	Query() Query
 
@@ -149,6 +154,9 @@ type Version interface {
 	// type versus the types of a defending pokemon. If boths types of the
 	// defender are the same, then they are counted as a single type.
 	TypeEffectiveness(atk Type, def [2]Type) float64
+	// Returns the effectiveness of every attacking type against every
+	// defending type.
+	TypeChart() *TypeChart
 }
 
 ////////////////////////////////////////////////////////////////
@@ -182,7 +190,6 @@ func (gc GameCode) Type() string {
 	default:
 		return "Unknown:" + string(gc[0])
 	}
-	return string(gc[0])
 }
 
 func (gc GameCode) ID() string {
@@ -224,6 +231,11 @@ type Pokedex interface {
 	// Returns a list of all species in the pokedex. Note that array indices
 	// may not correspond to pokedex numbers.
 	AllSpecies() []Species
+	// Returns a list of the species with pokedex numbers from `from` to `to`,
+	// inclusive, in pokedex order. Index i of the list holds the species of
+	// number from+i, or nil if no species has that number. Panics if the
+	// range is out of bounds.
+	Range(from, to int) []Species
 	// Returns the pokedex number for a given species. Returns 0 if the
 	// species is not in the pokedex.
 	SpeciesNumber(species Species) int
@@ -527,6 +539,65 @@ func (w Weight) Pounds() float64 {
 	return w.Kilograms() / 0.45359237
 }
 
+// DexScale describes how a species is compared to the trainer on the size
+// comparison page of the pokedex. A scale of 256 displays a sprite at its
+// original size, while greater values shrink the sprite. An offset moves a
+// sprite down by a number of pixels.
+type DexScale struct {
+	PokemonScale  int
+	PokemonOffset int
+	TrainerScale  int
+	TrainerOffset int
+}
+
+// Size of the size comparison page of the pokedex, which covers the screen.
+const (
+	SizeComparisonWidth  = 240
+	SizeComparisonHeight = 160
+)
+
+// Centers of the pokemon and trainer sprites on the size comparison page.
+var (
+	sizePokemonCenter = image.Pt(88, 56)
+	sizeTrainerCenter = image.Pt(152, 56)
+)
+
+// DrawSizeComparison draws the silhouettes of a pokemon and trainer sprite,
+// as displayed by the size comparison page of the pokedex. Each opaque pixel
+// of a sprite is drawn with color c. Sprites are 64x64 images, and are
+// clipped to that size after being scaled.
+func DrawSizeComparison(pokemon, trainer image.Image, scale DexScale, c color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, SizeComparisonWidth, SizeComparisonHeight))
+	fill := color.NRGBAModel.Convert(c).(color.NRGBA)
+	drawSilhouette(img, pokemon, sizePokemonCenter.Add(image.Pt(0, scale.PokemonOffset)), scale.PokemonScale, fill)
+	drawSilhouette(img, trainer, sizeTrainerCenter.Add(image.Pt(0, scale.TrainerOffset)), scale.TrainerScale, fill)
+	return img
+}
+
+// Draws a scaled sprite centered at a point, in the manner of an affine
+// sprite. Each pixel of the 64x64 area around the point samples the sprite at
+// a distance from the center of the sprite multiplied by scale/256.
+func drawSilhouette(img *image.NRGBA, sprite image.Image, center image.Point, scale int, c color.NRGBA) {
+	const size = 64
+	if sprite == nil || scale <= 0 {
+		return
+	}
+	bounds := sprite.Bounds()
+	src := image.Pt(bounds.Min.X+bounds.Dx()/2, bounds.Min.Y+bounds.Dy()/2)
+	for dy := -size / 2; dy < size/2; dy++ {
+		for dx := -size / 2; dx < size/2; dx++ {
+			p := image.Pt(src.X+dx*scale>>8, src.Y+dy*scale>>8)
+			if !p.In(bounds) {
+				continue
+			}
+			if _, _, _, a := sprite.At(p.X, p.Y).RGBA(); a == 0 {
+				continue
+			}
+			img.SetNRGBA(center.X+dx, center.Y+dy, c)
+		}
+	}
+}
+
 ////////////////////////////////////////////////////////////////
 
 // Item represents a single item for a Version.
@@ -955,6 +1026,205 @@ func (t Type) String() string {
 	return "Unknown"
 }
 
+// TypeIndexSize is a size that fits all type indices (the maximum index + 1).
+const TypeIndexSize = 18
+
+// Effectiveness indicates how effective an attack of one type is against a
+// defender of another type. The value is the damage multiplier times ten.
+type Effectiveness byte
+
+const (
+	NoEffect         Effectiveness = 0
+	NotVeryEffective Effectiveness = 5
+	NormalEffect     Effectiveness = 10
+	SuperEffective   Effectiveness = 20
+)
+
+func (e Effectiveness) Multiplier() float64 {
+	return float64(e) / 10
+}
+
+func (e Effectiveness) String() string {
+	switch e {
+	case NoEffect:
+		return "No effect"
+	case NotVeryEffective:
+		return "Not very effective"
+	case NormalEffect:
+		return "Normal"
+	case SuperEffective:
+		return "Super effective"
+	}
+	return "Unknown"
+}
+
+// TypeChart contains the effectiveness of each attacking type against each
+// defending type. Both arrays are indexed by the attacking type, then the
+// defending type.
+type TypeChart struct {
+	Effect [TypeIndexSize][TypeIndexSize]Effectiveness
+	// Foresight indicates an entry that is ignored when the defender has been
+	// identified by Foresight or Odor Sleuth.
+	Foresight [TypeIndexSize][TypeIndexSize]bool
+}
+
+// NewTypeChart returns a chart in which every type is normally effective
+// against every other type.
+func NewTypeChart() *TypeChart {
+	c := &TypeChart{}
+	for a := range c.Effect {
+		for d := range c.Effect[a] {
+			c.Effect[a][d] = NormalEffect
+		}
+	}
+	return c
+}
+
+func (c *TypeChart) multiplier(atk Type, def [2]Type, identified bool) float64 {
+	mult := 1.0
+	if atk >= TypeIndexSize {
+		return mult
+	}
+	for i, d := range def {
+		if d >= TypeIndexSize || i > 0 && d == def[0] {
+			continue
+		}
+		if identified && c.Foresight[atk][d] {
+			continue
+		}
+		mult *= c.Effect[atk][d].Multiplier()
+	}
+	return mult
+}
+
+// Effectiveness calculates the effectiveness of an attack of a given type
+// versus the types of a defending pokemon. If both types of the defender are
+// the same, then they are counted as a single type.
+func (c *TypeChart) Effectiveness(atk Type, def [2]Type) float64 {
+	return c.multiplier(atk, def, false)
+}
+
+// IdentifiedEffectiveness is like Effectiveness, but ignores entries that are
+// removed by Foresight.
+func (c *TypeChart) IdentifiedEffectiveness(atk Type, def [2]Type) float64 {
+	return c.multiplier(atk, def, true)
+}
+
+////////////////////////////////////////////////////////////////
+
+// Status indicates a non-volatile status condition of a pokemon.
+type Status byte
+
+const (
+	StatusNone Status = iota
+	StatusSleep
+	StatusPoison
+	StatusBurn
+	StatusFreeze
+	StatusParalysis
+	StatusToxic
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusNone:
+		return "None"
+	case StatusSleep:
+		return "Sleep"
+	case StatusPoison:
+		return "Poison"
+	case StatusBurn:
+		return "Burn"
+	case StatusFreeze:
+		return "Freeze"
+	case StatusParalysis:
+		return "Paralysis"
+	case StatusToxic:
+		return "Toxic"
+	}
+	return "Unknown"
+}
+
+// PID is the personality value of an individual pokemon, which determines
+// properties such as its nature, gender, and shininess.
+type PID uint32
+
+// Nature returns the nature determined by the personality value.
+func (p PID) Nature() Nature {
+	return Nature(p % 25)
+}
+
+// Shiny returns whether the pokemon is shiny, given the trainer ID and secret
+// ID of its original trainer.
+func (p PID) Shiny(tid, sid uint16) bool {
+	return tid^sid^uint16(p>>16)^uint16(p) < 8
+}
+
+// Female returns whether a pokemon of a species with a given gender ratio is
+// female. Always returns false for genderless species.
+func (p PID) Female(ratio GenderRatio) bool {
+	switch ratio {
+	case 0:
+		return false
+	case 254:
+		return true
+	case 255:
+		return false
+	}
+	return byte(ratio) > byte(p)
+}
+
+// AbilitySlot returns which of a species' two abilities the pokemon has.
+func (p PID) AbilitySlot() int {
+	return int(p & 1)
+}
+
+// Nature affects how the stats of a pokemon grow.
+type Nature byte
+
+const (
+	Hardy Nature = iota
+	Lonely
+	Brave
+	Adamant
+	Naughty
+	Bold
+	Docile
+	Relaxed
+	Impish
+	Lax
+	Timid
+	Hasty
+	Serious
+	Jolly
+	Naive
+	Modest
+	Mild
+	Quiet
+	Bashful
+	Rash
+	Calm
+	Gentle
+	Sassy
+	Careful
+	Quirky
+)
+
+var natureNames = [...]string{
+	"Hardy", "Lonely", "Brave", "Adamant", "Naughty",
+	"Bold", "Docile", "Relaxed", "Impish", "Lax",
+	"Timid", "Hasty", "Serious", "Jolly", "Naive",
+	"Modest", "Mild", "Quiet", "Bashful", "Rash",
+	"Calm", "Gentle", "Sassy", "Careful", "Quirky",
+}
+
+func (n Nature) String() string {
+	if int(n) < len(natureNames) {
+		return natureNames[n]
+	}
+	return "Unknown"
+}
+
 ////////////////////////////////////////////////////////////////
 
 // Query is used to extract interesting information from a Version.
@@ -0,0 +1,55 @@
+package randomize
+
+import (
+	"fmt"
+	"io"
+)
+
+// Change describes a single value changed by the randomizer.
+type Change struct {
+	// The kind of data that was changed, such as "Wild" or "TM".
+	Category string
+	// Identifies the changed value within the category.
+	Subject string
+	// The name of the original value.
+	Old string
+	// The name of the new value.
+	New string
+}
+
+// Log is a spoiler log, listing every change made by the randomizer.
+type Log struct {
+	Seed    int64
+	Changes []Change
+}
+
+func (l *Log) add(category, subject, old, new string) {
+	l.Changes = append(l.Changes, Change{
+		Category: category,
+		Subject:  subject,
+		Old:      old,
+		New:      new,
+	})
+}
+
+// WriteTo writes the log as text to w. Changes are grouped by category.
+func (l *Log) WriteTo(w io.Writer) (n int64, err error) {
+	write := func(format string, a ...interface{}) {
+		if err != nil {
+			return
+		}
+		var m int
+		m, err = fmt.Fprintf(w, format, a...)
+		n += int64(m)
+	}
+	write("Seed: %d\n", l.Seed)
+	category := ""
+	for _, c := range l.Changes {
+		if c.Category != category {
+			category = c.Category
+			write("\n== %s ==\n", category)
+		}
+		write("%s: %s -> %s\n", c.Subject, c.Old, c.New)
+	}
+	return n, err
+}
@@ -0,0 +1,375 @@
+// Package randomize randomizes the data of a writable gen3 Version. Given the
+// same ROM, options and seed, the same changes are always made. Every change
+// is recorded in a spoiler log.
+package randomize
+
+import (
+	"fmt"
+	"github.com/anaminus/pkm"
+	"github.com/anaminus/pkm/gen3"
+	"math/rand"
+)
+
+// WildMode determines how wild encounters are randomized.
+type WildMode int
+
+const (
+	// Wild encounters are not randomized.
+	WildNone WildMode = iota
+	// Each encounter slot receives a random species.
+	WildBySlot
+	// Within each area of a map, each species is replaced by a random
+	// species, so that slots sharing a species still share a species.
+	WildByArea
+	// Each species is replaced by the same random species everywhere.
+	WildGlobal
+)
+
+// Options determines which data is randomized.
+type Options struct {
+	// The seed of the random number generator.
+	Seed int64
+
+	// How wild encounters are randomized.
+	Wild WildMode
+	// If true, then each replacement species has a base stat total similar
+	// to that of the species it replaces. Applies to wild encounters,
+	// starters and trainer parties.
+	SimilarBST bool
+	// If true, then every species within an area of a map shares a random
+	// type. Has no effect with WildGlobal.
+	TypeTheme bool
+
+	// Randomize the species of each starter.
+	Starters bool
+	// Randomize the species of each member of each trainer party. Parties
+	// with custom moves receive the latest moves learned by the new species
+	// by the member's level.
+	Trainers bool
+	// Randomize the move taught by each TM. HMs are not changed.
+	TMs bool
+	// Randomize the moves of each learnset, retaining the level at which
+	// each move is learned.
+	Learnsets bool
+	// Randomize the abilities of each species.
+	Abilities bool
+	// Randomize the items found in item balls and hidden on maps.
+	FieldItems bool
+}
+
+// The tolerance of a similar base stat total, as a fraction of the original
+// total. The tolerance is widened if no species fits.
+const bstTolerance = 0.1
+
+const (
+	// Moves that cannot be chosen.
+	moveStruggle = 165
+	// The number of TMs, excluding HMs.
+	tmCount = 50
+)
+
+type randomizer struct {
+	v       *gen3.Version
+	opts    Options
+	rand    *rand.Rand
+	log     *Log
+	species []pkm.Species
+	types   []pkm.Type
+}
+
+// Randomize randomizes the data of v according to opts, returning a log of
+// every change. v must be writable.
+func Randomize(v *gen3.Version, opts Options) (*Log, error) {
+	r := &randomizer{
+		v:    v,
+		opts: opts,
+		rand: rand.New(rand.NewSource(opts.Seed)),
+		log:  &Log{Seed: opts.Seed},
+	}
+	for i := 1; i < v.SpeciesIndexSize(); i++ {
+		// Indices between Celebi and Treecko are unused.
+		if i > 251 && i < 277 {
+			continue
+		}
+		r.species = append(r.species, v.SpeciesByIndex(i))
+	}
+	for t := pkm.TypeNormal; t <= pkm.TypeDark; t++ {
+		if t != pkm.TypeCurse {
+			r.types = append(r.types, t)
+		}
+	}
+
+	steps := []struct {
+		enabled bool
+		f       func() error
+	}{
+		{opts.Wild != WildNone, r.wild},
+		{opts.Starters, r.starters},
+		{opts.Trainers, r.trainers},
+		{opts.TMs, r.tms},
+		{opts.Learnsets, r.learnsets},
+		{opts.Abilities, r.abilities},
+		{opts.FieldItems, r.fieldItems},
+	}
+	for _, step := range steps {
+		if !step.enabled {
+			continue
+		}
+		if err := step.f(); err != nil {
+			return r.log, err
+		}
+	}
+	return r.log, nil
+}
+
+func hasType(s pkm.Species, t pkm.Type) bool {
+	types := s.Type()
+	return types[0] == t || types[1] == t
+}
+
+// Returns a random species to replace the given species. If theme is not nil,
+// then the species has the given type.
+func (r *randomizer) pick(old pkm.Species, theme *pkm.Type) pkm.Species {
+	candidates := r.species
+	if theme != nil {
+		var typed []pkm.Species
+		for _, s := range candidates {
+			if hasType(s, *theme) {
+				typed = append(typed, s)
+			}
+		}
+		if len(typed) > 0 {
+			candidates = typed
+		}
+	}
+	if r.opts.SimilarBST {
+		total := float64(old.BaseStats().Total())
+		for tol := bstTolerance; ; tol *= 2 {
+			var similar []pkm.Species
+			for _, s := range candidates {
+				if d := float64(s.BaseStats().Total()) - total; d >= -total*tol && d <= total*tol {
+					similar = append(similar, s)
+				}
+			}
+			if len(similar) > 0 {
+				candidates = similar
+				break
+			}
+			if tol > 1 {
+				break
+			}
+		}
+	}
+	return candidates[r.rand.Intn(len(candidates))]
+}
+
+func (r *randomizer) maps() []pkm.Map {
+	r.v.ScanBanks()
+	return r.v.AllMaps()
+}
+
+func mapName(m pkm.Map) string {
+	return fmt.Sprintf("%s (%d.%d)", m.Name(), m.BankIndex(), m.Index())
+}
+
+func (r *randomizer) wild() error {
+	global := map[int]pkm.Species{}
+	for _, m := range r.maps() {
+		for _, list := range m.Encounters() {
+			if !list.Populated() {
+				continue
+			}
+			var theme *pkm.Type
+			if r.opts.TypeTheme && r.opts.Wild != WildGlobal {
+				t := r.types[r.rand.Intn(len(r.types))]
+				theme = &t
+			}
+			area := map[int]pkm.Species{}
+			for i, e := range list.Encounters() {
+				old := e.Species()
+				var s pkm.Species
+				switch r.opts.Wild {
+				case WildBySlot:
+					s = r.pick(old, theme)
+				case WildByArea:
+					if s = area[old.Index()]; s == nil {
+						s = r.pick(old, theme)
+						area[old.Index()] = s
+					}
+				case WildGlobal:
+					if s = global[old.Index()]; s == nil {
+						s = r.pick(old, nil)
+						global[old.Index()] = s
+					}
+				}
+				if err := e.(gen3.Encounter).SetSpecies(s); err != nil {
+					return err
+				}
+				r.log.add("Wild", fmt.Sprintf("%s %s #%d", mapName(m), list.Name(), i), old.Name(), s.Name())
+			}
+		}
+	}
+	return nil
+}
+
+func (r *randomizer) starters() error {
+	chosen := map[int]bool{}
+	for i, old := range r.v.Starters() {
+		s := r.pick(old, nil)
+		for n := 0; chosen[s.Index()] && n < 100; n++ {
+			s = r.pick(old, nil)
+		}
+		chosen[s.Index()] = true
+		if err := r.v.SetStarter(i, s); err != nil {
+			return err
+		}
+		r.log.add("Starter", fmt.Sprintf("#%d", i), old.Name(), s.Name())
+	}
+	return nil
+}
+
+// Returns the last four distinct moves learned by a species by a given level.
+func movesAt(s pkm.Species, level int) []pkm.Move {
+	var moves []pkm.Move
+	for _, lm := range s.LearnedMoves() {
+		if int(lm.Level) > level {
+			break
+		}
+		known := false
+		for _, m := range moves {
+			known = known || m.Index() == lm.Move.Index()
+		}
+		if !known {
+			moves = append(moves, lm.Move)
+		}
+	}
+	if len(moves) > 4 {
+		moves = moves[len(moves)-4:]
+	}
+	return moves
+}
+
+func (r *randomizer) trainers() error {
+	for _, t := range r.v.Trainers() {
+		for i, p := range t.Party() {
+			old := p.Species()
+			s := r.pick(old, nil)
+			if err := p.SetSpecies(s); err != nil {
+				return err
+			}
+			if p.Moves() != nil {
+				if err := p.SetMoves(movesAt(s, p.Level())); err != nil {
+					return err
+				}
+			}
+			r.log.add("Trainer", fmt.Sprintf("%s (#%d) #%d", t.Name(), t.Index(), i), old.Name(), s.Name())
+		}
+	}
+	return nil
+}
+
+// Returns a random move, excluding moves in the given set.
+func (r *randomizer) pickMove(exclude map[int]bool) pkm.Move {
+	for {
+		i := 1 + r.rand.Intn(r.v.MoveIndexSize()-1)
+		if i != moveStruggle && !exclude[i] {
+			return r.v.MoveByIndex(i)
+		}
+	}
+}
+
+func (r *randomizer) tms() error {
+	tms := r.v.TMs()
+	exclude := map[int]bool{}
+	// Moves taught by HMs remain exclusive to HMs.
+	for _, tm := range tms[tmCount:] {
+		exclude[tm.Move().Index()] = true
+	}
+	for _, tm := range tms[:tmCount] {
+		old := tm.Move()
+		m := r.pickMove(exclude)
+		exclude[m.Index()] = true
+		if err := tm.(gen3.TM).SetMove(m); err != nil {
+			return err
+		}
+		r.log.add("TM", tm.Name(), old.Name(), m.Name())
+	}
+	return nil
+}
+
+func (r *randomizer) learnsets() error {
+	for _, s := range r.species {
+		moves := s.LearnedMoves()
+		if len(moves) == 0 {
+			continue
+		}
+		exclude := map[int]bool{}
+		for i, lm := range moves {
+			m := r.pickMove(exclude)
+			exclude[m.Index()] = true
+			r.log.add("Learnset", fmt.Sprintf("%s level %d", s.Name(), lm.Level), lm.Move.Name(), m.Name())
+			moves[i].Move = m
+		}
+		if err := s.(gen3.Species).SetLearnedMoves(moves); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *randomizer) abilities() error {
+	for _, s := range r.species {
+		old := s.Ability()
+		var abilities [2]pkm.Ability
+		for i, a := range old {
+			if a == nil || a.Index() == 0 {
+				continue
+			}
+			for {
+				abilities[i] = r.v.AbilityByIndex(1 + r.rand.Intn(r.v.AbilityIndexSize()-1))
+				if abilities[0] == nil || i == 0 || abilities[0].Index() != abilities[i].Index() {
+					break
+				}
+			}
+			r.log.add("Ability", fmt.Sprintf("%s #%d", s.Name(), i), a.Name(), abilities[i].Name())
+		}
+		if err := s.(gen3.Species).SetAbility(abilities); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pockets from which field items are chosen.
+var fieldItemPockets = map[byte]bool{
+	1: true, // Items
+	2: true, // Balls
+	4: true, // Berries
+}
+
+func (r *randomizer) fieldItems() error {
+	var pool []pkm.Item
+	for _, item := range r.v.Items()[1:] {
+		if fieldItemPockets[item.(gen3.Item).Pocket()] {
+			pool = append(pool, item)
+		}
+	}
+	if len(pool) == 0 {
+		return nil
+	}
+	for _, m := range r.maps() {
+		for i, f := range m.(gen3.Map).FieldItems() {
+			old := f.Item()
+			item := pool[r.rand.Intn(len(pool))]
+			if err := f.SetItem(item); err != nil {
+				return err
+			}
+			kind := "Item"
+			if f.Hidden() {
+				kind = "Hidden item"
+			}
+			r.log.add("Field item", fmt.Sprintf("%s %s #%d", mapName(m), kind, i), old.Name(), item.Name())
+		}
+	}
+	return nil
+}
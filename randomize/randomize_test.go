@@ -0,0 +1,104 @@
+package randomize_test
+
+import (
+	"bytes"
+	"github.com/anaminus/pkm"
+	"github.com/anaminus/pkm/gba"
+	"github.com/anaminus/pkm/gen3"
+	"github.com/anaminus/pkm/randomize"
+	"strings"
+	"testing"
+)
+
+// Creates a writable Emerald version from an empty image, with abilities set
+// for a few species.
+func emptyROM(t *testing.T) (*gen3.Version, *gen3.Buffer) {
+	b := make([]byte, 0x1000000)
+	copy(b, gba.NewHeader(gen3.CodeEmeraldEN, "POKEMON EMER").Bytes())
+	buf := gen3.NewBuffer(b)
+	v, err := gen3.OpenROM(buf)
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+	ver := v.(*gen3.Version)
+	for i := 1; i <= 10; i++ {
+		s := ver.SpeciesByIndex(i).(gen3.Species)
+		if err := s.SetAbility([2]pkm.Ability{ver.AbilityByIndex(i), ver.AbilityByIndex(0)}); err != nil {
+			t.Fatalf("SetAbility: unexpected error: %s", err)
+		}
+	}
+	return ver, buf
+}
+
+func TestRandomize(t *testing.T) {
+	opts := randomize.Options{
+		Seed:       1,
+		SimilarBST: true,
+		Starters:   true,
+		Trainers:   true,
+		TMs:        true,
+		Abilities:  true,
+	}
+	randomized := func(seed int64) ([]byte, *randomize.Log) {
+		ver, buf := emptyROM(t)
+		opts.Seed = seed
+		log, err := randomize.Randomize(ver, opts)
+		if err != nil {
+			t.Fatalf("Randomize: unexpected error: %s", err)
+		}
+		return buf.Bytes(), log
+	}
+
+	a, alog := randomized(1)
+	b, blog := randomized(1)
+	c, _ := randomized(2)
+	if !bytes.Equal(a, b) {
+		t.Errorf("Randomize: results of the same seed differ")
+	}
+	if bytes.Equal(a, c) {
+		t.Errorf("Randomize: results of different seeds are equal")
+	}
+
+	var abuf, bbuf bytes.Buffer
+	alog.WriteTo(&abuf)
+	blog.WriteTo(&bbuf)
+	if abuf.String() != bbuf.String() {
+		t.Errorf("Log: logs of the same seed differ")
+	}
+	for _, s := range []string{"Seed: 1\n", "== Starter ==", "== TM ==", "== Ability ==", "TM01: "} {
+		if !strings.Contains(abuf.String(), s) {
+			t.Errorf("Log: missing %q", s)
+		}
+	}
+
+	counts := map[string]int{}
+	for _, c := range alog.Changes {
+		counts[c.Category]++
+	}
+	if counts["Starter"] != 3 || counts["TM"] != 50 || counts["Ability"] != 10 {
+		t.Errorf("Log: unexpected change counts %v", counts)
+	}
+
+	v, err := gen3.OpenROM(gen3.NewBuffer(a))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+	ver := v.(*gen3.Version)
+	starters := ver.Starters()
+	if starters[0].Index() == starters[1].Index() || starters[1].Index() == starters[2].Index() || starters[0].Index() == starters[2].Index() {
+		t.Errorf("Starters: starters are not distinct")
+	}
+	seen := map[int]bool{}
+	for _, tm := range ver.TMs()[:50] {
+		i := tm.Move().Index()
+		if i == 0 || seen[i] {
+			t.Errorf("TMs: invalid or repeated move %d", i)
+		}
+		seen[i] = true
+	}
+	for i := 1; i <= 10; i++ {
+		if a := ver.SpeciesByIndex(i).Ability(); a[0].Index() == 0 || a[1].Index() != 0 {
+			t.Errorf("Abilities: unexpected abilities of species %d: %d, %d", i, a[0].Index(), a[1].Index())
+		}
+	}
+}
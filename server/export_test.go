@@ -0,0 +1,8 @@
+package server
+
+// CacheLen returns the number of cached responses of s.
+func CacheLen(s *Server) int {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+	return len(s.cache)
+}
@@ -0,0 +1,353 @@
+// Package server serves the data of a pkm.Version over HTTP as JSON.
+//
+// The following endpoints are served. A value may be given by index or by
+// name. Names are case-insensitive.
+//
+//	GET /species/{name|index}             A species.
+//	GET /moves/{name|index}               A move.
+//	GET /items/{name|index}               An item.
+//	GET /abilities/{name|index}           An ability.
+//	GET /maps/{bank}/{map}                A map and its encounters.
+//	GET /maps/{bank}/{map}/image.png      A rendering of a map.
+//	GET /query/learners?move={name|index} Species that learn a move.
+//
+// Values are encoded with the types of the export package. Errors are encoded
+// as an object with a single "error" field.
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/anaminus/pkm"
+	"github.com/anaminus/pkm/export"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Server is an http.Handler that serves the data of a Version.
+//
+// Requests are handled concurrently, so the Version must be safe for
+// concurrent use, as is a Version returned by gen3.OpenROM. Because the
+// Version is assumed not to change, each successful response is cached after
+// it has been produced once. Responses are cached by the value they describe
+// rather than by the text of the request, so the cache is bounded by the
+// number of values in the Version. The Version must not be modified while it
+// is being served.
+type Server struct {
+	ver pkm.Version
+
+	cacheMu sync.RWMutex
+	cache   map[string]*response
+}
+
+// New returns a Server that serves the data of v.
+func New(v pkm.Version) *Server {
+	return &Server{
+		ver:   v,
+		cache: map[string]*response{},
+	}
+}
+
+// A produced response.
+type response struct {
+	status      int
+	contentType string
+	body        []byte
+}
+
+// A request resolved to the value it describes.
+type endpoint struct {
+	// Identifies the value, such as "species/25", regardless of whether it
+	// was requested by index or by name.
+	key string
+	// Produces the response for the value.
+	produce func() (*response, error)
+}
+
+// Error produced while handling a request, along with an HTTP status code.
+type statusError struct {
+	status int
+	err    error
+}
+
+func (err statusError) Error() string {
+	return err.err.Error()
+}
+
+func notFound(format string, a ...interface{}) error {
+	return statusError{http.StatusNotFound, fmt.Errorf(format, a...)}
+}
+
+func badRequest(format string, a ...interface{}) error {
+	return statusError{http.StatusBadRequest, fmt.Errorf(format, a...)}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		s.write(w, errorResponse(statusError{http.StatusMethodNotAllowed, errors.New("method not allowed")}))
+		return
+	}
+	e, err := s.route(r)
+	if err != nil {
+		s.write(w, errorResponse(err))
+		return
+	}
+
+	s.cacheMu.RLock()
+	resp, ok := s.cache[e.key]
+	s.cacheMu.RUnlock()
+	if !ok {
+		resp = s.produce(e)
+	}
+	s.write(w, resp)
+}
+
+// Produces the response for an endpoint, caching it if it succeeded. Errors
+// are not cached, so that requests for unknown values do not grow the cache.
+func (s *Server) produce(e endpoint) *response {
+	resp, err := e.produce()
+	if err != nil {
+		return errorResponse(err)
+	}
+	if resp.status == http.StatusOK {
+		s.cacheMu.Lock()
+		s.cache[e.key] = resp
+		s.cacheMu.Unlock()
+	}
+	return resp
+}
+
+func (s *Server) write(w http.ResponseWriter, resp *response) {
+	w.Header().Set("Content-Type", resp.contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(resp.body)))
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+}
+
+func jsonResponse(status int, v interface{}) (*response, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &response{
+		status:      status,
+		contentType: "application/json",
+		body:        append(b, '\n'),
+	}, nil
+}
+
+func errorResponse(err error) *response {
+	status := http.StatusInternalServerError
+	if err, ok := err.(statusError); ok {
+		status = err.status
+	}
+	b, _ := json.Marshal(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+	return &response{
+		status:      status,
+		contentType: "application/json",
+		body:        append(b, '\n'),
+	}
+}
+
+// Resolves a request to its endpoint.
+func (s *Server) route(r *http.Request) (endpoint, error) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "species":
+		sp, err := s.species(parts[1])
+		if err != nil {
+			return endpoint{}, err
+		}
+		return endpoint{fmt.Sprintf("species/%d", sp.Index()), func() (*response, error) {
+			return jsonResponse(http.StatusOK, export.NewSpecies(sp))
+		}}, nil
+	case len(parts) == 2 && parts[0] == "moves":
+		m, err := s.move(parts[1])
+		if err != nil {
+			return endpoint{}, err
+		}
+		return endpoint{fmt.Sprintf("moves/%d", m.Index()), func() (*response, error) {
+			return jsonResponse(http.StatusOK, export.NewMove(m))
+		}}, nil
+	case len(parts) == 2 && parts[0] == "items":
+		i, err := s.item(parts[1])
+		if err != nil {
+			return endpoint{}, err
+		}
+		return endpoint{fmt.Sprintf("items/%d", i.Index()), func() (*response, error) {
+			return jsonResponse(http.StatusOK, export.NewItem(i))
+		}}, nil
+	case len(parts) == 2 && parts[0] == "abilities":
+		a, err := s.ability(parts[1])
+		if err != nil {
+			return endpoint{}, err
+		}
+		return endpoint{fmt.Sprintf("abilities/%d", a.Index()), func() (*response, error) {
+			return jsonResponse(http.StatusOK, export.NewAbility(a))
+		}}, nil
+	case len(parts) == 3 && parts[0] == "maps":
+		m, err := s.mapByIndex(parts[1], parts[2])
+		if err != nil {
+			return endpoint{}, err
+		}
+		return endpoint{fmt.Sprintf("maps/%d/%d", m.BankIndex(), m.Index()), func() (*response, error) {
+			return jsonResponse(http.StatusOK, export.NewMap(m))
+		}}, nil
+	case len(parts) == 4 && parts[0] == "maps" && parts[3] == "image.png":
+		m, err := s.mapByIndex(parts[1], parts[2])
+		if err != nil {
+			return endpoint{}, err
+		}
+		return endpoint{fmt.Sprintf("maps/%d/%d/image.png", m.BankIndex(), m.Index()), func() (*response, error) {
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, pkm.CombineLayers(m.Image()...)); err != nil {
+				return nil, err
+			}
+			return &response{
+				status:      http.StatusOK,
+				contentType: "image/png",
+				body:        buf.Bytes(),
+			}, nil
+		}}, nil
+	case len(parts) == 2 && parts[0] == "query" && parts[1] == "learners":
+		arg := r.URL.Query().Get("move")
+		if arg == "" {
+			return endpoint{}, badRequest("missing move parameter")
+		}
+		m, err := s.move(arg)
+		if err != nil {
+			return endpoint{}, err
+		}
+		return endpoint{fmt.Sprintf("query/learners/%d", m.Index()), func() (*response, error) {
+			return s.learners(m)
+		}}, nil
+	}
+	return endpoint{}, notFound("unknown endpoint %q", r.URL.Path)
+}
+
+// Parses an index within [0, size). The second result is false if s is not a
+// number, in which case it should be treated as a name.
+func parseIndex(s string, size int) (int, bool, error) {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false, nil
+	}
+	if i < 0 || i >= size {
+		return 0, true, notFound("index %d out of range", i)
+	}
+	return i, true, nil
+}
+
+func (s *Server) species(arg string) (pkm.Species, error) {
+	if i, ok, err := parseIndex(arg, s.ver.SpeciesIndexSize()); ok {
+		if err != nil {
+			return nil, err
+		}
+		return s.ver.SpeciesByIndex(i), nil
+	}
+	if sp := s.ver.SpeciesByName(arg); sp != nil {
+		return sp, nil
+	}
+	return nil, notFound("species %q not found", arg)
+}
+
+func (s *Server) move(arg string) (pkm.Move, error) {
+	if i, ok, err := parseIndex(arg, s.ver.MoveIndexSize()); ok {
+		if err != nil {
+			return nil, err
+		}
+		return s.ver.MoveByIndex(i), nil
+	}
+	if m := s.ver.MoveByName(arg); m != nil {
+		return m, nil
+	}
+	return nil, notFound("move %q not found", arg)
+}
+
+func (s *Server) item(arg string) (pkm.Item, error) {
+	if i, ok, err := parseIndex(arg, s.ver.ItemIndexSize()); ok {
+		if err != nil {
+			return nil, err
+		}
+		return s.ver.ItemByIndex(i), nil
+	}
+	if i := s.ver.ItemByName(arg); i != nil {
+		return i, nil
+	}
+	return nil, notFound("item %q not found", arg)
+}
+
+func (s *Server) ability(arg string) (pkm.Ability, error) {
+	if i, ok, err := parseIndex(arg, s.ver.AbilityIndexSize()); ok {
+		if err != nil {
+			return nil, err
+		}
+		return s.ver.AbilityByIndex(i), nil
+	}
+	if a := s.ver.AbilityByName(arg); a != nil {
+		return a, nil
+	}
+	return nil, notFound("ability %q not found", arg)
+}
+
+func (s *Server) mapByIndex(bankArg, mapArg string) (pkm.Map, error) {
+	s.ver.ScanBanks()
+	b, err := strconv.Atoi(bankArg)
+	if err != nil {
+		return nil, badRequest("invalid bank %q", bankArg)
+	}
+	m, err := strconv.Atoi(mapArg)
+	if err != nil {
+		return nil, badRequest("invalid map %q", mapArg)
+	}
+	if b < 0 || b >= s.ver.BankIndexSize() {
+		return nil, notFound("bank %d out of range", b)
+	}
+	bank := s.ver.BankByIndex(b)
+	if m < 0 || m >= bank.MapIndexSize() {
+		return nil, notFound("map %d out of range", m)
+	}
+	return bank.MapByIndex(m), nil
+}
+
+// Result of the learners query.
+type learners struct {
+	Move export.Ref `json:"move"`
+	// Species that learn the move by leveling up.
+	LevelUp []export.Ref `json:"level_up"`
+	// Species that learn the move from a TM.
+	TM []export.Ref `json:"tm"`
+}
+
+func ref(index int, name string) export.Ref {
+	return export.Ref{Index: index, Name: name}
+}
+
+func (s *Server) learners(m pkm.Move) (*response, error) {
+	q := s.ver.Query()
+	result := learners{
+		Move:    ref(m.Index(), m.Name()),
+		LevelUp: []export.Ref{},
+		TM:      []export.Ref{},
+	}
+	for _, sp := range q.SpeciesLearningMove(m) {
+		result.LevelUp = append(result.LevelUp, ref(sp.Index(), sp.Name()))
+	}
+	for _, tm := range s.ver.TMs() {
+		if tm.Move().Index() != m.Index() {
+			continue
+		}
+		for _, sp := range q.SpeciesLearningTM(tm) {
+			result.TM = append(result.TM, ref(sp.Index(), sp.Name()))
+		}
+	}
+	return jsonResponse(http.StatusOK, result)
+}
@@ -0,0 +1,148 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/anaminus/pkm"
+	"github.com/anaminus/pkm/export"
+	"github.com/anaminus/pkm/gba"
+	"github.com/anaminus/pkm/gen3"
+	"github.com/anaminus/pkm/server"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// Opens an empty Emerald image.
+func emptyVersion(t *testing.T) pkm.Version {
+	b := make([]byte, 0x1000000)
+	for i := range b {
+		b[i] = 0xFF
+	}
+	copy(b, gba.NewHeader(gen3.CodeEmeraldEN, "POKEMON EMER").Bytes())
+	ver, err := gen3.OpenROM(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("failed to open ROM: %s", err)
+	}
+	return ver
+}
+
+// Creates a server over an empty Emerald image.
+func emptyServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(server.New(emptyVersion(t)))
+}
+
+func get(t *testing.T, url string, v interface{}) int {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Get: unexpected error: %s", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Get %s: unexpected content type %q", url, ct)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Errorf("Get %s: unexpected error: %s", url, err)
+	}
+	return resp.StatusCode
+}
+
+func TestServer(t *testing.T) {
+	ts := emptyServer(t)
+	defer ts.Close()
+
+	var species export.Species
+	if status := get(t, ts.URL+"/species/25", &species); status != http.StatusOK {
+		t.Errorf("species: unexpected status %d", status)
+	}
+	if species.Index != 25 {
+		t.Errorf("species: unexpected index %d", species.Index)
+	}
+
+	var move export.Move
+	if status := get(t, ts.URL+"/moves/33", &move); status != http.StatusOK {
+		t.Errorf("moves: unexpected status %d", status)
+	}
+	if move.Index != 33 {
+		t.Errorf("moves: unexpected index %d", move.Index)
+	}
+
+	var e struct{ Error string }
+	for _, path := range []string{
+		"/species/100000",
+		"/moves/-1",
+		"/items/unknown",
+		"/unknown",
+	} {
+		if status := get(t, ts.URL+path, &e); status != http.StatusNotFound {
+			t.Errorf("%s: expected status 404, got %d", path, status)
+		}
+		if e.Error == "" {
+			t.Errorf("%s: expected error message", path)
+		}
+	}
+	if status := get(t, ts.URL+"/query/learners", &e); status != http.StatusBadRequest {
+		t.Errorf("learners: expected status 400, got %d", status)
+	}
+
+	resp, err := http.Post(ts.URL+"/species/1", "text/plain", nil)
+	if err != nil {
+		t.Fatalf("Post: unexpected error: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Post: expected status 405, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerCache(t *testing.T) {
+	s := server.New(emptyVersion(t))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	// Failed requests are not cached.
+	var e struct{ Error string }
+	for i := 0; i < 10; i++ {
+		get(t, fmt.Sprintf("%s/unknown/%d", ts.URL, i), &e)
+		get(t, fmt.Sprintf("%s/species/%d", ts.URL, 100000+i), &e)
+		get(t, fmt.Sprintf("%s/moves/unknown%d", ts.URL, i), &e)
+	}
+	if n := server.CacheLen(s); n != 0 {
+		t.Errorf("cache: unexpected length %d after errors", n)
+	}
+
+	// Requests for the same value share a response, regardless of how the
+	// value is referred to.
+	var species export.Species
+	for _, path := range []string{"/species/25", "/species/025", "/species/25?a=1", "/species/25?b=2"} {
+		if status := get(t, ts.URL+path, &species); status != http.StatusOK {
+			t.Errorf("%s: unexpected status %d", path, status)
+		}
+	}
+	if n := server.CacheLen(s); n != 1 {
+		t.Errorf("cache: unexpected length %d", n)
+	}
+}
+
+func TestServerConcurrent(t *testing.T) {
+	ts := emptyServer(t)
+	defer ts.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var species export.Species
+			if status := get(t, ts.URL+"/species/"+[]string{"1", "2", "3", "4"}[i%4], &species); status != http.StatusOK {
+				t.Errorf("species: unexpected status %d", status)
+			}
+			if species.Index != i%4+1 {
+				t.Errorf("species: expected index %d, got %d", i%4+1, species.Index)
+			}
+		}(i)
+	}
+	wg.Wait()
+}